@@ -1,20 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/qx/syft_robot/api/internal/config"
 	"github.com/qx/syft_robot/api/internal/handler"
 	"github.com/qx/syft_robot/api/internal/logic"
+	"github.com/qx/syft_robot/api/internal/store"
 	"github.com/qx/syft_robot/api/internal/svc"
 	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/stores/redis"
 )
 
 var (
 	configFile = flag.String("f", "etc/dinner.yaml", "the config file")
 	testMode   = flag.Bool("test", false, "test mode for reminder")
+	migrate    = flag.Bool("migrate-ledger", false, "copy accounting cycles from redis to the sql ledger store and exit")
 )
 
 func main() {
@@ -23,6 +28,11 @@ func main() {
 	var c config.Config
 	conf.MustLoad(*configFile, &c)
 
+	if *migrate {
+		migrateLedger(c)
+		return
+	}
+
 	svcCtx := svc.NewServiceContext(c)
 	
 	// 创建 DinnerLogic 实例
@@ -68,6 +78,94 @@ func main() {
 			Command:     "accounting_history",
 			Description: "查看历史记账记录",
 		},
+		{
+			Command:     "accounting_export",
+			Description: "导出记账记录为CSV/XLSX",
+		},
+		{
+			Command:     "accounting_search",
+			Description: "搜索历史记账记录",
+		},
+		{
+			Command:     "accounting_report",
+			Description: "查看月度/年度账单报告",
+		},
+		{
+			Command:     "accounting_audit",
+			Description: "查看记账周期的变更记录",
+		},
+		{
+			Command:     "accounting_chart",
+			Description: "查看当前周期的支出图表",
+		},
+		{
+			Command:     "reimburse",
+			Description: "查看待报销记录",
+		},
+		{
+			Command:     "recurring",
+			Description: "管理周期性记账",
+		},
+		{
+			Command:     "receipt",
+			Description: "查看记录的小票照片",
+		},
+		{
+			Command:     "goal",
+			Description: "管理储蓄目标",
+		},
+		{
+			Command:     "digest",
+			Description: "订阅每日/每周账单摘要",
+		},
+		{
+			Command:     "accounting_link",
+			Description: "将私聊账本关联到本群",
+		},
+		{
+			Command:     "accounting_group",
+			Description: "查看群汇总",
+		},
+		{
+			Command:     "accounting_inline",
+			Description: "内联记账记入本群账本",
+		},
+		{
+			Command:     "privacy",
+			Description: "设置账单是否私聊发送",
+		},
+		{
+			Command:     "accounting_share",
+			Description: "共享账本给其他人查看",
+		},
+		{
+			Command:     "wallets",
+			Description: "查看各钱包余额",
+		},
+		{
+			Command:     "transfer",
+			Description: "在钱包之间转账",
+		},
+		{
+			Command:     "owe",
+			Description: "记录欠别人的钱",
+		},
+		{
+			Command:     "lent",
+			Description: "记录借给别人的钱",
+		},
+		{
+			Command:     "debts",
+			Description: "查看借款和欠款",
+		},
+		{
+			Command:     "fx_set",
+			Description: "设置外币汇率",
+		},
+		{
+			Command:     "fx_list",
+			Description: "查看当前汇率",
+		},
 	}
 	_, err := svcCtx.Bot.Request(tgbotapi.NewSetMyCommands(commands...))
 	if err != nil {
@@ -81,8 +179,17 @@ func main() {
 	}
 	log.Printf("机器人已启动: @%s", me.UserName)
 
-	// 启动定时提醒
-	dinnerLogic.StartReminder(*testMode)
+	// 注册定时任务：喝水提醒、周期性记账、账单摘要
+	interval := time.Minute
+	if *testMode {
+		// 测试模式下每10秒触发一次
+		interval = 10 * time.Second
+	}
+	scheduler := logic.NewScheduler(interval)
+	dinnerLogic.RegisterReminder(scheduler, *testMode)
+	logic.NewRecurringLogic(context.Background(), svcCtx).Register(scheduler)
+	logic.NewDigestLogic(context.Background(), svcCtx).Register(scheduler)
+	scheduler.Start()
 
 	// 开始接收更新
 	u := tgbotapi.NewUpdate(0)
@@ -93,4 +200,18 @@ func main() {
 		log.Printf("收到更新: %+v", update)
 		handler.HandleUpdate(update)
 	}
-} 
\ No newline at end of file
+} 
+
+// 将 Redis 中的记账周期复制到配置的 sql 存储，可以重复执行
+func migrateLedger(c config.Config) {
+	target, err := store.NewSqlLedgerStore(c.Ledger)
+	if err != nil {
+		log.Fatalf("连接 sql 记账存储失败: %v", err)
+	}
+	source := store.NewRedisLedgerStore(redis.MustNewRedis(c.Redis))
+	copied, err := store.CopyLedger(context.Background(), source, target)
+	if err != nil {
+		log.Fatalf("迁移记账周期失败（已复制 %d 个）: %v", copied, err)
+	}
+	log.Printf("迁移完成，共复制 %d 个记账周期，将 Ledger.Store 设置为 sql 后重启即可使用", copied)
+}
@@ -11,4 +11,12 @@ type Config struct {
 	Bot   struct {
 		Token string
 	}
-} 
\ No newline at end of file
+	Ledger LedgerConf `json:",optional"`
+}
+
+// LedgerConf 记账周期和记录的存储方式，其他数据（审计、借款、设置等）仍保存在 Redis
+type LedgerConf struct {
+	Store      string `json:",default=redis,options=redis|sql"` // redis 或 sql
+	Driver     string `json:",default=mysql"`                   // sql 存储的驱动名，mysql 或 sqlite3
+	DataSource string `json:",optional"`                        // sql 存储的连接串，mysql 需带 parseTime=true
+}
@@ -2,13 +2,17 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf16"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/qx/syft_robot/api/internal/logic"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/parser"
 	"github.com/qx/syft_robot/api/internal/svc"
 )
 
@@ -16,9 +20,20 @@ type DinnerHandler struct {
 	svcCtx         *svc.ServiceContext
 	dinnerLogic    *logic.DinnerLogic
 	accountingLogic *logic.AccountingLogic
+	exchangeRateLogic *logic.ExchangeRateLogic
+	recurringLogic    *logic.RecurringLogic
+	digestLogic       *logic.DigestLogic
+	debtLogic         *logic.DebtLogic
 	// 记录正在等待输入的用户
 	waitingForExpenseAmount map[int64]bool
 	waitingForIncomeAmount  map[int64]bool
+	waitingForBatchEdit     map[int64]batchEdit // 正在修改的批量记账
+}
+
+// 正在修改的批量记账，只处理回复修改提示的消息
+type batchEdit struct {
+	batchID  string
+	promptID int
 }
 
 func NewDinnerHandler(svcCtx *svc.ServiceContext, dinnerLogic *logic.DinnerLogic) *DinnerHandler {
@@ -27,8 +42,13 @@ func NewDinnerHandler(svcCtx *svc.ServiceContext, dinnerLogic *logic.DinnerLogic
 		svcCtx:                 svcCtx,
 		dinnerLogic:            dinnerLogic,
 		accountingLogic:        accountingLogic,
+		exchangeRateLogic:      logic.NewExchangeRateLogic(context.Background(), svcCtx),
+		recurringLogic:         logic.NewRecurringLogic(context.Background(), svcCtx),
+		digestLogic:            logic.NewDigestLogic(context.Background(), svcCtx),
+		debtLogic:              logic.NewDebtLogic(context.Background(), svcCtx),
 		waitingForExpenseAmount: make(map[int64]bool),
 		waitingForIncomeAmount:  make(map[int64]bool),
+		waitingForBatchEdit:     make(map[int64]batchEdit),
 	}
 }
 
@@ -41,6 +61,22 @@ func (h *DinnerHandler) HandleUpdate(update tgbotapi.Update) error {
 		return h.handleMessage(update.Message)
 	}
 
+	// 内联记账：输入时返回预览，选中后记账
+	if update.InlineQuery != nil {
+		return h.accountingLogic.AnswerInlineQuery(update.InlineQuery.ID, update.InlineQuery.From.ID, update.InlineQuery.Query)
+	}
+
+	if update.ChosenInlineResult != nil {
+		result := update.ChosenInlineResult
+		if err := h.accountingLogic.RecordInlineEntry(result.From.ID, result.ResultID, result.Query); err != nil {
+			// 内联消息发在其他聊天里，错误私聊告诉用户
+			msg := tgbotapi.NewMessage(result.From.ID, fmt.Sprintf("内联记账失败: %v", err))
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+	}
+
 	return nil
 }
 
@@ -92,11 +128,160 @@ func (h *DinnerHandler) handleCallback(callback *tgbotapi.CallbackQuery) error {
 			return nil
 		}
 		
-		// 确认收到回调
-		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "正在加载..."))
-		
 		// 获取并显示记账周期详情
-		return h.accountingLogic.GetAccountingCycleById(chatID, cycleID)
+		if err := h.accountingLogic.GetAccountingCycleById(chatID, userID, cycleID); err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return nil
+	}
+
+	// 处理查看共享账本历史按钮: shared_history_<所有者ID>
+	if strings.HasPrefix(data, "shared_history_") {
+		ownerID, err := strconv.ParseInt(strings.TrimPrefix(data, "shared_history_"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid owner in callback data: %s", data)
+		}
+		if err := h.accountingLogic.ViewSharedHistory(chatID, userID, ownerID); err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return nil
+	}
+
+	// 处理账单导入确认按钮
+	if strings.HasPrefix(data, "import_") {
+		var err error
+		switch {
+		case strings.HasPrefix(data, "import_confirm_"):
+			err = h.accountingLogic.ConfirmImport(chatID, userID, strings.TrimPrefix(data, "import_confirm_"), false)
+		case strings.HasPrefix(data, "import_all_"):
+			err = h.accountingLogic.ConfirmImport(chatID, userID, strings.TrimPrefix(data, "import_all_"), true)
+		case strings.HasPrefix(data, "import_cancel_"):
+			err = h.accountingLogic.CancelImport(chatID, userID, strings.TrimPrefix(data, "import_cancel_"))
+		default:
+			return fmt.Errorf("unknown callback data: %s", data)
+		}
+		if err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "已处理"))
+		return nil
+	}
+
+	// 处理批量记账预览按钮
+	if strings.HasPrefix(data, "batch_") {
+		var err error
+		switch {
+		case strings.HasPrefix(data, "batch_confirm_"):
+			batchID := strings.TrimPrefix(data, "batch_confirm_")
+			h.clearBatchEdit(userID, batchID)
+			err = h.accountingLogic.ConfirmBatch(chatID, userID, batchID)
+		case strings.HasPrefix(data, "batch_edit_"):
+			batchID := strings.TrimPrefix(data, "batch_edit_")
+			var promptID int
+			if promptID, err = h.accountingLogic.EditBatch(chatID, userID, batchID); err == nil {
+				h.waitingForBatchEdit[userID] = batchEdit{batchID: batchID, promptID: promptID}
+			}
+		case strings.HasPrefix(data, "batch_cancel_"):
+			batchID := strings.TrimPrefix(data, "batch_cancel_")
+			h.clearBatchEdit(userID, batchID)
+			err = h.accountingLogic.CancelBatch(chatID, userID, batchID)
+		default:
+			return fmt.Errorf("unknown callback data: %s", data)
+		}
+		if err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "已处理"))
+		return nil
+	}
+
+	// 处理报销状态按钮: reimb_<操作>_<账本聊天ID>_<记录ID>，旧按钮没有聊天ID
+	if strings.HasPrefix(data, "reimb_") {
+		parts := strings.Split(strings.TrimPrefix(data, "reimb_"), "_")
+		ledgerChatID := chatID
+		switch len(parts) {
+		case 2:
+		case 3:
+			id, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid chat ID in callback data: %s", parts[1])
+			}
+			ledgerChatID = id
+		default:
+			return fmt.Errorf("invalid callback data format: %s", data)
+		}
+		recordID := parts[len(parts)-1]
+		var err error
+		switch parts[0] {
+		case "submit":
+			err = h.accountingLogic.SubmitReimbursement(chatID, ledgerChatID, userID, recordID)
+		case "paid":
+			err = h.accountingLogic.MarkReimbursed(chatID, ledgerChatID, userID, recordID)
+		default:
+			return fmt.Errorf("unknown callback data: %s", data)
+		}
+		if err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "已更新"))
+		return nil
+	}
+
+	// 处理储蓄目标存入按钮: goal_<目标ID>_<比例>_<周期ID>
+	if strings.HasPrefix(data, "goal_") {
+		parts := strings.SplitN(strings.TrimPrefix(data, "goal_"), "_", 3)
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid callback data format: %s", data)
+		}
+		percent, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid percent in callback data: %s", parts[1])
+		}
+		if err := h.accountingLogic.AllocateToGoal(chatID, userID, parts[0], parts[2], percent); err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "已存入"))
+		return nil
+	}
+
+	// 处理搜索结果翻页按钮
+	if strings.HasPrefix(data, "search_") {
+		parts := strings.Split(strings.TrimPrefix(data, "search_"), "_")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid callback data format: %s", data)
+		}
+		page, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid page in callback data: %s", parts[1])
+		}
+		if err := h.accountingLogic.SearchPage(chatID, userID, callback.Message.MessageID, parts[0], page); err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return nil
+	}
+
+	// 处理借款确认和结清按钮: debt_<操作>_<借款ID>
+	if strings.HasPrefix(data, "debt_") {
+		parts := strings.SplitN(strings.TrimPrefix(data, "debt_"), "_", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid callback data format: %s", data)
+		}
+		if err := h.debtLogic.HandleAction(chatID, *debtParty(callback.From), parts[0], parts[1]); err != nil {
+			h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return err
+		}
+		h.svcCtx.Bot.Request(tgbotapi.NewCallback(callback.ID, "已更新"))
+		return nil
 	}
 
 	return fmt.Errorf("unknown callback data: %s", data)
@@ -105,6 +290,18 @@ func (h *DinnerHandler) handleCallback(callback *tgbotapi.CallbackQuery) error {
 func (h *DinnerHandler) handleMessage(message *tgbotapi.Message) error {
 	userID := message.From.ID
 
+	// 处理上传的账单文件
+	if message.Document != nil {
+		return h.handleDocument(message)
+	}
+
+	// 处理带小票照片的回复，照片说明即记账内容；私聊中不需要回复
+	if len(message.Photo) > 0 && (message.Chat.IsPrivate() || message.ReplyToMessage != nil &&
+		(h.waitingForExpenseAmount[userID] || (message.ReplyToMessage.From != nil && message.ReplyToMessage.From.IsBot))) {
+		delete(h.waitingForExpenseAmount, userID)
+		return h.handleReceiptPhoto(message)
+	}
+
 	// 处理回复消息 - 记录支出金额
 	if message.ReplyToMessage != nil && h.waitingForExpenseAmount[userID] {
 		delete(h.waitingForExpenseAmount, userID)
@@ -116,6 +313,13 @@ func (h *DinnerHandler) handleMessage(message *tgbotapi.Message) error {
 		return h.handleIncomeReply(message)
 	}
 
+	// 处理回复消息 - 修改批量记账，回复其他消息时按普通消息处理
+	if edit, ok := h.waitingForBatchEdit[userID]; ok && message.ReplyToMessage != nil &&
+		message.ReplyToMessage.MessageID == edit.promptID {
+		delete(h.waitingForBatchEdit, userID)
+		return h.handleBatchEdit(message, edit.batchID)
+	}
+
 	// 处理普通回复消息 - 尝试解析金额进行记账
 	if message.ReplyToMessage != nil && message.ReplyToMessage.From.IsBot {
 		return h.handleAccountingMessage(message)
@@ -126,6 +330,11 @@ func (h *DinnerHandler) handleMessage(message *tgbotapi.Message) error {
 		return h.handleCommand(message)
 	}
 
+	// 私聊中直接发送账目即可记入私聊账本，不需要回复机器人
+	if message.Chat.IsPrivate() && message.Text != "" {
+		return h.handleAccountingMessage(message)
+	}
+
 	return nil
 }
 
@@ -145,12 +354,22 @@ func (h *DinnerHandler) handleCommand(message *tgbotapi.Message) error {
 		}
 		
 		// 获取并显示记账周期详情
-		return h.accountingLogic.GetAccountingCycleById(chatID, cycleID)
+		if err := h.accountingLogic.GetAccountingCycleById(chatID, userID, cycleID); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
 	}
 
 	switch command {
 	case "start":
 		msg := tgbotapi.NewMessage(chatID, "欢迎使用晚餐报名机器人！\n使用 /dinner 开始今天的报名")
+		if message.Chat.IsPrivate() {
+			msg.Text = "欢迎使用记账机器人！\n\n" +
+				"在这里记账会保存在你的私聊账本中，记录和摘要不会出现在任何群里\n" +
+				"使用 /accounting_start 开始记账，在群里发送 /accounting_link 可以把私聊账本的总额显示在群汇总中"
+		}
 		_, err := h.svcCtx.Bot.Send(msg)
 		return err
 
@@ -163,8 +382,32 @@ func (h *DinnerHandler) handleCommand(message *tgbotapi.Message) error {
 			"/accounting_start - 开始记账周期\n"+
 			"/accounting_expense - 添加支出记录\n"+
 			"/accounting_end - 结束当前记账周期\n"+
-			"/accounting_status - 查看当前账单记录\n\n"+
-			"💡 提示：直接回复(Reply)机器人消息即可记录支出")
+			"/accounting_status - 查看当前账单记录\n"+
+			"/accounting_export - 导出记账记录（CSV/XLSX）\n"+
+			"/accounting_chart - 查看支出图表（daily/category/balance）\n"+
+			"/accounting_search 打车 >30 9/1~9/30 - 搜索所有周期的记录\n"+
+			"/accounting_report month 2026-09 - 月度/年度报告（year 2026）\n"+
+			"/accounting_audit - 查看当前周期的变更记录（带历史序号查看历史周期）\n"+
+			"📥 发送支付宝/微信导出的 CSV 账单（说明中写\"导入\"）即可批量导入\n"+
+			"/fx_set USD 7.2 - 设置汇率（1 单位外币折合人民币）\n"+
+			"/fx_list - 查看当前汇率\n"+
+			"/reimburse - 查看待报销记录（描述后加\"（公司报销）\"即可登记）\n"+
+			"/recurring add 房租 -3500 monthly 5 - 添加周期性记账（list/pause/resume/delete 管理）\n"+
+			"/receipt 3 - 查看第 3 条记录的小票（回复机器人时发送带说明的照片即可保存）\n"+
+			"/goal 旅行基金 10000 2027-02-01 - 创建储蓄目标（save/delete 管理）\n"+
+			"/wallets - 查看各钱包余额（记账时加 #支付宝 等标记指定钱包）\n"+
+			"/transfer 500 银行卡 支付宝 - 钱包之间转账，不计入收支\n"+
+			"/digest daily 21:00 - 订阅每日/每周账单摘要（dm 私聊发送）\n"+
+			"/owe @alice 50 奶茶 - 记录欠对方的钱（/lent 记录借出，需对方确认）\n"+
+			"/debts - 查看与每个人的欠款并申请结清\n"+
+			"/privacy dm - 摘要、历史和明细改为私聊发送（group 恢复）\n"+
+			"/accounting_share - 回复对方消息，共享账本给对方查看（remove 取消）\n"+
+			"/accounting_link - 将私聊账本关联到本群（off 取消），私聊机器人即可单独记账\n"+
+			"/accounting_group - 查看群汇总（只显示每人总额）\n"+
+			"/accounting_inline - 在任意聊天输入 @机器人 35 午餐 记入本群账本（默认记入私聊账本，off 恢复）\n\n"+
+			"💡 提示：直接回复(Reply)机器人消息即可记录支出\n"+
+			"📝 一条消息发送多行账目（每行一条），确认预览后一次性记入\n"+
+			"📅 开头加上日期即可补记，例如: 昨天 打车-30、周五 电影-60、3/14 午饭-25")
 		_, err := h.svcCtx.Bot.Send(msg)
 		return err
 
@@ -215,13 +458,197 @@ func (h *DinnerHandler) handleCommand(message *tgbotapi.Message) error {
 		
 		// 设置用户为等待输入支出金额状态
 		h.waitingForExpenseAmount[userID] = true
-		msg := tgbotapi.NewMessage(chatID, "请回复(Reply)本消息，增加记录\n\n支持以下格式：\n1. 100 午餐\n2. 买菜-5\n3. -100 打车\n4. 工资+5000\n5. 买菜-10(未报销)\n6. 打车-50（公司报销）")
+		msg := tgbotapi.NewMessage(chatID, "请回复(Reply)本消息，增加记录\n\n"+parser.Usage)
 		_, err := h.svcCtx.Bot.Send(msg)
 		if err != nil {
 			return err
 		}
 		return nil
 
+	case "accounting_export":
+		req, err := parseExportArgs(message.CommandArguments())
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, err := h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		if err := h.accountingLogic.ExportAccounting(chatID, userID, req); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_search":
+		query, err := model.ParseSearchQuery(message.CommandArguments(), time.Now())
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%v\n\n%s", err, searchUsage))
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		if err := h.accountingLogic.SearchRecords(chatID, userID, query); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_report":
+		req, err := parseReportArgs(message.CommandArguments(), time.Now())
+		if err == nil {
+			err = h.accountingLogic.SendReport(chatID, userID, req)
+		}
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_audit":
+		if err := h.accountingLogic.ShowAudit(chatID, userID, strings.TrimSpace(message.CommandArguments())); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_chart":
+		kind := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+		if err := h.accountingLogic.SendAccountingCharts(chatID, userID, kind); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "fx_set":
+		return h.handleFxSet(message)
+
+	case "fx_list":
+		return h.exchangeRateLogic.ListRates(chatID, userID)
+
+	case "reimburse":
+		return h.accountingLogic.ListReimbursements(chatID, userID)
+
+	case "recurring":
+		if err := h.handleRecurring(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "receipt":
+		if err := h.accountingLogic.SendReceipt(chatID, userID, strings.TrimSpace(message.CommandArguments())); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "goal":
+		if err := h.handleGoal(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "digest":
+		if err := h.handleDigest(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "owe", "lent":
+		if err := h.handleDebt(message, command == "lent"); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "privacy":
+		if err := h.handlePrivacy(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_share":
+		if err := h.handleShare(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_link":
+		var err error
+		switch {
+		case message.Chat.IsPrivate():
+			err = h.accountingLogic.ListLinks(userID)
+		case strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "off"):
+			err = h.accountingLogic.UnlinkLedger(chatID, userID)
+		default:
+			err = h.accountingLogic.LinkLedger(chatID, userID, message.Chat.Title)
+		}
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_inline":
+		off := strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "off")
+		if err := h.accountingLogic.SetInlineTarget(chatID, userID, message.Chat.Title, off); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "accounting_group":
+		if message.Chat.IsPrivate() {
+			msg := tgbotapi.NewMessage(chatID, "请在群里使用 /accounting_group 查看群汇总")
+			_, err := h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return h.accountingLogic.GroupSummary(chatID)
+
+	case "wallets":
+		if err := h.handleWallets(message); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "transfer":
+		transfer, err := parseTransferArgs(strings.Fields(message.CommandArguments()))
+		if err == nil {
+			err = h.accountingLogic.Transfer(chatID, userID, transfer)
+		}
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
+	case "debts":
+		if err := h.debtLogic.ListDebts(chatID, *debtParty(message.From)); err != nil {
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+
 	default:
 		msg := tgbotapi.NewMessage(chatID, "未知命令，请使用 /help 查看可用命令")
 		_, err := h.svcCtx.Bot.Send(msg)
@@ -229,6 +656,53 @@ func (h *DinnerHandler) handleCommand(message *tgbotapi.Message) error {
 	}
 }
 
+// 处理上传的文件，目前只用于导入支付宝/微信账单
+// 群组中只有回复机器人或说明中带"导入"时才处理，避免误触发
+func (h *DinnerHandler) handleDocument(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	isReplyToBot := message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.IsBot
+	if !message.Chat.IsPrivate() && !isReplyToBot && !strings.Contains(message.Caption, "导入") {
+		return nil
+	}
+
+	if err := h.accountingLogic.PreviewImport(chatID, userID, message.Document); err != nil {
+		msg := tgbotapi.NewMessage(chatID, err.Error())
+		_, _ = h.svcCtx.Bot.Send(msg)
+		return err
+	}
+	return nil
+}
+
+// 处理带小票照片的记账，例如说明为 "超市 -86.5" 的照片
+func (h *DinnerHandler) handleReceiptPhoto(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	if strings.TrimSpace(message.Caption) == "" {
+		msg := tgbotapi.NewMessage(chatID, "请在照片说明中写上账目，例如: 超市 -86.5")
+		_, err := h.svcCtx.Bot.Send(msg)
+		return err
+	}
+	entry, err := parser.ParseEntry(message.Caption, time.Now())
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%v\n\n%s", err, parser.Usage))
+		_, err := h.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	// 同一张照片有多个尺寸，保存最大的一张
+	req := entry.Request()
+	req.ReceiptFileID = message.Photo[len(message.Photo)-1].FileID
+	if err := h.accountingLogic.AddExpense(chatID, userID, req); err != nil {
+		msg := tgbotapi.NewMessage(chatID, err.Error())
+		_, _ = h.svcCtx.Bot.Send(msg)
+		return err
+	}
+	return nil
+}
+
 // 处理收入金额回复
 func (h *DinnerHandler) handleIncomeReply(message *tgbotapi.Message) error {
 	chatID := message.Chat.ID
@@ -237,187 +711,532 @@ func (h *DinnerHandler) handleIncomeReply(message *tgbotapi.Message) error {
 	// 清除等待状态
 	delete(h.waitingForIncomeAmount, userID)
 	
-	// 解析收入金额
-	income, err := strconv.ParseFloat(message.Text, 64)
-	if err != nil {
-		msg := tgbotapi.NewMessage(chatID, "请输入有效的金额数字")
+	// 解析收入金额，可附带币种作为本周期的本位币，例如 "5000 USD"
+	currency, text := model.ExtractCurrency(message.Text)
+	income, err := model.ParseAmount(text)
+	if err != nil || income < 0 {
+		msg := tgbotapi.NewMessage(chatID, "请输入有效的金额数字（最多两位小数）")
 		_, err := h.svcCtx.Bot.Send(msg)
 		return err
 	}
 	
 	// 开始记账周期
-	return h.accountingLogic.StartAccounting(chatID, userID, income)
+	return h.accountingLogic.StartAccounting(chatID, userID, &model.AccountingStartRequest{
+		Income:       income,
+		BaseCurrency: currency,
+	})
 }
 
-// 处理支出金额回复
-func (h *DinnerHandler) handleExpenseReply(message *tgbotapi.Message) error {
+// 处理设置汇率命令，格式: /fx_set USD 7.2 [2026-10-01]
+func (h *DinnerHandler) handleFxSet(message *tgbotapi.Message) error {
 	chatID := message.Chat.ID
 	userID := message.From.ID
-	
-	// 清除等待状态
-	delete(h.waitingForExpenseAmount, userID)
-	
-	// 解析支出金额和描述
-	text := message.Text
-	amount, description, err := parseExpenseAmountAndDescription(text)
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 2 || len(args) > 3 {
+		msg := tgbotapi.NewMessage(chatID, "用法: /fx_set USD 7.2 [2026-10-01]\n汇率表示 1 单位外币折合多少人民币，日期默认为今天")
+		_, err := h.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	currency, ok := model.NormalizeCurrency(args[0])
+	if !ok {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("不支持的币种: %s，目前支持 %s", args[0], strings.Join(model.SupportedCurrencies, "/")))
+		_, err := h.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	rate, err := model.ParseRate(args[1])
 	if err != nil {
 		msg := tgbotapi.NewMessage(chatID, err.Error())
 		_, err := h.svcCtx.Bot.Send(msg)
 		return err
 	}
-	
-	// 添加记录
-	return h.accountingLogic.AddExpense(chatID, userID, amount, description)
+
+	date := time.Now()
+	if len(args) == 3 {
+		date, err = time.ParseInLocation("2006-01-02", args[2], time.Local)
+		if err != nil {
+			msg := tgbotapi.NewMessage(chatID, "日期格式应为 2006-01-02")
+			_, err := h.svcCtx.Bot.Send(msg)
+			return err
+		}
+	}
+
+	return h.exchangeRateLogic.SetRate(chatID, userID, currency, rate, date)
 }
 
-// 解析支出金额和描述
-func parseExpenseAmountAndDescription(text string) (float64, string, error) {
-	// 支持多种格式：
-	// 1. "100 午餐" - 标准格式
-	// 2. "午餐-100" - 描述在前，金额在后
-	// 3. "-100 午餐" - 带负号的金额
-	// 4. "午餐 -100" - 描述在前，带负号的金额
-	// 5. "买菜-10(未报销)" - 带括号备注
-	// 6. "买菜-10（未报销）" - 带中文括号备注
-	
-	// 首先尝试标准格式（金额在前）
-	parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
-	amount, err := strconv.ParseFloat(parts[0], 64)
-	if err == nil {
-		description := "未知项目"
-		if len(parts) > 1 {
-			description = strings.TrimSpace(parts[1])
+// 处理 /recurring 子命令
+func (h *DinnerHandler) handleRecurring(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		return h.recurringLogic.ListRules(chatID, userID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		rule, err := parseRecurringArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return h.recurringLogic.AddRule(chatID, userID, rule)
+	case "list":
+		return h.recurringLogic.ListRules(chatID, userID)
+	case "pause", "resume", "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("用法: /recurring %s <序号>", args[0])
 		}
-		return amount, description, nil
+		if strings.ToLower(args[0]) == "delete" {
+			return h.recurringLogic.DeleteRule(chatID, userID, args[1])
+		}
+		return h.recurringLogic.SetPaused(chatID, userID, args[1], strings.ToLower(args[0]) == "pause")
 	}
-	
-	// 尝试其他格式
-	// 1. 检查是否包含负号
-	if strings.Contains(text, "-") {
-		// 分割负号前后的内容
-		parts := strings.SplitN(text, "-", 2)
-		if len(parts) == 2 {
-			// 尝试解析金额（可能包含括号备注）
-			amountStr := parts[1]
-			// 移除括号中的备注
-			amountStr = regexp.MustCompile(`[（(].*?[)）]`).ReplaceAllString(amountStr, "")
-			amountStr = strings.TrimSpace(amountStr)
-			
-			amount, err := strconv.ParseFloat(amountStr, 64)
-			if err == nil {
-				// 获取描述和备注
-				description := strings.TrimSpace(parts[0])
-				if description == "" {
-					description = "未知项目"
-				}
-				
-				// 提取括号中的备注
-				noteMatch := regexp.MustCompile(`[（(](.*?)[)）]`).FindStringSubmatch(text)
-				if len(noteMatch) > 1 {
-					description = fmt.Sprintf("%s(%s)", description, noteMatch[1])
-				}
-				
-				return -amount, description, nil
+	return errors.New(recurringUsage)
+}
+
+const recurringUsage = "用法:\n" +
+	"/recurring add 房租 -3500 monthly 5 - 每月5日记支出\n" +
+	"/recurring add 工资 +8000 monthly 10 - 每月10日记收入\n" +
+	"/recurring add 会员 -25 weekly 1 - 每周一记支出\n" +
+	"/recurring list - 查看规则\n" +
+	"/recurring pause|resume|delete <序号> - 暂停、恢复或删除"
+
+// 解析 /recurring add 的参数: 描述 金额[币种] 频率 [日期]
+// 描述和金额与普通记账使用同样的语法，金额不带符号时按支出处理
+func parseRecurringArgs(args []string) (*model.RecurringRule, error) {
+	// 从后往前找频率，频率之前是记账条目
+	for i := len(args) - 1; i >= 1; i-- {
+		frequency, ok := model.NormalizeFrequency(args[i])
+		if !ok {
+			continue
+		}
+		if len(args) > i+2 {
+			break
+		}
+
+		rule := &model.RecurringRule{Frequency: frequency}
+		if len(args) == i+2 {
+			day, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("日期应为数字: %s", args[i+1])
 			}
+			rule.Day = day
+		}
+
+		entry, err := parser.ParseEntry(strings.Join(args[:i], " "), time.Now())
+		if err != nil {
+			return nil, err
 		}
+		req := entry.Request()
+		rule.Amount = req.Amount
+		rule.Currency = req.Currency
+		rule.Description = req.Description
+		return rule, nil
 	}
-	
-	// 2. 检查是否包含加号
-	if strings.Contains(text, "+") {
-		// 分割加号前后的内容
-		parts := strings.SplitN(text, "+", 2)
-		if len(parts) == 2 {
-			// 尝试解析金额（可能包含括号备注）
-			amountStr := parts[1]
-			// 移除括号中的备注
-			amountStr = regexp.MustCompile(`[（(].*?[)）]`).ReplaceAllString(amountStr, "")
-			amountStr = strings.TrimSpace(amountStr)
-			
-			amount, err := strconv.ParseFloat(amountStr, 64)
-			if err == nil {
-				// 获取描述和备注
-				description := strings.TrimSpace(parts[0])
-				if description == "" {
-					description = "未知项目"
-				}
-				
-				// 提取括号中的备注
-				noteMatch := regexp.MustCompile(`[（(](.*?)[)）]`).FindStringSubmatch(text)
-				if len(noteMatch) > 1 {
-					description = fmt.Sprintf("%s(%s)", description, noteMatch[1])
-				}
-				
-				return amount, description, nil
-			}
+	return nil, errors.New(recurringUsage)
+}
+
+// 处理 /goal 子命令
+func (h *DinnerHandler) handleGoal(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		return h.accountingLogic.ListGoals(chatID, userID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return h.accountingLogic.ListGoals(chatID, userID)
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("用法: /goal delete <序号>")
+		}
+		return h.accountingLogic.DeleteGoal(chatID, userID, args[1])
+	case "save":
+		if len(args) != 3 {
+			return fmt.Errorf("用法: /goal save <序号> <金额>")
+		}
+		amount, err := model.ParseAmount(args[2])
+		if err != nil {
+			return err
 		}
+		return h.accountingLogic.SaveToGoal(chatID, userID, args[1], amount)
+	case "add":
+		args = args[1:]
 	}
-	
-	// 如果所有格式都不匹配，返回错误
-	return 0, "", fmt.Errorf("无法解析金额和描述")
+
+	goal, err := parseGoalArgs(args)
+	if err != nil {
+		return err
+	}
+	return h.accountingLogic.AddGoal(chatID, userID, goal)
 }
 
-// 提取消息中的金额
-func extractAmountsFromMessage(text string) []struct {
-	Amount      float64
-	Description string
-} {
-	var results []struct {
-		Amount      float64
-		Description string
-	}
-
-	// 匹配格式：数字前有+或-号，或者支出/收入关键词附近的数字
-	// 例如：+100, -50, 支出20, 收入30
-	// 支持小数点
-	expensePattern := regexp.MustCompile(`[-]?\d+(\.\d+)?`)
-	parts := strings.Split(text, ",")
-	
-	for _, part := range parts {
-		// 查找所有的数字
-		matches := expensePattern.FindAllString(part, -1)
-		for _, match := range matches {
-			amount, err := strconv.ParseFloat(match, 64)
-			if err == nil {
-				// 如果是数字，判断是支出还是收入
-				var description string
-				
-				// 默认情况下，如果数字前有-号，或者文本中包含"支出"关键词，则视为支出
-				if strings.Contains(match, "-") || strings.Contains(part, "支出") {
-					if amount > 0 {
-						amount = -amount // 确保支出为负数
-					}
-					description = strings.TrimSpace(strings.ReplaceAll(part, match, ""))
-					description = strings.ReplaceAll(description, "支出", "")
-				} else if strings.Contains(match, "+") || strings.Contains(part, "收入") {
-					// 如果数字前有+号，或者文本中包含"收入"关键词，则视为收入
-					if amount < 0 {
-						amount = -amount // 确保收入为正数
-					}
-					description = strings.TrimSpace(strings.ReplaceAll(part, match, ""))
-					description = strings.ReplaceAll(description, "收入", "")
-				} else {
-					// 没有明确标识，默认为支出
-					if amount > 0 {
-						amount = -amount
-					}
-					description = strings.TrimSpace(strings.ReplaceAll(part, match, ""))
-				}
-				
-				// 清理description中的无用字符
-				description = strings.Trim(description, ":：,，、. ")
-				if description == "" {
-					description = "未知项目"
-				}
-				
-				results = append(results, struct {
-					Amount      float64
-					Description string
-				}{Amount: amount, Description: description})
+const goalUsage = "用法:\n" +
+	"/goal 旅行基金 10000 2027-02-01 - 创建目标（截止日期可省略）\n" +
+	"/goal - 查看进度\n" +
+	"/goal save <序号> <金额> - 手动存入\n" +
+	"/goal delete <序号> - 删除目标\n" +
+	"结束记账周期时可以将结余存入目标"
+
+// 解析创建储蓄目标的参数: 名称 金额 [截止日期]
+func parseGoalArgs(args []string) (*model.SavingsGoal, error) {
+	goal := &model.SavingsGoal{}
+	if n := len(args); n > 0 {
+		if deadline, err := time.ParseInLocation("2006-01-02", args[n-1], time.Local); err == nil {
+			goal.Deadline = deadline
+			args = args[:n-1]
+		}
+	}
+	if len(args) < 2 {
+		return nil, errors.New(goalUsage)
+	}
+	target, err := model.ParseAmount(args[len(args)-1])
+	if err != nil {
+		return nil, fmt.Errorf("%v\n\n%s", err, goalUsage)
+	}
+	if target <= 0 {
+		return nil, fmt.Errorf("目标金额必须大于 0")
+	}
+	goal.Name = strings.Join(args[:len(args)-1], " ")
+	goal.Target = target
+	return goal, nil
+}
+
+// 处理 /wallets 子命令
+func (h *DinnerHandler) handleWallets(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		return h.accountingLogic.ShowWallets(chatID, userID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "default":
+		if len(args) != 2 {
+			return errors.New(walletsUsage)
+		}
+		wallet, ok := model.NormalizeWallet(args[1])
+		if !ok {
+			return fmt.Errorf("未知的钱包: %s\n\n%s", args[1], walletsUsage)
+		}
+		return h.accountingLogic.SetDefaultWallet(chatID, userID, wallet)
+	case "set":
+		if len(args) != 3 {
+			return errors.New(walletsUsage)
+		}
+		wallet, ok := model.NormalizeWallet(args[1])
+		if !ok {
+			return fmt.Errorf("未知的钱包: %s\n\n%s", args[1], walletsUsage)
+		}
+		amount, currency, err := parseWalletAmount(args[2])
+		if err != nil {
+			return err
+		}
+		return h.accountingLogic.SetWalletBalance(chatID, userID, wallet, amount, currency)
+	}
+	return errors.New(walletsUsage)
+}
+
+const walletsUsage = "用法:\n" +
+	"/wallets - 查看各钱包余额\n" +
+	"/wallets default 支付宝 - 设置默认钱包\n" +
+	"/wallets set 银行卡 5000 - 校准钱包余额\n" +
+	"钱包: 现金(cash)、银行卡(card)、支付宝(alipay)、微信(wechat)、usdt"
+
+const transferUsage = "用法: /transfer <金额> <转出钱包> <转入钱包> [到账金额] [备注]\n" +
+	"例如:\n" +
+	"/transfer 500 银行卡 支付宝\n" +
+	"/transfer 3600 银行卡 usdt 500U 换U - 跨币种转账需要写到账金额"
+
+// 解析 /transfer 的参数: 金额[币种] 转出钱包 转入钱包 [到账金额[币种]] [备注]
+func parseTransferArgs(args []string) (*model.WalletTransfer, error) {
+	if len(args) < 3 {
+		return nil, errors.New(transferUsage)
+	}
+	amount, currency, err := parseWalletAmount(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%v\n\n%s", err, transferUsage)
+	}
+	from, ok := model.NormalizeWallet(args[1])
+	if !ok {
+		return nil, fmt.Errorf("未知的钱包: %s\n\n%s", args[1], walletsUsage)
+	}
+	to, ok := model.NormalizeWallet(args[2])
+	if !ok {
+		return nil, fmt.Errorf("未知的钱包: %s\n\n%s", args[2], walletsUsage)
+	}
+
+	transfer := &model.WalletTransfer{From: from, To: to, Amount: amount, Currency: currency}
+	rest := args[3:]
+	if len(rest) > 0 {
+		if toAmount, toCurrency, err := parseWalletAmount(rest[0]); err == nil {
+			transfer.ToAmount = toAmount
+			transfer.ToCurrency = toCurrency
+			rest = rest[1:]
+		}
+	}
+	transfer.Note = strings.Join(rest, " ")
+	return transfer, nil
+}
+
+// 解析带可选币种的金额，例如 "500"、"500U"、"12USD"，没有币种时返回空字符串
+func parseWalletAmount(text string) (model.Money, string, error) {
+	currency, rest := model.ExtractCurrency(text)
+	amount, err := model.ParseAmount(rest)
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, currency, nil
+}
+
+// 处理 /privacy，不带参数时显示当前设置
+func (h *DinnerHandler) handlePrivacy(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	switch arg := strings.ToLower(strings.TrimSpace(message.CommandArguments())); arg {
+	case "":
+		return h.accountingLogic.ShowPrivacy(chatID, userID)
+	case model.PrivacyDM, model.PrivacyGroup:
+		return h.accountingLogic.SetPrivacy(chatID, userID, arg)
+	default:
+		return fmt.Errorf("用法: /privacy [dm|group]\ndm - 摘要、历史和明细私聊发送\ngroup - 在群里显示")
+	}
+}
+
+// 处理 /accounting_share，回复对方的消息共享账本，带 remove 参数时取消共享
+func (h *DinnerHandler) handleShare(message *tgbotapi.Message) error {
+	reply := message.ReplyToMessage
+	if reply == nil || reply.From == nil || reply.From.IsBot {
+		return h.accountingLogic.ShowPrivacy(message.Chat.ID, message.From.ID)
+	}
+	share := &model.LedgerShare{
+		OwnerID:    message.From.ID,
+		OwnerName:  message.From.FirstName,
+		ViewerID:   reply.From.ID,
+		ViewerName: reply.From.FirstName,
+	}
+	revoke := strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "remove")
+	return h.accountingLogic.ShareLedger(message.Chat.ID, share, revoke)
+}
+
+// 处理 /owe 和 /lent，对方可以是 @用户名、点选的用户或被回复消息的发送者
+func (h *DinnerHandler) handleDebt(message *tgbotapi.Message, lent bool) error {
+	usage := debtUsage(message.Command())
+	args := strings.TrimSpace(message.CommandArguments())
+
+	var counterparty *model.DebtParty
+	if fields := strings.Fields(args); len(fields) > 0 && strings.HasPrefix(fields[0], "@") && len(fields[0]) > 1 {
+		counterparty = &model.DebtParty{Username: strings.TrimPrefix(fields[0], "@")}
+		args = strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+	}
+	// 没有用户名的用户只能通过点选提及，提及文本是对方的名字
+	for _, entity := range message.Entities {
+		if counterparty == nil && entity.Type == "text_mention" && entity.User != nil {
+			counterparty = debtParty(entity.User)
+			text := utf16.Encode([]rune(message.Text))
+			name := string(utf16.Decode(text[entity.Offset : entity.Offset+entity.Length]))
+			args = strings.TrimSpace(strings.Replace(args, name, "", 1))
+		}
+	}
+	if counterparty == nil && message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && !message.ReplyToMessage.From.IsBot {
+		counterparty = debtParty(message.ReplyToMessage.From)
+	}
+	if counterparty == nil || args == "" {
+		return errors.New(usage)
+	}
+
+	entry, err := parser.ParseEntry(args, time.Now())
+	if err != nil {
+		return fmt.Errorf("%v\n\n%s", err, usage)
+	}
+	req := entry.Request()
+	if entry.Description == parser.DefaultDescription && entry.Note == "" {
+		req.Description = "借款"
+	}
+	return h.debtLogic.AddDebt(message.Chat.ID, *debtParty(message.From), *counterparty, lent, req.Amount.Abs(), req.Currency, req.Description)
+}
+
+func debtUsage(command string) string {
+	return fmt.Sprintf("用法: /%s @用户名 金额 [说明]，例如 /%s @alice 50 奶茶\n也可以回复对方的消息发送 /%s 50 奶茶", command, command, command)
+}
+
+// 将 Telegram 用户转换为借款的一方
+func debtParty(user *tgbotapi.User) *model.DebtParty {
+	return &model.DebtParty{UserID: user.ID, Username: user.UserName, Name: user.FirstName}
+}
+
+// 处理 /digest 子命令
+func (h *DinnerHandler) handleDigest(message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		return h.digestLogic.ListSettings(chatID, userID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off", "now":
+		frequency := ""
+		if len(args) > 1 {
+			var ok bool
+			if frequency, ok = model.NormalizeFrequency(args[1]); !ok || frequency == model.FrequencyMonthly {
+				return errors.New(digestUsage)
 			}
 		}
+		if strings.ToLower(args[0]) == "off" {
+			return h.digestLogic.Unsubscribe(chatID, userID, frequency)
+		}
+		if frequency == "" {
+			frequency = model.FrequencyDaily
+		}
+		return h.digestLogic.SendNow(chatID, userID, frequency)
 	}
-	
-	return results
+
+	setting, err := parseDigestArgs(args)
+	if err != nil {
+		return err
+	}
+	return h.digestLogic.Subscribe(chatID, userID, setting)
+}
+
+const digestUsage = "用法:\n" +
+	"/digest daily 21:00 [dm|group] - 每天 21:00 发送当天摘要\n" +
+	"/digest weekly 周日 20:00 [dm|group] - 每周日 20:00 发送本周摘要\n" +
+	"/digest now [daily|weekly] - 立即查看摘要\n" +
+	"/digest off [daily|weekly] - 关闭摘要\n" +
+	"/digest - 查看当前设置\n" +
+	"dm 为私聊发送，group 为发送到本群（默认）"
+
+// 解析 /digest 的订阅参数: 频率 [星期] 时间 [dm|group]
+func parseDigestArgs(args []string) (*model.DigestSetting, error) {
+	frequency, ok := model.NormalizeFrequency(args[0])
+	if !ok || frequency == model.FrequencyMonthly {
+		return nil, errors.New(digestUsage)
+	}
+	setting := &model.DigestSetting{Frequency: frequency, Target: model.DigestTargetGroup}
+	args = args[1:]
+
+	if n := len(args); n > 0 {
+		switch strings.ToLower(args[n-1]) {
+		case model.DigestTargetDM, "私聊":
+			setting.Target = model.DigestTargetDM
+			args = args[:n-1]
+		case model.DigestTargetGroup, "群":
+			args = args[:n-1]
+		}
+	}
+	if frequency == model.FrequencyWeekly && len(args) == 2 {
+		weekday, ok := model.ParseWeekday(args[0])
+		if !ok {
+			return nil, fmt.Errorf("无效的星期: %s", args[0])
+		}
+		setting.Weekday = weekday
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return nil, errors.New(digestUsage)
+	}
+	hour, minute, err := model.ParseClock(args[0])
+	if err != nil {
+		return nil, err
+	}
+	setting.Hour = hour
+	setting.Minute = minute
+	return setting, nil
+}
+
+var searchUsage = "用法: /accounting_search [关键词] [收入|支出] [分类] [金额] [日期]\n" +
+	"金额: >100、<500、100-500、35\n" +
+	"日期: 昨天、9/1~9/30、2026-09-01~2026-09-30\n" +
+	"分类: " + strings.Join(model.Categories, "、") + "\n" +
+	"例如: /accounting_search 打车 >30 9/1~9/30"
+
+const reportUsage = "用法: /accounting_report [month 2026-09 | year 2026]，不带参数时为本月"
+
+// 解析 /accounting_report 的参数
+// 支持: month [2026-09] | year [2026] | 2026-09 | 2026，省略日期时为本月或本年
+func parseReportArgs(args string, now time.Time) (*model.AccountingReportRequest, error) {
+	fields := strings.Fields(strings.ToLower(args))
+	req := &model.AccountingReportRequest{Period: model.ReportMonth}
+	if len(fields) > 0 {
+		switch fields[0] {
+		case model.ReportMonth, "月":
+			fields = fields[1:]
+		case model.ReportYear, "年":
+			req.Period = model.ReportYear
+			fields = fields[1:]
+		}
+	}
+
+	switch len(fields) {
+	case 0:
+		req.Start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if req.Period == model.ReportYear {
+			req.Start = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		}
+	case 1:
+		if start, err := time.ParseInLocation("2006-01", fields[0], now.Location()); err == nil && req.Period == model.ReportMonth {
+			req.Start = start
+		} else if start, err := time.ParseInLocation("2006", fields[0], now.Location()); err == nil {
+			req.Period = model.ReportYear
+			req.Start = start
+		} else {
+			return nil, errors.New(reportUsage)
+		}
+	default:
+		return nil, errors.New(reportUsage)
+	}
+	if req.Start.After(now) {
+		return nil, fmt.Errorf("不能查看未来的报告")
+	}
+	return req, nil
+}
+
+// 解析 /accounting_export 的参数
+// 支持: [周期ID] | [开始日期 结束日期] | [开始日期~结束日期]，可在末尾附加 csv 或 xlsx
+func parseExportArgs(args string) (*model.AccountingExportRequest, error) {
+	req := &model.AccountingExportRequest{Format: "csv"}
+	fields := strings.Fields(strings.NewReplacer("~", " ", "～", " ", "至", " ").Replace(args))
+
+	if n := len(fields); n > 0 {
+		switch strings.ToLower(fields[n-1]) {
+		case "csv", "xlsx":
+			req.Format = strings.ToLower(fields[n-1])
+			fields = fields[:n-1]
+		}
+	}
+
+	switch len(fields) {
+	case 0:
+	case 1:
+		req.CycleID = fields[0]
+	case 2:
+		from, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("日期格式应为 2006-01-02")
+		}
+		to, err := time.ParseInLocation("2006-01-02", fields[1], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("日期格式应为 2006-01-02")
+		}
+		if to.Before(from) {
+			return nil, fmt.Errorf("结束日期不能早于开始日期")
+		}
+		req.From, req.To = from, to
+	default:
+		return nil, fmt.Errorf("用法: /accounting_export [周期ID | 2026-09-01 2026-09-30] [csv|xlsx]")
+	}
+	return req, nil
 }
 
 // 处理普通记账消息
@@ -425,25 +1244,81 @@ func (h *DinnerHandler) handleAccountingMessage(message *tgbotapi.Message) error
 	chatID := message.Chat.ID
 	userID := message.From.ID
 	
-	// 提取消息中的金额
-	amountItems := extractAmountsFromMessage(message.Text)
-	if len(amountItems) == 0 {
+	// 提取消息中的记账条目
+	entries, err := parser.ParseEntries(message.Text, time.Now())
+	if errors.Is(err, parser.ErrNoAmount) {
 		// 没有找到金额，忽略
 		return nil
 	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, err.Error())
+		_, _ = h.svcCtx.Bot.Send(msg)
+		return err
+	}
 	
-	// 添加所有找到的记账项目
-	for _, item := range amountItems {
-		if err := h.accountingLogic.AddExpense(chatID, userID, item.Amount, item.Description); err != nil {
+	// 多条记账先预览，确认后一次性写入
+	if len(entries) > 1 {
+		if err := h.accountingLogic.PreviewBatch(chatID, userID, message.MessageID, message.Text, entryRequests(entries)); err != nil {
+			if strings.Contains(err.Error(), "找不到活跃的记账周期") {
+				msg := tgbotapi.NewMessage(chatID, "请先使用 /accounting_start 命令开始记账周期")
+				_, _ = h.svcCtx.Bot.Send(msg)
+				return err
+			}
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
+			return err
+		}
+		return nil
+	}
+
+	// 添加找到的记账项目
+	for _, entry := range entries {
+		if err := h.accountingLogic.AddExpense(chatID, userID, entry.Request()); err != nil {
 			// 如果是因为没有活跃的记账周期而失败，告知用户
 			if strings.Contains(err.Error(), "找不到活跃的记账周期") {
 				msg := tgbotapi.NewMessage(chatID, "请先使用 /accounting_start 命令开始记账周期")
 				_, _ = h.svcCtx.Bot.Send(msg)
 				return err
 			}
+			// 其他错误（如缺少汇率）同样告知用户
+			msg := tgbotapi.NewMessage(chatID, err.Error())
+			_, _ = h.svcCtx.Bot.Send(msg)
 			return err
 		}
 	}
 	
 	return nil
-} 
\ No newline at end of file
+} 
+
+// 处理修改批量记账的回复，替换原来的内容并重新预览
+func (h *DinnerHandler) handleBatchEdit(message *tgbotapi.Message, batchID string) error {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	entries, err := parser.ParseEntries(message.Text, time.Now())
+	if err == nil {
+		err = h.accountingLogic.ReviseBatch(chatID, userID, batchID, message.MessageID, message.Text, entryRequests(entries))
+	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%v\n\n%s", err, parser.Usage))
+		_, _ = h.svcCtx.Bot.Send(msg)
+		return err
+	}
+	return nil
+}
+
+// 批量记账已确认或取消时，不再等待修改
+func (h *DinnerHandler) clearBatchEdit(userID int64, batchID string) {
+	if edit, ok := h.waitingForBatchEdit[userID]; ok && edit.batchID == batchID {
+		delete(h.waitingForBatchEdit, userID)
+	}
+}
+
+// 将解析出的记账条目转换为记账请求
+func entryRequests(entries []*parser.Entry) []*model.AccountingExpenseRequest {
+	reqs := make([]*model.AccountingExpenseRequest, len(entries))
+	for i, entry := range entries {
+		reqs[i] = entry.Request()
+	}
+	return reqs
+}
@@ -0,0 +1,152 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// /accounting_audit 最多显示最近的事件数
+const auditShowLimit = 30
+
+// ShowAudit 显示记账周期的变更记录
+// ref 为空时查看当前周期，也可以是 /accounting_history 中的序号或周期ID
+func (l *AccountingLogic) ShowAudit(chatID int64, userID int64, ref string) error {
+	cycle, err := l.findAuditCycle(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	if !l.canViewCycle(cycle, userID) {
+		return fmt.Errorf("无权查看该记账周期")
+	}
+
+	key := auditKey(cycle.ID)
+	total, err := l.svcCtx.Redis.Llen(key)
+	if err != nil {
+		return fmt.Errorf("获取变更记录失败: %v", err)
+	}
+	values, err := l.svcCtx.Redis.Lrange(key, -auditShowLimit, -1)
+	if err != nil {
+		return fmt.Errorf("获取变更记录失败: %v", err)
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("🧾 记账周期 %s 至 %s 的变更记录:\n",
+		cycle.StartTime.Format("2006-01-02"), cycle.EndTime.Format("2006-01-02")))
+	if total == 0 {
+		msgText.WriteString("\n暂无变更记录（变更记录从升级后开始保存）")
+	}
+	if total > len(values) {
+		msgText.WriteString(fmt.Sprintf("共 %d 条，显示最近 %d 条\n", total, len(values)))
+	}
+	msgText.WriteString("\n")
+
+	// 同一个人只查询一次名字
+	names := make(map[int64]string)
+	for _, value := range values {
+		var event model.AuditEvent
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			log.Printf("解析变更记录失败: %v", err)
+			continue
+		}
+		name, ok := names[event.ActorID]
+		if !ok {
+			name = l.memberName(cycle.ChatID, event.ActorID)
+			names[event.ActorID] = name
+		}
+		msgText.WriteString(formatAuditEvent(&event, name))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.TrimRight(msgText.String(), "\n"))
+	return l.sendView(chatID, userID, msg)
+}
+
+// 查找要查看变更记录的周期
+func (l *AccountingLogic) findAuditCycle(chatID int64, userID int64, ref string) (*model.AccountingCycle, error) {
+	if ref == "" {
+		return l.getActiveCycle(chatID, userID)
+	}
+	if index, err := strconv.Atoi(ref); err == nil {
+		cycleIDs, err := l.getHistoryCycleIDs(chatID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if index < 1 || index > len(cycleIDs) {
+			return nil, fmt.Errorf("序号超出范围，共 %d 个记账周期", len(cycleIDs))
+		}
+		return l.getAccountingCycle(cycleIDs[index-1])
+	}
+	return l.getAccountingCycle(ref)
+}
+
+// 追加一条变更记录，失败时只记录日志，不影响记账本身
+func (l *AccountingLogic) audit(cycle *model.AccountingCycle, actorID int64, action string, recordID string, before string, after string, note string) {
+	event := &model.AuditEvent{
+		CycleID:  cycle.ID,
+		Action:   action,
+		ActorID:  actorID,
+		RecordID: recordID,
+		Before:   before,
+		After:    after,
+		Note:     note,
+		Time:     time.Now(),
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化变更记录失败: %v", err)
+		return
+	}
+	if _, err := l.svcCtx.Redis.Rpush(auditKey(cycle.ID), string(data)); err != nil {
+		log.Printf("保存变更记录失败: %v", err)
+	}
+}
+
+// 新增一条记录的审计事件
+func (l *AccountingLogic) auditRecord(cycle *model.AccountingCycle, actorID int64, record *model.AccountingRecord, note string) {
+	l.audit(cycle, actorID, model.AuditAdd, record.ID, "", describeRecord(record, cycle.Currency()), note)
+}
+
+func auditKey(cycleID string) string {
+	return fmt.Sprintf("accounting:audit:%s", cycleID)
+}
+
+// 记录的文字描述，例如 "10-18 12:30 午饭 -25.00 元"
+func describeRecord(record *model.AccountingRecord, base string) string {
+	return fmt.Sprintf("%s %s %s", record.Date.Format("01-02 15:04"), record.Description, formatRecordAmount(record, base, 1))
+}
+
+// 周期的文字描述，例如 "收入 3000.00 元，2026-10-01 至 2026-10-08，进行中"
+func describeCycle(cycle *model.AccountingCycle) string {
+	state := "进行中"
+	if !cycle.IsActive {
+		state = "已结束"
+	}
+	return fmt.Sprintf("收入 %s，%s 至 %s，%s",
+		formatMoney(cycle.Income, cycle.Currency()),
+		cycle.StartTime.Format("2006-01-02"), cycle.EndTime.Format("2006-01-02"), state)
+}
+
+// 一条变更记录的显示文本
+func formatAuditEvent(event *model.AuditEvent, actor string) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%s %s %s", event.Time.Format("01-02 15:04"), actor, model.AuditActionName(event.Action)))
+	switch {
+	case event.Before != "" && event.After != "":
+		text.WriteString(fmt.Sprintf(": %s → %s", event.Before, event.After))
+	case event.After != "":
+		text.WriteString(": " + event.After)
+	case event.Before != "":
+		text.WriteString(": " + event.Before)
+	}
+	if event.Note != "" {
+		text.WriteString(fmt.Sprintf("（%s）", event.Note))
+	}
+	text.WriteString("\n")
+	return text.String()
+}
@@ -0,0 +1,214 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 待确认批量记账的保存时间（秒）
+const batchExpireSeconds = 30 * 60
+
+// PreviewBatch 一条消息中有多条记账时，先发送预览表格，确认后再一次性写入
+// messageID 为用户发送的原始消息，修改时的提示回复这条消息
+func (l *AccountingLogic) PreviewBatch(chatID int64, userID int64, messageID int, text string, reqs []*model.AccountingExpenseRequest) error {
+	cycleID, err := l.getActiveCycleID(chatID, userID)
+	if err != nil {
+		return err
+	}
+	cycle, err := l.getCycleMeta(cycleID)
+	if err != nil {
+		return err
+	}
+	// 预览时就折算外币，缺少汇率等问题在确认前提示
+	records, err := l.buildBatchRecords(userID, cycle, reqs)
+	if err != nil {
+		return err
+	}
+
+	batch := &model.AccountingBatch{
+		ID:        newShortID(),
+		ChatID:    chatID,
+		UserID:    userID,
+		Text:      text,
+		MessageID: messageID,
+		Requests:  reqs,
+		CreatedAt: time.Now(),
+	}
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("序列化批量记账失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Setex(batchKey(batch.ID), string(payload), batchExpireSeconds); err != nil {
+		return fmt.Errorf("保存批量记账失败: %v", err)
+	}
+
+	base := cycle.Currency()
+	defaultWallet := l.getDefaultWallet(chatID, userID)
+	today := dayStart(time.Now())
+	var income, expense model.Money
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("📝 批量记账预览（共 %d 条）:\n\n", len(records)))
+	for i, record := range records {
+		line := fmt.Sprintf("%d. %s %s", i+1, record.Description, formatRecordAmount(record, base, 1))
+		if !dayStart(record.Date).Equal(today) {
+			line += " " + record.Date.Format("01-02")
+		}
+		if record.Wallet != defaultWallet {
+			line += " #" + model.WalletName(record.Wallet)
+		}
+		msgText.WriteString(line + "\n")
+		if record.Amount > 0 {
+			income += record.Amount
+		} else {
+			expense -= record.Amount
+		}
+	}
+	msgText.WriteString(fmt.Sprintf("\n合计: 收入 %s，支出 %s\n确认后一次性记入当前周期",
+		formatMoney(income, base), formatMoney(expense, base)))
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认", fmt.Sprintf("batch_confirm_%s", batch.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ 修改", fmt.Sprintf("batch_edit_%s", batch.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("batch_cancel_%s", batch.ID)),
+		),
+	)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ConfirmBatch 将待确认的批量记账一次性写入当前活跃周期，要么全部写入要么都不写入
+func (l *AccountingLogic) ConfirmBatch(chatID int64, userID int64, batchID string) error {
+	batch, err := l.getPendingBatch(batchID, userID)
+	if err != nil {
+		return err
+	}
+	cycleID, err := l.getActiveCycleID(batch.ChatID, batch.UserID)
+	if err != nil {
+		return err
+	}
+	cycle, err := l.getCycleMeta(cycleID)
+	if err != nil {
+		return err
+	}
+	records, err := l.buildBatchRecords(userID, cycle, batch.Requests)
+	if err != nil {
+		return err
+	}
+	// 先删除批次再写入，重复点击确认不会重复记账
+	if n, err := l.svcCtx.Redis.Del(batchKey(batchID)); err != nil || n == 0 {
+		return fmt.Errorf("批量记账已过期或已处理，请重新发送")
+	}
+	if err := l.appendRecords(cycle, records...); err != nil {
+		return err
+	}
+	for _, record := range records {
+		l.auditRecord(cycle, userID, record, "批量记账")
+	}
+
+	base := cycle.Currency()
+	summary := l.calculateSummary(cycle)
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ 已记录 %d 条\n\n"+
+			"📊 当前统计:\n"+
+			"💰 总收入: %s\n"+
+			"💸 总支出: %s\n"+
+			"💵 剩余金额: %s\n"+
+			"⏰ 剩余天数: %d 天",
+		len(records),
+		formatMoney(summary.TotalIncome, base),
+		formatMoney(summary.TotalExpense, base),
+		formatMoney(summary.Balance, base),
+		summary.DaysRemaining,
+	))
+	return l.sendView(chatID, userID, msg)
+}
+
+// EditBatch 发送原始内容，请用户回复修改后的内容，返回提示消息的ID
+// 提示回复用户的原始消息，群里只有发起人会看到强制回复
+func (l *AccountingLogic) EditBatch(chatID int64, userID int64, batchID string) (int, error) {
+	batch, err := l.getPendingBatch(batchID, userID)
+	if err != nil {
+		return 0, err
+	}
+	msg := tgbotapi.NewMessage(chatID, "✏️ 请回复(Reply)本消息，发送修改后的内容，每行一条:\n\n"+batch.Text)
+	msg.ReplyToMessageID = batch.MessageID
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+	sent, err := l.svcCtx.Bot.Send(msg)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// ReviseBatch 用修改后的内容替换待确认的批量记账，并重新预览
+func (l *AccountingLogic) ReviseBatch(chatID int64, userID int64, batchID string, messageID int, text string, reqs []*model.AccountingExpenseRequest) error {
+	if _, err := l.getPendingBatch(batchID, userID); err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Del(batchKey(batchID)); err != nil {
+		return fmt.Errorf("修改批量记账失败: %v", err)
+	}
+	return l.PreviewBatch(chatID, userID, messageID, text, reqs)
+}
+
+// CancelBatch 放弃待确认的批量记账
+func (l *AccountingLogic) CancelBatch(chatID int64, userID int64, batchID string) error {
+	if _, err := l.getPendingBatch(batchID, userID); err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Del(batchKey(batchID)); err != nil {
+		return fmt.Errorf("取消批量记账失败: %v", err)
+	}
+	msg := tgbotapi.NewMessage(chatID, "已取消批量记账")
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 按请求构建全部记录，任何一条失败时都不返回记录
+func (l *AccountingLogic) buildBatchRecords(userID int64, cycle *model.AccountingCycle, reqs []*model.AccountingExpenseRequest) ([]*model.AccountingRecord, error) {
+	now := time.Now()
+	records := make([]*model.AccountingRecord, 0, len(reqs))
+	for i, req := range reqs {
+		date := now
+		if !req.Date.IsZero() {
+			date = req.Date
+		}
+		record, err := l.newRecord(userID, cycle, req, date)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条: %v", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// 获取待确认的批量记账，只有发起人可以操作
+func (l *AccountingLogic) getPendingBatch(batchID string, userID int64) (*model.AccountingBatch, error) {
+	data, err := l.svcCtx.Redis.Get(batchKey(batchID))
+	if err != nil {
+		return nil, fmt.Errorf("获取批量记账失败: %v", err)
+	}
+	if data == "" {
+		return nil, fmt.Errorf("批量记账已过期或已处理，请重新发送")
+	}
+
+	var batch model.AccountingBatch
+	if err := json.Unmarshal([]byte(data), &batch); err != nil {
+		return nil, fmt.Errorf("解析批量记账失败: %v", err)
+	}
+	if batch.UserID != userID {
+		return nil, fmt.Errorf("只能操作自己发起的批量记账")
+	}
+	return &batch, nil
+}
+
+func batchKey(batchID string) string {
+	return fmt.Sprintf("accounting:batch:%s", batchID)
+}
@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/parser"
+	"github.com/qx/syft_robot/api/internal/store"
+	"github.com/qx/syft_robot/api/internal/svc"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// 模拟 Telegram Bot API，记录机器人发送的消息
+type fakeTelegram struct {
+	mu     sync.Mutex
+	nextID int
+	sent   []map[string]string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = r.ParseForm()
+	var result any = true
+	switch r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:] {
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "bot", "username": "test_bot"}
+	case "sendMessage":
+		f.nextID++
+		values := map[string]string{"message_id": fmt.Sprint(f.nextID)}
+		for key := range r.PostForm {
+			values[key] = r.PostForm.Get(key)
+		}
+		f.sent = append(f.sent, values)
+		result = map[string]any{"message_id": f.nextID, "date": 0, "chat": map[string]any{"id": 0}}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// 最近发送的一条消息
+func (f *fakeTelegram) last(t *testing.T) map[string]string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) == 0 {
+		t.Fatal("没有发送任何消息")
+	}
+	return f.sent[len(f.sent)-1]
+}
+
+func newTestAccountingLogic(t *testing.T) (*AccountingLogic, *fakeTelegram) {
+	t.Helper()
+	telegram := &fakeTelegram{}
+	server := httptest.NewServer(telegram)
+	t.Cleanup(server.Close)
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("NewBotAPIWithAPIEndpoint error = %v", err)
+	}
+
+	rds := redis.MustNewRedis(redis.RedisConf{Host: miniredis.RunT(t).Addr(), Type: redis.NodeType})
+	svcCtx := &svc.ServiceContext{
+		Bot:    bot,
+		Redis:  rds,
+		Ledger: store.NewRedisLedgerStore(rds),
+	}
+	return NewAccountingLogic(context.Background(), svcCtx), telegram
+}
+
+// 按记账语法解析批量记账的内容
+func batchRequests(t *testing.T, text string) []*model.AccountingExpenseRequest {
+	t.Helper()
+	entries, err := parser.ParseEntries(text, time.Now())
+	if err != nil {
+		t.Fatalf("ParseEntries(%q) error = %v", text, err)
+	}
+	reqs := make([]*model.AccountingExpenseRequest, len(entries))
+	for i, entry := range entries {
+		reqs[i] = entry.Request()
+	}
+	return reqs
+}
+
+// 预览 -> 修改 -> 确认，确认后只写入修改后的记录
+func TestBatchPreviewEditConfirm(t *testing.T) {
+	const chatID, userID, messageID, replyID = int64(-100), int64(42), 7, 20
+	l, telegram := newTestAccountingLogic(t)
+	if err := l.StartAccounting(chatID, userID, &model.AccountingStartRequest{Income: 100000}); err != nil {
+		t.Fatalf("StartAccounting error = %v", err)
+	}
+
+	if err := l.PreviewBatch(chatID, userID, messageID, "午饭 -25\n咖啡 -12", batchRequests(t, "午饭 -25\n咖啡 -12")); err != nil {
+		t.Fatalf("PreviewBatch error = %v", err)
+	}
+	preview := telegram.last(t)
+	if !strings.Contains(preview["text"], "共 2 条") || !strings.Contains(preview["text"], "支出 37.00 元") {
+		t.Errorf("预览内容 = %q", preview["text"])
+	}
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(preview["reply_markup"]), &markup); err != nil {
+		t.Fatalf("解析预览按钮失败: %v", err)
+	}
+	batchID := strings.TrimPrefix(*markup.InlineKeyboard[0][0].CallbackData, "batch_confirm_")
+
+	// 只有发起人可以修改
+	if _, err := l.EditBatch(chatID, userID+1, batchID); err == nil {
+		t.Error("EditBatch 其他用户 error = nil")
+	}
+	promptID, err := l.EditBatch(chatID, userID, batchID)
+	if err != nil {
+		t.Fatalf("EditBatch error = %v", err)
+	}
+	prompt := telegram.last(t)
+	if prompt["message_id"] != fmt.Sprint(promptID) || prompt["reply_to_message_id"] != fmt.Sprint(messageID) ||
+		!strings.Contains(prompt["text"], "咖啡 -12") {
+		t.Errorf("修改提示 = %v, promptID = %d", prompt, promptID)
+	}
+
+	// 修改后原批次失效，确认新批次
+	if err := l.ReviseBatch(chatID, userID, batchID, replyID, "午饭 -30\n咖啡 -12\n地铁 -4", batchRequests(t, "午饭 -30\n咖啡 -12\n地铁 -4")); err != nil {
+		t.Fatalf("ReviseBatch error = %v", err)
+	}
+	if err := l.ConfirmBatch(chatID, userID, batchID); err == nil {
+		t.Error("ConfirmBatch 原批次 error = nil")
+	}
+	if err := json.Unmarshal([]byte(telegram.last(t)["reply_markup"]), &markup); err != nil {
+		t.Fatalf("解析预览按钮失败: %v", err)
+	}
+	revisedID := strings.TrimPrefix(*markup.InlineKeyboard[0][0].CallbackData, "batch_confirm_")
+	if revisedID == batchID {
+		t.Fatal("修改后批次ID未变化")
+	}
+	// 再次修改时提示回复用户的修改消息
+	if _, err := l.EditBatch(chatID, userID, revisedID); err != nil || telegram.last(t)["reply_to_message_id"] != fmt.Sprint(replyID) {
+		t.Errorf("EditBatch 修改后的批次 error = %v, reply_to_message_id = %s", err, telegram.last(t)["reply_to_message_id"])
+	}
+
+	if err := l.ConfirmBatch(chatID, userID, revisedID); err != nil {
+		t.Fatalf("ConfirmBatch error = %v", err)
+	}
+	if text := telegram.last(t)["text"]; !strings.Contains(text, "已记录 3 条") {
+		t.Errorf("确认消息 = %q", text)
+	}
+	// 重复确认不会重复记账
+	if err := l.ConfirmBatch(chatID, userID, revisedID); err == nil {
+		t.Error("重复 ConfirmBatch error = nil")
+	}
+
+	cycle, err := l.getActiveCycle(chatID, userID)
+	if err != nil {
+		t.Fatalf("getActiveCycle error = %v", err)
+	}
+	if len(cycle.Records) != 3 {
+		t.Fatalf("记录数 = %d, want 3", len(cycle.Records))
+	}
+	if summary := l.calculateSummary(cycle); summary.TotalExpense != 4600 {
+		t.Errorf("总支出 = %s, want 46.00", summary.TotalExpense)
+	}
+	for _, record := range cycle.Records {
+		if record.Date.After(time.Now()) || record.Wallet == "" {
+			t.Errorf("记录 = %+v", record)
+		}
+	}
+}
+
+// 取消后不能再确认
+func TestBatchCancel(t *testing.T) {
+	const chatID, userID = int64(42), int64(42)
+	l, telegram := newTestAccountingLogic(t)
+	if err := l.StartAccounting(chatID, userID, &model.AccountingStartRequest{Income: 100000}); err != nil {
+		t.Fatalf("StartAccounting error = %v", err)
+	}
+	if err := l.PreviewBatch(chatID, userID, 1, "午饭 -25\n咖啡 -12", batchRequests(t, "午饭 -25\n咖啡 -12")); err != nil {
+		t.Fatalf("PreviewBatch error = %v", err)
+	}
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(telegram.last(t)["reply_markup"]), &markup); err != nil {
+		t.Fatalf("解析预览按钮失败: %v", err)
+	}
+	batchID := strings.TrimPrefix(*markup.InlineKeyboard[0][2].CallbackData, "batch_cancel_")
+
+	if err := l.CancelBatch(chatID, userID, batchID); err != nil {
+		t.Fatalf("CancelBatch error = %v", err)
+	}
+	if err := l.ConfirmBatch(chatID, userID, batchID); err == nil {
+		t.Error("取消后 ConfirmBatch error = nil")
+	}
+	cycle, err := l.getActiveCycle(chatID, userID)
+	if err != nil {
+		t.Fatalf("getActiveCycle error = %v", err)
+	}
+	if len(cycle.Records) != 0 {
+		t.Errorf("记录数 = %d, want 0", len(cycle.Records))
+	}
+}
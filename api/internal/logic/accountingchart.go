@@ -0,0 +1,402 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+const (
+	chartWidth   = 800
+	chartHeight  = 480
+	chartPadding = 40
+)
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartAxis       = color.RGBA{0x60, 0x60, 0x60, 0xff}
+	chartGrid       = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	chartExpense    = color.RGBA{0xe5, 0x3e, 0x3e, 0xff}
+	chartBalance    = color.RGBA{0x31, 0x82, 0xce, 0xff}
+)
+
+// errNoChartData 图表没有可绘制的数据，发送全部图表时跳过该图表
+var errNoChartData = errors.New("当前周期暂无支出记录")
+
+// 图片里不绘制文字，饼图颜色与说明文字中的色块 emoji 一一对应
+var chartPalette = []struct {
+	Color color.RGBA
+	Emoji string
+}{
+	{color.RGBA{0xdd, 0x2e, 0x44, 0xff}, "🟥"},
+	{color.RGBA{0xf4, 0x90, 0x0c, 0xff}, "🟧"},
+	{color.RGBA{0xfd, 0xcb, 0x58, 0xff}, "🟨"},
+	{color.RGBA{0x78, 0xb1, 0x59, 0xff}, "🟩"},
+	{color.RGBA{0x55, 0xac, 0xee, 0xff}, "🟦"},
+	{color.RGBA{0xaa, 0x8e, 0xd6, 0xff}, "🟪"},
+	{color.RGBA{0xc1, 0x69, 0x4f, 0xff}, "🟫"},
+	{color.RGBA{0x31, 0x37, 0x3d, 0xff}, "⬛"},
+}
+
+// SendAccountingCharts 为当前记账周期生成图表并以图片形式发送
+// kind 为 daily、category、balance 之一，为空时发送全部
+func (l *AccountingLogic) SendAccountingCharts(chatID int64, userID int64, kind string) error {
+	cycle, err := l.getActiveCycle(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if len(cycle.Records) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "当前周期暂无记账记录，无法生成图表")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	records := make([]*model.AccountingRecord, len(cycle.Records))
+	copy(records, cycle.Records)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+
+	charts := []struct {
+		Kind   string
+		Render func(*model.AccountingCycle, []*model.AccountingRecord) ([]byte, string, error)
+	}{
+		{"daily", renderDailyChart},
+		{"category", renderCategoryChart},
+		{"balance", renderBalanceChart},
+	}
+
+	target := l.viewChat(chatID, userID)
+	matched, sent := false, false
+	for _, chart := range charts {
+		if kind != "" && kind != chart.Kind {
+			continue
+		}
+		matched = true
+		data, caption, err := chart.Render(cycle, records)
+		if errors.Is(err, errNoChartData) {
+			// 只有指定了这个图表时才报错
+			if kind != "" {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("生成图表失败: %v", err)
+		}
+		photo := tgbotapi.NewPhoto(target, tgbotapi.FileBytes{Name: chart.Kind + ".png", Bytes: data})
+		photo.Caption = caption
+		if _, err := l.svcCtx.Bot.Send(photo); err != nil {
+			return err
+		}
+		sent = true
+	}
+	if !matched {
+		return fmt.Errorf("未知的图表类型: %s，可选 daily、category、balance", kind)
+	}
+	if !sent {
+		return errNoChartData
+	}
+	l.notifyViewSent(chatID, target)
+	return nil
+}
+
+// 每日支出柱状图
+func renderDailyChart(cycle *model.AccountingCycle, records []*model.AccountingRecord) ([]byte, string, error) {
+	start := dayStart(cycle.StartTime)
+	end := dayStart(cycle.EndTime)
+	if today := dayStart(time.Now()); today.Before(end) {
+		end = today
+	}
+	if last := dayStart(records[len(records)-1].Date); last.After(end) {
+		end = last
+	}
+	if first := dayStart(records[0].Date); first.Before(start) {
+		start = first
+	}
+	days := int(end.Sub(start).Hours()/24+0.5) + 1
+
+	spending := make([]model.Money, days)
+	var total, peak model.Money
+	for _, record := range records {
+		if record.Amount >= 0 {
+			continue
+		}
+		i := int(dayStart(record.Date).Sub(start).Hours()/24 + 0.5)
+		spending[i] += -record.Amount
+		total += -record.Amount
+	}
+	for _, v := range spending {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	img := newChartImage()
+	plot := plotArea()
+	drawGridLines(img, plot, 4)
+
+	slot := float64(plot.Dx()) / float64(days)
+	barWidth := int(math.Max(1, slot*0.7))
+	for i, v := range spending {
+		if v == 0 || peak == 0 {
+			continue
+		}
+		h := int(float64(plot.Dy()) * float64(v) / float64(peak))
+		x0 := plot.Min.X + int(slot*float64(i)+(slot-float64(barWidth))/2)
+		fillRect(img, image.Rect(x0, plot.Max.Y-h, x0+barWidth, plot.Max.Y), chartExpense)
+	}
+	drawAxes(img, plot)
+
+	base := cycle.Currency()
+	caption := fmt.Sprintf("📊 每日支出 %s 至 %s\n最高单日: %s\n日均支出: %s\n总支出: %s",
+		start.Format("01-02"), end.Format("01-02"),
+		formatMoney(peak, base),
+		formatMoney(total.MulDiv(1, int64(days)), base),
+		formatMoney(total, base))
+	data, err := encodePNG(img)
+	return data, caption, err
+}
+
+// 支出分类饼图
+func renderCategoryChart(cycle *model.AccountingCycle, records []*model.AccountingRecord) ([]byte, string, error) {
+	totals := make(map[string]model.Money)
+	var total model.Money
+	for _, record := range records {
+		if record.Amount >= 0 {
+			continue
+		}
+		totals[record.CategoryOf()] += -record.Amount
+		total += -record.Amount
+	}
+	if total == 0 {
+		return nil, "", errNoChartData
+	}
+
+	type slice struct {
+		Category string
+		Amount   model.Money
+	}
+	slices := make([]slice, 0, len(totals))
+	for category, amount := range totals {
+		slices = append(slices, slice{category, amount})
+	}
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].Amount != slices[j].Amount {
+			return slices[i].Amount > slices[j].Amount
+		}
+		return slices[i].Category < slices[j].Category
+	})
+	// 超出调色板的分类合并为"其余"
+	if len(slices) > len(chartPalette) {
+		rest := slice{Category: "其余"}
+		for _, s := range slices[len(chartPalette)-1:] {
+			rest.Amount += s.Amount
+		}
+		slices = append(slices[:len(chartPalette)-1], rest)
+	}
+
+	img := newChartImage()
+	cx, cy := chartWidth/2, chartHeight/2
+	radius := float64(chartHeight/2 - chartPadding)
+
+	// 逐像素按角度着色，从 12 点方向顺时针
+	bounds := make([]float64, len(slices))
+	acc := 0.0
+	for i, s := range slices {
+		acc += float64(s.Amount) / float64(total)
+		bounds[i] = acc
+	}
+	for y := cy - int(radius); y <= cy+int(radius); y++ {
+		for x := cx - int(radius); x <= cx+int(radius); x++ {
+			dx, dy := float64(x-cx), float64(y-cy)
+			if dx*dx+dy*dy > radius*radius {
+				continue
+			}
+			angle := math.Atan2(dx, -dy)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			fraction := angle / (2 * math.Pi)
+			for i, bound := range bounds {
+				if fraction <= bound || i == len(bounds)-1 {
+					img.Set(x, y, chartPalette[i].Color)
+					break
+				}
+			}
+		}
+	}
+
+	var caption strings.Builder
+	caption.WriteString(fmt.Sprintf("🥧 支出分类（共 %s）\n", formatMoney(total, cycle.Currency())))
+	for i, s := range slices {
+		caption.WriteString(fmt.Sprintf("%s %s %s (%.1f%%)\n",
+			chartPalette[i].Emoji, s.Category, formatMoney(s.Amount, cycle.Currency()),
+			float64(s.Amount)*100/float64(total)))
+	}
+	data, err := encodePNG(img)
+	return data, strings.TrimRight(caption.String(), "\n"), err
+}
+
+// 余额变化折线图
+func renderBalanceChart(cycle *model.AccountingCycle, records []*model.AccountingRecord) ([]byte, string, error) {
+	balances := make([]model.Money, 0, len(records)+1)
+	times := make([]time.Time, 0, len(records)+1)
+	balance := cycle.Income
+	balances = append(balances, balance)
+	// 导入或补记的记录可能早于周期开始时间
+	start := cycle.StartTime
+	if records[0].Date.Before(start) {
+		start = records[0].Date
+	}
+	times = append(times, start)
+	for _, record := range records {
+		balance += record.Amount
+		balances = append(balances, balance)
+		times = append(times, record.Date)
+	}
+
+	lowest := balances[0]
+	lo, hi := model.Money(0), balances[0]
+	for _, b := range balances {
+		if b < lowest {
+			lowest = b
+		}
+		if b < lo {
+			lo = b
+		}
+		if b > hi {
+			hi = b
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	first, last := times[0], times[len(times)-1]
+	if !first.Before(last) {
+		last = first.Add(time.Hour)
+	}
+
+	img := newChartImage()
+	plot := plotArea()
+	drawGridLines(img, plot, 4)
+
+	point := func(i int) image.Point {
+		x := plot.Min.X + int(float64(plot.Dx())*times[i].Sub(first).Seconds()/last.Sub(first).Seconds())
+		y := plot.Max.Y - int(float64(plot.Dy())*float64(balances[i]-lo)/float64(hi-lo))
+		return image.Pt(x, y)
+	}
+
+	// 零线，余额为负时便于观察
+	if lo < 0 {
+		zero := plot.Max.Y - int(float64(plot.Dy())*float64(-lo)/float64(hi-lo))
+		drawLine(img, image.Pt(plot.Min.X, zero), image.Pt(plot.Max.X, zero), chartExpense, 1)
+	}
+	for i := 1; i < len(balances); i++ {
+		// 余额按阶梯变化：先水平到记录时间，再垂直跳变
+		prev, cur := point(i-1), point(i)
+		drawLine(img, prev, image.Pt(cur.X, prev.Y), chartBalance, 3)
+		drawLine(img, image.Pt(cur.X, prev.Y), cur, chartBalance, 3)
+	}
+	drawAxes(img, plot)
+
+	base := cycle.Currency()
+	caption := fmt.Sprintf("📈 余额变化 %s 至 %s\n起始: %s\n最低: %s\n当前: %s",
+		first.Format("01-02"), last.Format("01-02"),
+		formatMoney(balances[0], base),
+		formatMoney(lowest, base),
+		formatMoney(balance, base))
+	data, err := encodePNG(img)
+	return data, caption, err
+}
+
+func newChartImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	fillRect(img, img.Bounds(), chartBackground)
+	return img
+}
+
+func plotArea() image.Rectangle {
+	return image.Rect(chartPadding, chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// 画线（Bresenham），width 为线宽
+func drawLine(img *image.RGBA, a, b image.Point, c color.RGBA, width int) {
+	dx, dy := abs(b.X-a.X), -abs(b.Y-a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	err := dx + dy
+	half := width / 2
+	for {
+		fillRect(img, image.Rect(a.X-half, a.Y-half, a.X-half+width, a.Y-half+width), c)
+		if a == b {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			a.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			a.Y += sy
+		}
+	}
+}
+
+func drawAxes(img *image.RGBA, plot image.Rectangle) {
+	drawLine(img, image.Pt(plot.Min.X, plot.Min.Y), image.Pt(plot.Min.X, plot.Max.Y), chartAxis, 2)
+	drawLine(img, image.Pt(plot.Min.X, plot.Max.Y), image.Pt(plot.Max.X, plot.Max.Y), chartAxis, 2)
+}
+
+func drawGridLines(img *image.RGBA, plot image.Rectangle, n int) {
+	for i := 1; i <= n; i++ {
+		y := plot.Max.Y - plot.Dy()*i/n
+		drawLine(img, image.Pt(plot.Min.X, y), image.Pt(plot.Max.X, y), chartGrid, 1)
+	}
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,229 @@
+package logic
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 导出文件的表头
+var exportHeader = []string{"日期", "金额", "类型", "分类", "描述", "备注", "钱包"}
+
+// ExportAccounting 将记账记录导出为 CSV 或 XLSX 文件并以文档形式发送
+func (l *AccountingLogic) ExportAccounting(chatID int64, userID int64, req *model.AccountingExportRequest) error {
+	cycles, err := l.exportCycles(chatID, userID, req)
+	if err != nil {
+		return err
+	}
+
+	// 收集记录，日期范围导出时过滤掉范围外的记录
+	var rows [][]string
+	var records []*model.AccountingRecord
+	currencies := make(map[string]bool)
+	for _, cycle := range cycles {
+		for _, record := range cycle.Records {
+			if !req.From.IsZero() && (record.Date.Before(req.From) || !record.Date.Before(req.To.AddDate(0, 0, 1))) {
+				continue
+			}
+			records = append(records, record)
+			currencies[cycle.Currency()] = true
+		}
+	}
+	if len(records) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "没有可导出的记账记录")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	for _, record := range records {
+		rows = append(rows, exportRow(record))
+	}
+
+	// 文件名包含导出的日期范围
+	from, to := records[0].Date, records[len(records)-1].Date
+	if !req.From.IsZero() {
+		from, to = req.From, req.To
+	}
+	name := fmt.Sprintf("accounting_%s_%s", from.Format("20060102"), to.Format("20060102"))
+
+	var data []byte
+	if req.Format == "xlsx" {
+		name += ".xlsx"
+		data, err = buildXLSX(exportHeader, rows, map[int]bool{1: true})
+	} else {
+		name += ".csv"
+		data, err = buildCSV(exportHeader, rows)
+	}
+	if err != nil {
+		return fmt.Errorf("生成导出文件失败: %v", err)
+	}
+
+	target := l.viewChat(chatID, userID)
+	doc := tgbotapi.NewDocument(target, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = fmt.Sprintf("📤 已导出 %d 条记录（%s 至 %s）", len(records), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if len(currencies) > 1 {
+		doc.Caption += "\n⚠️ 包含多个本位币的周期，金额未统一折算"
+	}
+	if _, err := l.svcCtx.Bot.Send(doc); err != nil {
+		return err
+	}
+	l.notifyViewSent(chatID, target)
+	return nil
+}
+
+// 根据导出请求确定需要导出的记账周期
+func (l *AccountingLogic) exportCycles(chatID int64, userID int64, req *model.AccountingExportRequest) ([]*model.AccountingCycle, error) {
+	// 指定周期ID
+	if req.CycleID != "" {
+		cycle, err := l.getAccountingCycle(req.CycleID)
+		if err != nil {
+			return nil, err
+		}
+		if !l.canViewCycle(cycle, userID) {
+			return nil, fmt.Errorf("无权查看该记账周期")
+		}
+		return []*model.AccountingCycle{cycle}, nil
+	}
+
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 日期范围：导出与范围有交集的所有周期
+	if !req.From.IsZero() {
+		var matched []*model.AccountingCycle
+		for _, cycle := range cycles {
+			if cycle.StartTime.Before(req.To.AddDate(0, 0, 1)) && !cycle.EndTime.Before(req.From) {
+				matched = append(matched, cycle)
+			}
+		}
+		return matched, nil
+	}
+
+	// 默认导出当前活跃周期，没有则导出最近一个周期
+	for _, cycle := range cycles {
+		if cycle.IsActive {
+			return []*model.AccountingCycle{cycle}, nil
+		}
+	}
+	if len(cycles) > 0 {
+		return cycles[len(cycles)-1:], nil
+	}
+	return nil, nil
+}
+
+// 将一条记录转换为导出行
+func exportRow(record *model.AccountingRecord) []string {
+	recordType := "支出"
+	if record.Amount > 0 {
+		recordType = "收入"
+	}
+	description, note := model.SplitNote(record.Description)
+	return []string{
+		record.Date.Format("2006-01-02 15:04"),
+		record.Amount.String(),
+		recordType,
+		record.CategoryOf(),
+		description,
+		note,
+		model.WalletName(record.RecordWallet()),
+	}
+}
+
+// 生成带 BOM 的 UTF-8 CSV，保证 Excel 打开中文不乱码
+func buildCSV(header []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 生成只有一个工作表的最小 XLSX 文件，numericCols 中的列按数字写入
+func buildXLSX(header []string, rows [][]string, numericCols map[int]bool) ([]byte, error) {
+	var sheet strings.Builder
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	writeRow := func(r int, cells []string, header bool) {
+		sheet.WriteString(fmt.Sprintf(`<row r="%d">`, r))
+		for c, value := range cells {
+			ref := fmt.Sprintf("%s%d", xlsxColumn(c), r)
+			if !header && numericCols[c] {
+				sheet.WriteString(fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, value))
+				continue
+			}
+			var escaped bytes.Buffer
+			_ = xml.EscapeText(&escaped, []byte(value))
+			sheet.WriteString(fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escaped.String()))
+		}
+		sheet.WriteString(`</row>`)
+	}
+	writeRow(1, header, true)
+	for i, row := range rows {
+		writeRow(i+2, row, false)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	files := []struct {
+		Name string
+		Body string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="记账明细" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(file.Body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 将从 0 开始的列序号转换为 Excel 列名（A, B, ..., Z, AA, ...）
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,295 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 结束周期时提供的存入比例
+var goalAllocationPercents = []int{50, 100}
+
+// 结束周期时最多为几个目标提供存入按钮
+const goalAllocationLimit = 5
+
+// AddGoal 新建储蓄目标，币种取当前周期的本位币
+func (l *AccountingLogic) AddGoal(chatID int64, userID int64, goal *model.SavingsGoal) error {
+	now := time.Now()
+	if !goal.Deadline.IsZero() && !goal.Deadline.After(now) {
+		return fmt.Errorf("截止日期必须晚于今天")
+	}
+	goal.ID = newShortID()
+	goal.ChatID = chatID
+	goal.UserID = userID
+	goal.Currency = model.DefaultCurrency
+	if cycle, err := l.getActiveCycle(chatID, userID); err == nil {
+		goal.Currency = cycle.Currency()
+	}
+	goal.CreatedAt = now
+
+	if err := l.saveGoal(goal); err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "🎯 已创建储蓄目标\n\n"+formatGoal(goal, now)+
+		"\n\n结束记账周期时可以将结余存入目标，也可以使用 /goal save <序号> <金额> 手动存入")
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListGoals 展示所有储蓄目标的进度
+func (l *AccountingLogic) ListGoals(chatID int64, userID int64) error {
+	goals, err := l.getGoals(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if len(goals) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "还没有储蓄目标\n\n例如: /goal 旅行基金 10000 2027-02-01")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	now := time.Now()
+	var msgText strings.Builder
+	msgText.WriteString("🎯 储蓄目标:\n")
+	for i, goal := range goals {
+		msgText.WriteString(fmt.Sprintf("\n%d. %s\n", i+1, formatGoal(goal, now)))
+	}
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// DeleteGoal 删除储蓄目标
+func (l *AccountingLogic) DeleteGoal(chatID int64, userID int64, ref string) error {
+	goal, err := l.findGoal(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Hdel(goalKey(chatID, userID), goal.ID); err != nil {
+		return fmt.Errorf("删除储蓄目标失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 已删除储蓄目标: %s", goal.Name))
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// SaveToGoal 手动向储蓄目标存入一笔钱
+func (l *AccountingLogic) SaveToGoal(chatID int64, userID int64, ref string, amount model.Money) error {
+	if amount <= 0 {
+		return fmt.Errorf("存入金额必须大于 0")
+	}
+	goal, err := l.findGoal(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	return l.contribute(goal, amount, "")
+}
+
+// AllocateToGoal 将记账周期结余的 percent% 存入储蓄目标
+// 同一周期存入的总额不超过结余，重复点击按钮只会存入剩余的部分
+func (l *AccountingLogic) AllocateToGoal(chatID int64, userID int64, goalID string, cycleID string, percent int) error {
+	if percent <= 0 || percent > 100 {
+		return fmt.Errorf("无效的存入比例: %d%%", percent)
+	}
+	goal, err := l.findGoal(chatID, userID, goalID)
+	if err != nil {
+		return err
+	}
+
+	// 只能使用自己的记账周期
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return err
+	}
+	var cycle *model.AccountingCycle
+	for _, c := range cycles {
+		if c.ID == cycleID {
+			cycle = c
+		}
+	}
+	if cycle == nil {
+		return fmt.Errorf("找不到该记账周期")
+	}
+	if cycle.IsActive {
+		return fmt.Errorf("记账周期尚未结束")
+	}
+	if cycle.Currency() != goal.Currency {
+		return fmt.Errorf("记账周期本位币 %s 与目标币种 %s 不同", cycle.Currency(), goal.Currency)
+	}
+
+	balance := l.calculateSummary(cycle).Balance
+	available := balance - cycle.GoalAllocated
+	if available <= 0 {
+		return fmt.Errorf("该周期的结余已全部存入")
+	}
+	amount := min(balance.MulDiv(int64(percent), 100), available)
+
+	before := cycle.GoalAllocated
+	cycle.GoalAllocated += amount
+	if err := l.saveAccountingCycle(cycle); err != nil {
+		return err
+	}
+	l.audit(cycle, userID, model.AuditEdit, "",
+		formatMoney(before, cycle.Currency()), formatMoney(cycle.GoalAllocated, cycle.Currency()), "结余存入储蓄目标「"+goal.Name+"」")
+	return l.contribute(goal, amount, cycle.ID)
+}
+
+// 记录一次存入并发送进度
+func (l *AccountingLogic) contribute(goal *model.SavingsGoal, amount model.Money, cycleID string) error {
+	now := time.Now()
+	goal.Saved += amount
+	goal.Contributions = append(goal.Contributions, &model.GoalContribution{Amount: amount, CycleID: cycleID, Date: now})
+	if err := l.saveGoal(goal); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("💰 已存入 %s\n\n%s", formatMoney(amount, goal.Currency), formatGoal(goal, now))
+	if goal.Remaining() == 0 {
+		text = "🎉 恭喜，储蓄目标已完成！\n\n" + text
+	}
+	msg := tgbotapi.NewMessage(goal.ChatID, text)
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 周期结束且有结余时，提供将结余存入储蓄目标的按钮
+func (l *AccountingLogic) offerGoalAllocation(cycle *model.AccountingCycle, balance model.Money) {
+	if balance <= 0 {
+		return
+	}
+	goals, err := l.getGoals(cycle.ChatID, cycle.UserID)
+	if err != nil {
+		log.Printf("获取储蓄目标失败: %v", err)
+		return
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, goal := range goals {
+		if goal.Remaining() == 0 || goal.Currency != cycle.Currency() {
+			continue
+		}
+		if len(keyboard) == goalAllocationLimit {
+			break
+		}
+		var row []tgbotapi.InlineKeyboardButton
+		for _, percent := range goalAllocationPercents {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s %d%%", goal.Name, percent),
+				fmt.Sprintf("goal_%s_%d_%s", goal.ID, percent, cycle.ID)))
+		}
+		keyboard = append(keyboard, row)
+	}
+	if len(keyboard) == 0 {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(cycle.ChatID, fmt.Sprintf("🎯 本周期结余 %s，要存入储蓄目标吗？", formatMoney(balance, cycle.Currency())))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	if _, err := l.svcCtx.Bot.Send(msg); err != nil {
+		log.Printf("发送储蓄目标提示失败: %v", err)
+	}
+}
+
+// 按创建时间获取用户的全部储蓄目标
+func (l *AccountingLogic) getGoals(chatID int64, userID int64) ([]*model.SavingsGoal, error) {
+	data, err := l.svcCtx.Redis.Hgetall(goalKey(chatID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取储蓄目标失败: %v", err)
+	}
+
+	goals := make([]*model.SavingsGoal, 0, len(data))
+	for _, value := range data {
+		var goal model.SavingsGoal
+		if err := json.Unmarshal([]byte(value), &goal); err != nil {
+			log.Printf("解析储蓄目标失败: %v", err)
+			continue
+		}
+		goals = append(goals, &goal)
+	}
+	sort.Slice(goals, func(i, j int) bool {
+		return goals[i].CreatedAt.Before(goals[j].CreatedAt)
+	})
+	return goals, nil
+}
+
+// 根据列表序号或目标ID查找储蓄目标
+func (l *AccountingLogic) findGoal(chatID int64, userID int64, ref string) (*model.SavingsGoal, error) {
+	goals, err := l.getGoals(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if index, err := strconv.Atoi(ref); err == nil && index >= 1 && index <= len(goals) {
+		return goals[index-1], nil
+	}
+	for _, goal := range goals {
+		if goal.ID == ref {
+			return goal, nil
+		}
+	}
+	return nil, fmt.Errorf("找不到储蓄目标 %s，请使用 /goal 查看序号", ref)
+}
+
+func (l *AccountingLogic) saveGoal(goal *model.SavingsGoal) error {
+	data, err := json.Marshal(goal)
+	if err != nil {
+		return fmt.Errorf("序列化储蓄目标失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Hset(goalKey(goal.ChatID, goal.UserID), goal.ID, string(data)); err != nil {
+		return fmt.Errorf("保存储蓄目标失败: %v", err)
+	}
+	return nil
+}
+
+func goalKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:goal:%d:%d", chatID, userID)
+}
+
+// 目标的进度、每月需存金额和预计完成日期
+func formatGoal(goal *model.SavingsGoal, now time.Time) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%s: %s / %s（%.1f%%）\n",
+		goal.Name,
+		formatMoney(goal.Saved, goal.Currency),
+		formatMoney(goal.Target, goal.Currency),
+		goal.Progress()))
+	text.WriteString(progressBar(goal.Progress()))
+
+	if goal.Remaining() == 0 {
+		text.WriteString("\n✅ 已完成")
+		return text.String()
+	}
+	if !goal.Deadline.IsZero() {
+		text.WriteString(fmt.Sprintf("\n📅 截止日期: %s", goal.Deadline.Format("2006-01-02")))
+		if goal.Deadline.After(now) {
+			text.WriteString(fmt.Sprintf("，每月需存 %s", formatMoney(goal.MonthlyRequired(now), goal.Currency)))
+		} else {
+			text.WriteString("（已过期）")
+		}
+	}
+
+	projected := goal.ProjectedCompletion(now)
+	if projected.IsZero() {
+		text.WriteString("\n🔮 还没有存入，暂无法预计完成日期")
+		return text.String()
+	}
+	text.WriteString(fmt.Sprintf("\n🔮 按当前速度预计 %s 完成", projected.Format("2006-01-02")))
+	if !goal.Deadline.IsZero() && projected.After(goal.Deadline) {
+		text.WriteString("，⚠️ 晚于截止日期")
+	}
+	return text.String()
+}
+
+// 10 格进度条
+func progressBar(percent float64) string {
+	filled := int(percent / 10)
+	return strings.Repeat("▓", filled) + strings.Repeat("░", 10-filled)
+}
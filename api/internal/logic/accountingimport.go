@@ -0,0 +1,491 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	// 账单文件大小上限
+	maxStatementSize = 5 << 20
+	// 待确认导入的保存时间（秒）
+	importExpireSeconds = 30 * 60
+	// 预览中最多列出的记录数
+	importPreviewLimit = 15
+)
+
+// 账单表头中各字段可能的列名，兼容支付宝新旧版本和微信支付
+var statementColumns = map[string][]string{
+	"time":     {"交易时间", "交易创建时间", "付款时间"},
+	"amount":   {"金额", "金额（元）", "金额(元)"},
+	"flow":     {"收/支"},
+	"party":    {"交易对方"},
+	"item":     {"商品说明", "商品名称", "商品"},
+	"status":   {"交易状态", "当前状态"},
+	"id":       {"交易订单号", "交易号", "交易单号"},
+	"category": {"交易分类", "交易类型"},
+}
+
+// 这些状态的交易没有实际发生资金变动
+var ignoredStatuses = []string{"关闭", "失败", "撤销", "全额退款"}
+
+// PreviewImport 下载并解析用户上传的支付宝/微信账单，发送导入预览等待确认
+func (l *AccountingLogic) PreviewImport(chatID int64, userID int64, doc *tgbotapi.Document) error {
+	if !strings.HasSuffix(strings.ToLower(doc.FileName), ".csv") {
+		return fmt.Errorf("目前只支持导入支付宝/微信导出的 CSV 账单，请先解压后再上传")
+	}
+	if doc.FileSize > maxStatementSize {
+		return fmt.Errorf("账单文件过大，请分段导出后再上传")
+	}
+
+	// 必须有活跃周期才能导入
+	cycle, err := l.getActiveCycle(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	data, err := l.downloadFile(doc.FileID)
+	if err != nil {
+		return err
+	}
+
+	source, records, skipped, err := parseStatement(data)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("账单中没有可导入的收支记录")
+	}
+
+	pending := &model.AccountingImport{
+		ID:         newShortID(),
+		ChatID:     chatID,
+		UserID:     userID,
+		Source:     source,
+		FileName:   doc.FileName,
+		Records:    records,
+		Duplicates: findDuplicates(cycle.Records, records),
+		Skipped:    skipped,
+		CreatedAt:  time.Now(),
+	}
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("序列化导入数据失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Setex(importKey(pending.ID), string(payload), importExpireSeconds); err != nil {
+		return fmt.Errorf("保存导入数据失败: %v", err)
+	}
+
+	// 构建预览
+	var income, expense model.Money
+	duplicates := 0
+	for i, record := range records {
+		if pending.Duplicates[i] {
+			duplicates++
+			continue
+		}
+		if record.Amount > 0 {
+			income += record.Amount
+		} else {
+			expense += -record.Amount
+		}
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("📥 %s账单预览（%s）\n", sourceName(source), doc.FileName))
+	msgText.WriteString(fmt.Sprintf("共解析 %d 条，疑似重复 %d 条，忽略 %d 行\n", len(records), duplicates, skipped))
+	msgText.WriteString(fmt.Sprintf("将导入: 收入 %s，支出 %s\n\n", formatMoney(income, model.DefaultCurrency), formatMoney(expense, model.DefaultCurrency)))
+	for i, record := range records {
+		if i >= importPreviewLimit {
+			msgText.WriteString(fmt.Sprintf("... 其余 %d 条未显示\n", len(records)-importPreviewLimit))
+			break
+		}
+		mark := ""
+		if pending.Duplicates[i] {
+			mark = " ⚠️重复"
+		}
+		msgText.WriteString(fmt.Sprintf("%d. %s %s %s%s\n",
+			i+1,
+			record.Date.Format("01-02 15:04"),
+			record.Amount,
+			record.Description,
+			mark,
+		))
+	}
+	msgText.WriteString("\n疑似重复的记录默认跳过，确认后导入到当前记账周期")
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认导入", fmt.Sprintf("import_confirm_%s", pending.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("import_cancel_%s", pending.ID)),
+		},
+	}
+	if duplicates > 0 {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("📥 包含重复项全部导入", fmt.Sprintf("import_all_%s", pending.ID)),
+		})
+	}
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ConfirmImport 将待确认的账单批量写入当前活跃周期
+func (l *AccountingLogic) ConfirmImport(chatID int64, userID int64, importID string, includeDuplicates bool) error {
+	pending, err := l.getPendingImport(importID)
+	if err != nil {
+		return err
+	}
+	if pending.UserID != userID {
+		return fmt.Errorf("只能确认自己发起的导入")
+	}
+
+	cycle, err := l.getActiveCycle(pending.ChatID, pending.UserID)
+	if err != nil {
+		return err
+	}
+
+	// 重新检查重复，防止预览之后又有新记录写入
+	duplicates := findDuplicates(cycle.Records, pending.Records)
+	base := cycle.Currency()
+	var added []*model.AccountingRecord
+	var income, expense model.Money
+	for i, record := range pending.Records {
+		if duplicates[i] && !includeDuplicates {
+			continue
+		}
+		if base != model.DefaultCurrency {
+			converted, _, err := l.fx.Convert(userID, record.Amount, model.DefaultCurrency, base, record.Date)
+			if err != nil {
+				return err
+			}
+			record.Currency = model.DefaultCurrency
+			record.OriginalAmount = record.Amount
+			record.Rate = model.BaseRate
+			record.Amount = converted
+		}
+		record.ID = newShortID()
+		added = append(added, record)
+		if record.Amount > 0 {
+			income += record.Amount
+		} else {
+			expense -= record.Amount
+		}
+	}
+
+	// 一次性写入，保证导入要么全部成功要么全部失败
+	if err := l.appendRecords(cycle, added...); err != nil {
+		return err
+	}
+	imported := len(added)
+	_, _ = l.svcCtx.Redis.Del(importKey(importID))
+	l.audit(cycle, userID, model.AuditImport, "", "", fmt.Sprintf("%d 条记录，收入 %s，支出 %s",
+		imported, formatMoney(income, base), formatMoney(expense, base)), sourceName(pending.Source)+"账单")
+
+	summary := l.calculateSummary(cycle)
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ 已导入 %d 条%s账单记录\n\n"+
+			"📊 当前统计:\n"+
+			"💰 总收入: %s\n"+
+			"💸 总支出: %s\n"+
+			"💵 剩余金额: %s",
+		imported,
+		sourceName(pending.Source),
+		formatMoney(summary.TotalIncome, base),
+		formatMoney(summary.TotalExpense, base),
+		formatMoney(summary.Balance, base),
+	))
+	return l.sendView(chatID, userID, msg)
+}
+
+// CancelImport 放弃待确认的导入
+func (l *AccountingLogic) CancelImport(chatID int64, userID int64, importID string) error {
+	pending, err := l.getPendingImport(importID)
+	if err != nil {
+		return err
+	}
+	if pending.UserID != userID {
+		return fmt.Errorf("只能取消自己发起的导入")
+	}
+
+	if _, err := l.svcCtx.Redis.Del(importKey(importID)); err != nil {
+		return fmt.Errorf("取消导入失败: %v", err)
+	}
+	msg := tgbotapi.NewMessage(chatID, "已取消导入")
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 获取当前活跃的记账周期
+func (l *AccountingLogic) getActiveCycle(chatID int64, userID int64) (*model.AccountingCycle, error) {
+	cycleID, err := l.getActiveCycleID(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return l.getAccountingCycle(cycleID)
+}
+
+// 获取待确认的导入
+func (l *AccountingLogic) getPendingImport(importID string) (*model.AccountingImport, error) {
+	data, err := l.svcCtx.Redis.Get(importKey(importID))
+	if err != nil {
+		return nil, fmt.Errorf("获取导入数据失败: %v", err)
+	}
+	if data == "" {
+		return nil, fmt.Errorf("导入已过期或已处理，请重新上传账单")
+	}
+
+	var pending model.AccountingImport
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return nil, fmt.Errorf("解析导入数据失败: %v", err)
+	}
+	return &pending, nil
+}
+
+// 通过 Telegram 下载文件内容
+func (l *AccountingLogic) downloadFile(fileID string) ([]byte, error) {
+	url, err := l.svcCtx.Bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件地址失败: %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("下载文件失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载文件失败: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStatementSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if len(data) > maxStatementSize {
+		return nil, fmt.Errorf("账单文件过大，请分段导出后再上传")
+	}
+	return data, nil
+}
+
+func importKey(importID string) string {
+	return fmt.Sprintf("accounting:import:%s", importID)
+}
+
+func sourceName(source string) string {
+	switch source {
+	case "alipay":
+		return "支付宝"
+	case "wechat":
+		return "微信支付"
+	}
+	return ""
+}
+
+// 解析支付宝/微信导出的 CSV 账单
+// 返回来源、解析出的记录（按交易时间排列）以及被忽略的行数
+func parseStatement(data []byte) (string, []*model.AccountingRecord, int, error) {
+	// 支付宝账单通常是 GBK 编码
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", nil, 0, fmt.Errorf("无法识别账单编码: %v", err)
+		}
+		data = decoded
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	source := ""
+	switch {
+	case bytes.Contains(data, []byte("微信支付")):
+		source = "wechat"
+	case bytes.Contains(data, []byte("支付宝")):
+		source = "alipay"
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("解析账单失败: %v", err)
+	}
+
+	// 找到表头所在行，表头之前是账单说明
+	headerRow, columns := -1, map[string]int{}
+	for i, row := range rows {
+		if idx := statementColumnIndex(row); idx["time"] >= 0 && idx["amount"] >= 0 && idx["flow"] >= 0 {
+			headerRow, columns = i, idx
+			break
+		}
+	}
+	if headerRow < 0 {
+		return "", nil, 0, fmt.Errorf("未识别的账单格式，请上传支付宝或微信支付导出的 CSV 账单")
+	}
+
+	now := time.Now()
+	var records []*model.AccountingRecord
+	skipped := 0
+	for _, row := range rows[headerRow+1:] {
+		cell := func(name string) string {
+			i := columns[name]
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		date, err := parseStatementTime(cell("time"))
+		if err != nil {
+			// 表尾的说明行
+			continue
+		}
+
+		flow := cell("flow")
+		status := cell("status")
+		if (flow != "支出" && flow != "收入") || containsAny(status, ignoredStatuses) {
+			skipped++
+			continue
+		}
+
+		amount, err := model.ParseMoney(strings.NewReplacer("¥", "", "￥", "", ",", "").Replace(cell("amount")))
+		if err != nil || amount <= 0 {
+			skipped++
+			continue
+		}
+		if flow == "支出" {
+			amount = -amount
+		}
+
+		description := cell("party")
+		if item := cell("item"); item != "" && item != "/" {
+			if description == "" || description == "/" {
+				description = item
+			} else {
+				description = fmt.Sprintf("%s - %s", description, item)
+			}
+		}
+		if description == "" {
+			description = "未知项目"
+		}
+
+		category := model.Categorize(cell("category"), amount > 0)
+		if category == model.CategoryOther || category == model.CategoryIncome {
+			category = model.Categorize(description, amount > 0)
+		}
+
+		records = append(records, &model.AccountingRecord{
+			Amount:      amount,
+			Description: description,
+			Category:    category,
+			Source:      source,
+			Wallet:      source, // 账单来源 alipay、wechat 与钱包同名
+			ExternalID:  strings.Trim(cell("id"), "\t"),
+			Date:        date,
+			CreatedAt:   now,
+		})
+	}
+
+	// 账单一般按时间倒序导出，这里改为正序
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	return source, records, skipped, nil
+}
+
+// 在表头行中查找各字段所在列，找不到为 -1
+func statementColumnIndex(row []string) map[string]int {
+	idx := make(map[string]int, len(statementColumns))
+	for name, candidates := range statementColumns {
+		idx[name] = -1
+		for i, cell := range row {
+			cell = strings.TrimSpace(cell)
+			for _, candidate := range candidates {
+				if cell == candidate {
+					idx[name] = i
+				}
+			}
+			if idx[name] >= 0 {
+				break
+			}
+		}
+	}
+	return idx
+}
+
+func parseStatementTime(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006/1/2 15:04:05", "2006-01-02 15:04", "2006/1/2 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的交易时间: %s", s)
+}
+
+// 标记导入记录中疑似重复的项：
+//  1. 交易单号相同，或同一天、同金额且描述相同（包括同一批次内部的重复）
+//  2. 与手工记录同一天、同金额，每条手工记录最多匹配一条导入记录
+func findDuplicates(existing []*model.AccountingRecord, incoming []*model.AccountingRecord) []bool {
+	seenIDs := make(map[string]bool)
+	seenKeys := make(map[string]bool)
+	manual := make(map[string]int)
+	amountOf := func(r *model.AccountingRecord) model.Money {
+		if r.OriginalAmount != 0 {
+			return r.OriginalAmount
+		}
+		return r.Amount
+	}
+	dayKey := func(r *model.AccountingRecord) string {
+		return fmt.Sprintf("%s|%s", r.Date.Format("2006-01-02"), amountOf(r))
+	}
+	for _, r := range existing {
+		if r.ExternalID != "" {
+			seenIDs[r.ExternalID] = true
+		}
+		seenKeys[dayKey(r)+"|"+r.Description] = true
+		if r.Source == "" {
+			manual[dayKey(r)]++
+		}
+	}
+
+	duplicates := make([]bool, len(incoming))
+	for i, r := range incoming {
+		key := dayKey(r) + "|" + r.Description
+		switch {
+		case r.ExternalID != "" && seenIDs[r.ExternalID], seenKeys[key]:
+			duplicates[i] = true
+		case manual[dayKey(r)] > 0:
+			manual[dayKey(r)]--
+			duplicates[i] = true
+		}
+		if r.ExternalID != "" {
+			seenIDs[r.ExternalID] = true
+		}
+		seenKeys[key] = true
+	}
+	return duplicates
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qx/syft_robot/api/internal/model"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 支付宝导出的账单（GB18030 编码），表头前后有说明行，交易订单号后带制表符
+const alipayStatement = `支付宝交易记录明细查询
+账号:[test@example.com]
+起始日期:[2026-10-01 00:00:00]    终止日期:[2026-10-14 00:00:00]
+---------------------------------交易记录明细列表------------------------------------
+交易时间,交易分类,交易对方,商品说明,收/支,金额,收/付款方式,交易状态,交易订单号,商家订单号
+2026-10-12 08:30:00,交通出行,滴滴出行,快车,支出,23.50,花呗,交易成功,2026101222001	,M001
+2026-10-13 12:05:10,餐饮美食,某某餐厅,/,支出,"1,035.00",余额宝,交易成功,2026101322002	,M002
+2026-10-11 09:00:00,转账红包,张三,,收入,200.00,余额,交易成功,2026101122003	,M003
+2026-10-13 19:00:00,日用百货,某超市,购物,支出,50.00,余额,交易关闭,2026101322004	,M004
+2026-10-13 20:00:00,投资理财,余额宝,收益发放,不计收支,0.12,余额宝,交易成功,2026101322005	,M005
+------------------------------------------------------------------------------------
+共5笔记录
+`
+
+// 微信支付导出的账单（UTF-8，带 BOM），金额带 ¥ 符号
+const wechatStatement = "\ufeff" + `微信支付账单明细,,,,,,,,,,
+微信昵称：[测试],,,,,,,,,,
+起始时间：[2026-10-01 00:00:00] 终止时间：[2026-10-14 00:00:00],,,,,,,,,,
+----------------------微信支付账单明细列表--------------------,,,,,,,,,,
+交易时间,交易类型,交易对方,商品,收/支,金额(元),支付方式,当前状态,交易单号,商户单号,备注
+2026/10/13 12:00,商户消费,咖啡店,拿铁,支出,¥32.00,零钱,支付成功,4200001,/,/
+2026/10/12 18:30,微信红包,李四,/,收入,¥66.60,/,已存入零钱,4200002,/,/
+2026/10/12 19:00,商户消费,外卖平台,晚餐,支出,¥45.00,零钱,已全额退款,4200003,/,/
+2026/10/12 20:00,零钱提现,招商银行,/,/,¥100.00,零钱,提现已到账,4200004,/,/
+`
+
+func TestParseStatement(t *testing.T) {
+	gbk, err := simplifiedchinese.GB18030.NewEncoder().String(alipayStatement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, second, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		source  string
+		skipped int
+		want    []*model.AccountingRecord
+	}{
+		{"支付宝 GB18030", gbk, "alipay", 2, []*model.AccountingRecord{
+			{Amount: 20000, Description: "张三", Category: model.CategoryIncome, ExternalID: "2026101122003", Date: at(11, 9, 0, 0)},
+			{Amount: -2350, Description: "滴滴出行 - 快车", Category: model.CategoryTransport, ExternalID: "2026101222001", Date: at(12, 8, 30, 0)},
+			{Amount: -103500, Description: "某某餐厅", Category: model.CategoryFood, ExternalID: "2026101322002", Date: at(13, 12, 5, 10)},
+		}},
+		{"支付宝 UTF-8", alipayStatement, "alipay", 2, []*model.AccountingRecord{
+			{Amount: 20000, Description: "张三", Category: model.CategoryIncome, ExternalID: "2026101122003", Date: at(11, 9, 0, 0)},
+			{Amount: -2350, Description: "滴滴出行 - 快车", Category: model.CategoryTransport, ExternalID: "2026101222001", Date: at(12, 8, 30, 0)},
+			{Amount: -103500, Description: "某某餐厅", Category: model.CategoryFood, ExternalID: "2026101322002", Date: at(13, 12, 5, 10)},
+		}},
+		{"微信支付", wechatStatement, "wechat", 2, []*model.AccountingRecord{
+			{Amount: 6660, Description: "李四", Category: model.CategoryIncome, ExternalID: "4200002", Date: at(12, 18, 30, 0)},
+			{Amount: -3200, Description: "咖啡店 - 拿铁", Category: model.CategoryFood, ExternalID: "4200001", Date: at(13, 12, 0, 0)},
+		}},
+	}
+	for _, tt := range tests {
+		source, records, skipped, err := parseStatement([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: parseStatement error = %v", tt.name, err)
+			continue
+		}
+		if source != tt.source || skipped != tt.skipped || len(records) != len(tt.want) {
+			t.Errorf("%s: parseStatement = %s, %d 条, 忽略 %d, want %s, %d 条, 忽略 %d", tt.name,
+				source, len(records), skipped, tt.source, len(tt.want), tt.skipped)
+			continue
+		}
+		for i, got := range records {
+			want := tt.want[i]
+			if got.Amount != want.Amount || got.Description != want.Description || got.Category != want.Category ||
+				got.ExternalID != want.ExternalID || !got.Date.Equal(want.Date) || got.Source != tt.source || got.Wallet != tt.source {
+				t.Errorf("%s: 第 %d 条 = %+v, want %+v", tt.name, i+1, got, want)
+			}
+		}
+	}
+
+	for _, data := range []string{"", "时间,金额\n2026-10-13 12:00:00,10\n", "\xff\xfe\x00"} {
+		if _, _, _, err := parseStatement([]byte(data)); err == nil {
+			t.Errorf("parseStatement(%q) error = nil", data)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 12, 0, 0, 0, time.Local)
+	}
+	existing := []*model.AccountingRecord{
+		// 之前导入过的账单
+		{Amount: -2350, Description: "滴滴出行 - 快车", Source: "alipay", ExternalID: "A1", Date: day(12)},
+		// 手工记录的午饭，与账单中的餐厅消费同一天同金额
+		{Amount: -3500, Description: "午饭", Date: day(13)},
+		// 外币记录按原始金额比较
+		{Amount: -7200, OriginalAmount: -1000, Currency: "USD", Description: "订阅", Date: day(13)},
+	}
+	incoming := []*model.AccountingRecord{
+		{Amount: -2350, Description: "滴滴出行 - 快车", Source: "alipay", ExternalID: "A1", Date: day(12)}, // 交易单号相同
+		{Amount: -3500, Description: "某某餐厅", Source: "alipay", ExternalID: "A2", Date: day(13)},      // 与手工记录同一天同金额
+		{Amount: -3500, Description: "另一家餐厅", Source: "alipay", ExternalID: "A3", Date: day(13)},     // 手工记录只匹配一次
+		{Amount: -1000, Description: "订阅", Source: "alipay", ExternalID: "A4", Date: day(13)},        // 同一天同金额同描述
+		{Amount: -5000, Description: "超市", Source: "wechat", ExternalID: "W1", Date: day(14)},        // 新记录
+		{Amount: -5000, Description: "超市", Source: "wechat", ExternalID: "W2", Date: day(14)},        // 同一批次内重复
+		{Amount: -5000, Description: "超市", Source: "wechat", ExternalID: "W3", Date: day(15)},        // 不同日期
+		{Amount: -2350, Description: "滴滴出行 - 快车", Source: "alipay", ExternalID: "A5", Date: day(11)}, // 单号和日期都不同
+	}
+	want := []bool{true, true, false, true, false, true, false, false}
+
+	got := findDuplicates(existing, incoming)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("findDuplicates[%d] (%s %s) = %v, want %v", i, incoming[i].Description, incoming[i].ExternalID, got[i], want[i])
+		}
+	}
+}
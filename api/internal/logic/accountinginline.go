@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/parser"
+)
+
+// 内联模式：在任意聊天中输入 "@机器人 35 午餐"，选中预览结果后记入用户的账本
+// 内联查询不带聊天ID，默认记入私聊账本，在群里发送 /accounting_inline 后改为记入该群的账本。
+// 需要在 BotFather 中开启 inline 模式和 inline feedback，否则收不到选中结果。
+
+// 可以记账的预览结果ID，选中其他结果时不记账
+const inlineEntryResultID = "entry"
+
+// 跳转私聊时 /start 的参数
+const inlineStartParameter = "inline"
+
+// AnswerInlineQuery 解析内联查询并返回记账预览
+func (l *AccountingLogic) AnswerInlineQuery(queryID string, userID int64, query string) error {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID:     queryID,
+		IsPersonal:        true,
+		CacheTime:         1, // 记入的账本、活跃周期和默认钱包随时可能变化，不能使用 Telegram 默认的 300 秒缓存；0 会被当作未设置
+		SwitchPMParameter: inlineStartParameter,
+	}
+
+	query = strings.TrimSpace(query)
+	chatID, title := l.inlineTarget(userID)
+	cycle, err := l.getActiveCycle(chatID, userID)
+	switch {
+	case query == "":
+		answer.SwitchPMText = "输入金额和描述，例如 35 午餐"
+	case err != nil:
+		answer.SwitchPMText = fmt.Sprintf("%s还没有进行中的记账周期，点击开始记账", title)
+	default:
+		entry, err := parser.ParseEntry(query, time.Now())
+		if err != nil {
+			answer.SwitchPMText = fmt.Sprintf("无法记账: %v", err)
+			break
+		}
+		wallet := entry.Wallet
+		if wallet == "" {
+			wallet = l.getDefaultWallet(cycle.ChatID, userID)
+		}
+		preview := describeInlineEntry(entry, wallet, cycle)
+		result := tgbotapi.NewInlineQueryResultArticle(inlineEntryResultID, preview, "✍️ "+preview)
+		result.Description = fmt.Sprintf("记入%s · 钱包: %s", title, model.WalletName(wallet))
+		if !entry.Date.IsZero() {
+			result.Description += " · " + entry.Date.Format("2006-01-02")
+		}
+		answer.Results = []interface{}{result}
+	}
+
+	_, err = l.svcCtx.Bot.Request(answer)
+	return err
+}
+
+// RecordInlineEntry 记录用户选中的内联预览结果
+func (l *AccountingLogic) RecordInlineEntry(userID int64, resultID string, query string) error {
+	if resultID != inlineEntryResultID {
+		return nil
+	}
+	entry, err := parser.ParseEntry(strings.TrimSpace(query), time.Now())
+	if err != nil {
+		return err
+	}
+	chatID, _ := l.inlineTarget(userID)
+	return l.AddExpense(chatID, userID, entry.Request())
+}
+
+// SetInlineTarget 设置内联记账记入的账本，off 为 true 或在私聊中设置时恢复为私聊账本
+func (l *AccountingLogic) SetInlineTarget(chatID int64, userID int64, title string, off bool) error {
+	text := "✍️ 内联记账已改为记入你的私聊账本"
+	if off || chatID == userID {
+		if _, err := l.svcCtx.Redis.Del(inlineKey(userID)); err != nil {
+			return fmt.Errorf("设置内联记账失败: %v", err)
+		}
+	} else {
+		err := l.svcCtx.Redis.Hmset(inlineKey(userID), map[string]string{
+			"chat_id": strconv.FormatInt(chatID, 10),
+			"title":   title,
+		})
+		if err != nil {
+			return fmt.Errorf("设置内联记账失败: %v", err)
+		}
+		text = "✍️ 在任意聊天中输入 @机器人 35 午餐，选中预览结果后会记入你在本群的账本\n\n" +
+			"使用 /accounting_inline off 恢复记入私聊账本"
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 内联记账记入的聊天和账本名称，没有设置时为私聊账本
+func (l *AccountingLogic) inlineTarget(userID int64) (int64, string) {
+	values, err := l.svcCtx.Redis.Hmget(inlineKey(userID), "chat_id", "title")
+	if err != nil || len(values) != 2 {
+		return userID, "私聊账本"
+	}
+	chatID, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return userID, "私聊账本"
+	}
+	return chatID, fmt.Sprintf("群账本「%s」", values[1])
+}
+
+// 预览文本，例如 "记一笔支出 35.00 元 午餐"
+func describeInlineEntry(entry *parser.Entry, wallet string, cycle *model.AccountingCycle) string {
+	kind := "支出"
+	if entry.Income() {
+		kind = "收入"
+	}
+	currency := entry.Currency
+	if currency == "" {
+		currency = model.WalletCurrency(wallet)
+	}
+	if currency == "" {
+		currency = cycle.Currency()
+	}
+	description := entry.Description
+	if entry.Note != "" {
+		description = fmt.Sprintf("%s(%s)", description, entry.Note)
+	}
+	return fmt.Sprintf("记一笔%s %s %s", kind, formatMoney(entry.Amount.Abs(), currency), description)
+}
+
+// 内联记账记入的账本，field 为 chat_id 和 title
+func inlineKey(userID int64) string {
+	return fmt.Sprintf("accounting:inline:%d", userID)
+}
@@ -0,0 +1,206 @@
+package logic
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 私聊账本：用户在与机器人的私聊中记账时，账本以用户ID作为聊天ID保存，记录和摘要不会出现在任何群里。
+// 用户可以把私聊账本关联到群，群汇总中只显示关联账本的总额，不显示明细。
+
+// LinkLedger 将用户的私聊账本关联到群
+func (l *AccountingLogic) LinkLedger(groupID int64, userID int64, title string) error {
+	if groupID == userID {
+		return fmt.Errorf("请在要关联的群里发送 /accounting_link")
+	}
+	if err := l.svcCtx.Redis.Hset(linkKey(userID), strconv.FormatInt(groupID, 10), title); err != nil {
+		return fmt.Errorf("保存账本关联失败: %v", err)
+	}
+	if _, err := l.svcCtx.Redis.Sadd(linkedKey(groupID), userID); err != nil {
+		return fmt.Errorf("保存账本关联失败: %v", err)
+	}
+
+	text := "🔗 已将你的私聊账本关联到本群\n\n" +
+		"/accounting_group 的群汇总中会显示私聊账本的收支总额，明细仍只在私聊中可见\n" +
+		"使用 /accounting_link off 取消关联"
+	if _, err := l.getActiveCycle(userID, userID); err != nil {
+		text += "\n\n💡 你还没有私聊账本，私聊机器人发送 /accounting_start 开始记账"
+	}
+	msg := tgbotapi.NewMessage(groupID, text)
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// UnlinkLedger 取消私聊账本与群的关联
+func (l *AccountingLogic) UnlinkLedger(groupID int64, userID int64) error {
+	if _, err := l.svcCtx.Redis.Hdel(linkKey(userID), strconv.FormatInt(groupID, 10)); err != nil {
+		return fmt.Errorf("取消账本关联失败: %v", err)
+	}
+	if _, err := l.svcCtx.Redis.Srem(linkedKey(groupID), userID); err != nil {
+		return fmt.Errorf("取消账本关联失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(groupID, "🔓 已取消私聊账本与本群的关联")
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListLinks 在私聊中列出私聊账本关联的群
+func (l *AccountingLogic) ListLinks(userID int64) error {
+	links, err := l.svcCtx.Redis.Hgetall(linkKey(userID))
+	if err != nil {
+		return fmt.Errorf("获取账本关联失败: %v", err)
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString("🔒 这里是你的私聊账本，记录和摘要不会出现在任何群里\n")
+	if len(links) == 0 {
+		msgText.WriteString("\n还没有关联任何群，在群里发送 /accounting_link 可以让群汇总显示你的收支总额")
+	} else {
+		titles := make([]string, 0, len(links))
+		for _, title := range links {
+			titles = append(titles, title)
+		}
+		sort.Strings(titles)
+		msgText.WriteString("\n🔗 已关联的群（只显示总额）:\n")
+		for _, title := range titles {
+			msgText.WriteString(fmt.Sprintf("• %s\n", title))
+		}
+		msgText.WriteString("\n在群里发送 /accounting_link off 取消关联")
+	}
+
+	msg := tgbotapi.NewMessage(userID, msgText.String())
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// GroupSummary 群汇总：群内记账成员和关联了私聊账本的成员的当前周期总额
+func (l *AccountingLogic) GroupSummary(groupID int64) error {
+	members, err := l.svcCtx.Redis.Smembers(membersKey(groupID))
+	if err != nil {
+		return fmt.Errorf("获取群成员失败: %v", err)
+	}
+	linked, err := l.svcCtx.Redis.Smembers(linkedKey(groupID))
+	if err != nil {
+		return fmt.Errorf("获取关联账本失败: %v", err)
+	}
+
+	type ledger struct {
+		userID  int64
+		private bool
+	}
+	var ledgers []ledger
+	// 关联了私聊账本的成员只显示私聊账本的总额，不再重复显示群内账本
+	linkedUsers := make(map[int64]bool)
+	for _, member := range linked {
+		if userID, err := strconv.ParseInt(member, 10, 64); err == nil {
+			linkedUsers[userID] = true
+		}
+	}
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		// 设置了私聊查看的成员，群内账本不出现在群汇总中
+		if err != nil || linkedUsers[userID] || l.getPrivacy(userID) == model.PrivacyDM {
+			continue
+		}
+		ledgers = append(ledgers, ledger{userID, false})
+	}
+	for _, member := range linked {
+		if userID, err := strconv.ParseInt(member, 10, 64); err == nil {
+			ledgers = append(ledgers, ledger{userID, true})
+		}
+	}
+
+	var lines []string
+	expense := make(map[string]model.Money)
+	balance := make(map[string]model.Money)
+	var currencies []string
+	for _, item := range ledgers {
+		chatID := groupID
+		if item.private {
+			chatID = item.userID
+		}
+		cycle, err := l.getActiveCycle(chatID, item.userID)
+		if err != nil {
+			continue
+		}
+		summary := l.calculateSummary(cycle)
+		if _, ok := expense[summary.Currency]; !ok {
+			currencies = append(currencies, summary.Currency)
+		}
+		expense[summary.Currency] += summary.TotalExpense
+		balance[summary.Currency] += summary.Balance
+
+		mark := ""
+		if item.private {
+			mark = "🔒"
+		}
+		lines = append(lines, fmt.Sprintf("%s%s: 支出 %s，剩余 %s",
+			l.memberName(groupID, item.userID), mark,
+			formatMoney(summary.TotalExpense, summary.Currency),
+			formatMoney(summary.Balance, summary.Currency)))
+	}
+
+	if len(lines) == 0 {
+		msg := tgbotapi.NewMessage(groupID, "👥 本群还没有进行中的记账周期\n\n使用 /accounting_start 开始记账，或者在群里发送 /accounting_link 关联私聊账本")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString("👥 群汇总（当前周期）:\n")
+	for _, line := range lines {
+		msgText.WriteString(line + "\n")
+	}
+	msgText.WriteString("\n合计:")
+	for _, currency := range currencies {
+		msgText.WriteString(fmt.Sprintf(" 支出 %s，剩余 %s", formatMoney(expense[currency], currency), formatMoney(balance[currency], currency)))
+	}
+	msgText.WriteString("\n\n🔒 为关联的私聊账本，只显示总额")
+
+	msg := tgbotapi.NewMessage(groupID, msgText.String())
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 记录在群里开始过记账的成员，用于群汇总
+func (l *AccountingLogic) trackMember(chatID int64, userID int64) {
+	if chatID == userID {
+		return
+	}
+	if _, err := l.svcCtx.Redis.Sadd(membersKey(chatID), userID); err != nil {
+		log.Printf("记录群成员失败: %v", err)
+	}
+}
+
+// 获取成员在群里的名字，获取失败时显示用户ID
+func (l *AccountingLogic) memberName(chatID int64, userID int64) string {
+	member, err := l.svcCtx.Bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil || member.User == nil {
+		return strconv.FormatInt(userID, 10)
+	}
+	return member.User.FirstName
+}
+
+// 私聊账本关联的群，field 为群ID，值为群名称
+func linkKey(userID int64) string {
+	return fmt.Sprintf("accounting:links:%d", userID)
+}
+
+// 关联到群的私聊账本
+func linkedKey(groupID int64) string {
+	return fmt.Sprintf("accounting:linked:%d", groupID)
+}
+
+// 在群里开始过记账的成员
+func membersKey(groupID int64) string {
+	return fmt.Sprintf("accounting:members:%d", groupID)
+}
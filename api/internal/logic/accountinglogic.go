@@ -2,8 +2,10 @@ package logic
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,20 +18,21 @@ import (
 type AccountingLogic struct {
 	ctx    context.Context
 	svcCtx *svc.ServiceContext
+	fx     *ExchangeRateLogic
 }
 
 func NewAccountingLogic(ctx context.Context, svcCtx *svc.ServiceContext) *AccountingLogic {
 	return &AccountingLogic{
 		ctx:    ctx,
 		svcCtx: svcCtx,
+		fx:     NewExchangeRateLogic(ctx, svcCtx),
 	}
 }
 
 // StartAccounting 开始一个新的记账周期
-func (l *AccountingLogic) StartAccounting(chatID int64, userID int64, income float64) error {
+func (l *AccountingLogic) StartAccounting(chatID int64, userID int64, req *model.AccountingStartRequest) error {
 	// 检查是否已有活跃的记账周期
-	activeKey := fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
-	activeID, err := l.svcCtx.Redis.Get(activeKey)
+	activeID, err := l.svcCtx.Ledger.ActiveCycleID(l.ctx, chatID, userID)
 	if err == nil && activeID != "" {
 		// 结束现有周期
 		if err := l.EndAccounting(chatID, userID); err != nil {
@@ -47,40 +50,36 @@ func (l *AccountingLogic) StartAccounting(chatID int64, userID int64, income flo
 		UserID:    userID,
 		StartTime: now,
 		EndTime:   endTime,
-		Income:    income,
-		Records:   make([]*model.AccountingRecord, 0),
+		Income:    req.Income,
+		BaseCurrency: req.BaseCurrency,
 		IsActive:  true,
 		CreatedAt: now,
 	}
 
 	// 保存到Redis
-	cycleKey := fmt.Sprintf("accounting:cycle:%s", cycle.ID)
-	data, err := json.Marshal(cycle)
-	if err != nil {
-		return fmt.Errorf("序列化记账周期失败: %v", err)
-	}
-
-	if err := l.svcCtx.Redis.Set(cycleKey, string(data)); err != nil {
-		return fmt.Errorf("保存记账周期失败: %v", err)
+	if err := l.saveAccountingCycle(cycle); err != nil {
+		return err
 	}
 
 	// 设置为活跃周期
-	if err := l.svcCtx.Redis.Set(activeKey, cycle.ID); err != nil {
-		return fmt.Errorf("设置活跃周期失败: %v", err)
+	if err := l.svcCtx.Ledger.SetActiveCycleID(l.ctx, chatID, userID, cycle.ID); err != nil {
+		return err
 	}
+	l.audit(cycle, userID, model.AuditStart, "", "", describeCycle(cycle), "")
 
 	// 添加到历史记录
-	l.addToHistory(chatID, userID, cycle.ID)
+	l.addToHistory(cycle)
+	l.trackMember(chatID, userID)
 
 	// 发送确认消息
 	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
 		"✅ 已开始新的记账周期！\n"+
 			"📅 起始日期: %s\n"+
-			"💰 收入金额: %.2f 元\n"+
+			"💰 收入金额: %s\n"+
 			"⏰ 结束日期: %s\n\n"+
 			"使用回复(Reply)方式输入每日支出金额即可记账",
 		now.Format("2006-01-02"),
-		income,
+		formatMoney(req.Income, cycle.Currency()),
 		endTime.Format("2006-01-02"),
 	))
 	_, err = l.svcCtx.Bot.Send(msg)
@@ -90,19 +89,19 @@ func (l *AccountingLogic) StartAccounting(chatID int64, userID int64, income flo
 // EndAccounting 结束当前记账周期
 func (l *AccountingLogic) EndAccounting(chatID int64, userID int64) error {
 	// 获取当前活跃的记账周期
-	activeKey := fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
-	cycleID, err := l.svcCtx.Redis.Get(activeKey)
+	cycleID, err := l.svcCtx.Ledger.ActiveCycleID(l.ctx, chatID, userID)
 	if err != nil || cycleID == "" {
 		return fmt.Errorf("找不到活跃的记账周期")
 	}
 
 	// 获取周期详情
-	cycle, err := l.getAccountingCycle(cycleID)
+	cycle, err := l.getCycleMeta(cycleID)
 	if err != nil {
 		return err
 	}
 
 	// 设置为非活跃
+	before := describeCycle(cycle)
 	cycle.IsActive = false
 	cycle.EndTime = time.Now()
 
@@ -110,17 +109,17 @@ func (l *AccountingLogic) EndAccounting(chatID int64, userID int64) error {
 	if err := l.saveAccountingCycle(cycle); err != nil {
 		return err
 	}
+	l.audit(cycle, userID, model.AuditEnd, "", before, describeCycle(cycle), "")
 
 	// 添加到历史记录
-	if err := l.addToHistory(chatID, userID, cycleID); err != nil {
+	if err := l.addToHistory(cycle); err != nil {
 		// 仅记录错误，不中断流程
 		fmt.Printf("添加到历史记录失败: %v\n", err)
 	}
 
 	// 清除活跃标记
-	_, err = l.svcCtx.Redis.Del(activeKey)
-	if err != nil {
-		return fmt.Errorf("清除活跃周期标记失败: %v", err)
+	if err := l.svcCtx.Ledger.ClearActiveCycleID(l.ctx, chatID, userID); err != nil {
+		return err
 	}
 
 	// 发送统计信息
@@ -128,84 +127,143 @@ func (l *AccountingLogic) EndAccounting(chatID int64, userID int64) error {
 	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
 		"📊 记账周期已结束！\n"+
 			"📅 周期: %s 至 %s\n"+
-			"💰 总收入: %.2f 元\n"+
-			"💸 总支出: %.2f 元\n"+
-			"💵 剩余金额: %.2f 元\n\n"+
+			"💰 总收入: %s\n"+
+			"💸 总支出: %s\n"+
+			"💵 剩余金额: %s\n%s\n"+
 			"使用 /accounting_history 查看所有历史记录",
 		cycle.StartTime.Format("2006-01-02"),
 		cycle.EndTime.Format("2006-01-02"),
-		summary.TotalIncome,
-		summary.TotalExpense,
-		summary.Balance,
+		formatMoney(summary.TotalIncome, summary.Currency),
+		formatMoney(summary.TotalExpense, summary.Currency),
+		formatMoney(summary.Balance, summary.Currency),
+		formatCurrencyBreakdown(summary),
 	))
-	_, err = l.svcCtx.Bot.Send(msg)
-	return err
+	if err := l.sendView(chatID, userID, msg); err != nil {
+		return err
+	}
+
+	// 有结余时询问是否存入储蓄目标
+	l.offerGoalAllocation(cycle, summary.Balance)
+	return nil
 }
 
 // AddExpense 添加支出记录
-func (l *AccountingLogic) AddExpense(chatID int64, userID int64, amount float64, description string) error {
+func (l *AccountingLogic) AddExpense(chatID int64, userID int64, req *model.AccountingExpenseRequest) error {
 	// 获取当前活跃的记账周期
-	activeKey := fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
-	cycleID, err := l.svcCtx.Redis.Get(activeKey)
-	if err != nil || cycleID == "" {
-		return fmt.Errorf("找不到活跃的记账周期，请先使用 /accounting_start 命令开始记账")
+	cycleID, err := l.getActiveCycleID(chatID, userID)
+	if err != nil {
+		return err
 	}
 
 	// 获取周期详情
-	cycle, err := l.getAccountingCycle(cycleID)
+	cycle, err := l.getCycleMeta(cycleID)
 	if err != nil {
 		return err
 	}
+	// 升级前开始的周期没有登记成员
+	l.trackMember(chatID, userID)
 
-	// 添加支出记录
-	now := time.Now()
-	record := &model.AccountingRecord{
-		Amount:      amount,  // 直接使用传入的金额，不再取负
-		Description: description,
-		Date:        now,
-		CreatedAt:   now,
+	// 添加支出记录，补记的账使用指定日期
+	date := time.Now()
+	if !req.Date.IsZero() {
+		date = req.Date
 	}
-	cycle.Records = append(cycle.Records, record)
-
-	// 保存更新后的周期
-	if err := l.saveAccountingCycle(cycle); err != nil {
+	record, err := l.newRecord(userID, cycle, req, date)
+	if err != nil {
+		return err
+	}
+	if err := l.appendRecords(cycle, record); err != nil {
 		return err
 	}
+	l.auditRecord(cycle, userID, record, "")
 
 	// 计算摘要
 	summary := l.calculateSummary(cycle)
+	base := cycle.Currency()
 
 	// 发送确认消息
 	var msgText string
-	if amount < 0 {
-		msgText = fmt.Sprintf("✅ 已记录支出: %.2f 元 - %s\n\n", -amount, description)
+	if record.Amount < 0 {
+		msgText = fmt.Sprintf("✅ 已记录支出: %s - %s", formatRecordAmount(record, base, -1), record.Description)
 	} else {
-		msgText = fmt.Sprintf("✅ 已记录收入: %.2f 元 - %s\n\n", amount, description)
+		msgText = fmt.Sprintf("✅ 已记录收入: %s - %s", formatRecordAmount(record, base, 1), record.Description)
+	}
+	if !dayStart(record.Date).Equal(dayStart(record.CreatedAt)) {
+		msgText += fmt.Sprintf("（%s）", record.Date.Format("2006-01-02"))
 	}
+	msgText += fmt.Sprintf("\n👛 钱包: %s", model.WalletName(record.Wallet))
+	if record.ReceiptFileID != "" {
+		msgText += "\n📎 已保存小票，可使用 /receipt 查看"
+	}
+	msgText += "\n\n"
 	
 	msgText += fmt.Sprintf(
 		"📊 当前统计:\n"+
-		"💰 总收入: %.2f 元\n"+
-		"💸 总支出: %.2f 元\n"+
-		"💵 剩余金额: %.2f 元\n"+
-		"⏰ 剩余天数: %d 天",
-		summary.TotalIncome,
-		summary.TotalExpense,
-		summary.Balance,
-		summary.DaysRemaining)
+		"💰 总收入: %s\n"+
+		"💸 总支出: %s\n"+
+		"💵 剩余金额: %s\n"+
+		"⏰ 剩余天数: %d 天\n%s",
+		formatMoney(summary.TotalIncome, base),
+		formatMoney(summary.TotalExpense, base),
+		formatMoney(summary.Balance, base),
+		summary.DaysRemaining,
+		strings.TrimSuffix(formatForecast(summary, cycle), "\n"))
 	
 	msg := tgbotapi.NewMessage(chatID, msgText)
-	_, err = l.svcCtx.Bot.Send(msg)
-	return err
+	return l.sendView(chatID, userID, msg)
+}
+
+// 根据请求构建一条记录，date 为记录所属的日期
+func (l *AccountingLogic) newRecord(userID int64, cycle *model.AccountingCycle, req *model.AccountingExpenseRequest, date time.Time) (*model.AccountingRecord, error) {
+	record := &model.AccountingRecord{
+		ID:            newShortID(),
+		Amount:        req.Amount, // 直接使用传入的金额，不再取负
+		Description:   req.Description,
+		Category:      req.Category,
+		ReceiptFileID: req.ReceiptFileID,
+		Wallet:        req.Wallet,
+		Date:          date,
+		CreatedAt:     time.Now(),
+	}
+	if record.Category == "" {
+		record.Category = model.Categorize(req.Description, req.Amount > 0)
+	}
+
+	// 支出备注中带"报销"时记录报销状态，例如 "打车-50（公司报销）"
+	if req.Amount < 0 {
+		_, note := model.SplitNote(req.Description)
+		record.Reimbursement = model.ParseReimbursement(note)
+	}
+
+	// 外币记录保留原始金额，并按记账日期的汇率折算为本位币
+	// 没有写币种时使用钱包固定的币种，例如 USDT 钱包
+	if record.Wallet == "" {
+		record.Wallet = l.getDefaultWallet(cycle.ChatID, cycle.UserID)
+	}
+	base := cycle.Currency()
+	currency := req.Currency
+	if currency == "" {
+		currency = model.WalletCurrency(record.Wallet)
+	}
+	if currency != "" && currency != base {
+		converted, rate, err := l.fx.Convert(userID, req.Amount, currency, base, record.Date)
+		if err != nil {
+			return nil, err
+		}
+		record.Amount = converted
+		record.Currency = currency
+		record.OriginalAmount = req.Amount
+		record.Rate = rate
+	}
+	return record, nil
 }
 
 // GetAccountingSummary 获取当前记账周期的摘要
 func (l *AccountingLogic) GetAccountingSummary(chatID int64, userID int64) error {
 	// 获取当前活跃的记账周期
-	activeKey := fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
-	cycleID, err := l.svcCtx.Redis.Get(activeKey)
-	if err != nil || cycleID == "" {
-		return fmt.Errorf("找不到活跃的记账周期，请先使用 /accounting_start 命令开始记账")
+	cycleID, err := l.getActiveCycleID(chatID, userID)
+	if err != nil {
+		return err
 	}
 
 	// 获取周期详情
@@ -223,97 +281,43 @@ func (l *AccountingLogic) GetAccountingSummary(chatID int64, userID int64) error
 		"📊 当前记账周期统计:\n"+
 			"📅 开始日期: %s\n"+
 			"⏰ 结束日期: %s\n"+
-			"💰 总收入: %.2f 元\n"+
-			"💸 总支出: %.2f 元\n"+
-			"💵 剩余金额: %.2f 元\n"+
-			"⏰ 剩余天数: %d 天\n\n",
+			"💰 总收入: %s\n"+
+			"💸 总支出: %s\n"+
+			"💵 剩余金额: %s\n"+
+			"⏰ 剩余天数: %d 天\n%s%s\n",
 		cycle.StartTime.Format("2006-01-02"),
 		cycle.EndTime.Format("2006-01-02"),
-		summary.TotalIncome,
-		summary.TotalExpense,
-		summary.Balance,
+		formatMoney(summary.TotalIncome, summary.Currency),
+		formatMoney(summary.TotalExpense, summary.Currency),
+		formatMoney(summary.Balance, summary.Currency),
 		summary.DaysRemaining,
+		formatForecast(summary, cycle),
+		formatCurrencyBreakdown(summary),
 	))
 
 	// 添加记录明细
-	if len(cycle.Records) > 0 {
-		msgText.WriteString("📝 记账明细:\n")
-		for i, record := range cycle.Records {
-			if record.Amount < 0 {
-				// 支出记录
-				msgText.WriteString(fmt.Sprintf("%d. %s - 支出 %.2f 元 - %s\n", 
-					i+1, 
-					record.Date.Format("01-02 15:04"), 
-					-record.Amount,
-					record.Description,
-				))
-			} else {
-				// 收入记录
-				msgText.WriteString(fmt.Sprintf("%d. %s - 收入 %.2f 元 - %s\n", 
-					i+1, 
-					record.Date.Format("01-02 15:04"), 
-					record.Amount,
-					record.Description,
-				))
-			}
-		}
-	} else {
-		msgText.WriteString("暂无记账记录")
-	}
+	writeRecords(&msgText, cycle)
 
 	// 发送摘要消息
 	msg := tgbotapi.NewMessage(chatID, msgText.String())
-	_, err = l.svcCtx.Bot.Send(msg)
-	return err
+	return l.sendView(chatID, userID, msg)
 }
 
-// 获取记账周期
-func (l *AccountingLogic) getAccountingCycle(cycleID string) (*model.AccountingCycle, error) {
-	cycleKey := fmt.Sprintf("accounting:cycle:%s", cycleID)
-	data, err := l.svcCtx.Redis.Get(cycleKey)
-	if err != nil {
-		return nil, fmt.Errorf("获取记账周期失败: %v", err)
-	}
-	if data == "" {
-		return nil, fmt.Errorf("记账周期不存在")
-	}
-
-	var cycle model.AccountingCycle
-	if err := json.Unmarshal([]byte(data), &cycle); err != nil {
-		return nil, fmt.Errorf("解析记账周期失败: %v", err)
-	}
-
-	return &cycle, nil
-}
-
-// 保存记账周期
-func (l *AccountingLogic) saveAccountingCycle(cycle *model.AccountingCycle) error {
-	cycleKey := fmt.Sprintf("accounting:cycle:%s", cycle.ID)
-	data, err := json.Marshal(cycle)
-	if err != nil {
-		return fmt.Errorf("序列化记账周期失败: %v", err)
-	}
-
-	return l.svcCtx.Redis.Set(cycleKey, string(data))
-}
-
-// 计算摘要
+// 计算摘要，收支合计读取记账时维护的合计，不需要加载记录
 func (l *AccountingLogic) calculateSummary(cycle *model.AccountingCycle) *model.AccountingSummary {
 	summary := &model.AccountingSummary{
-		TotalIncome: cycle.Income,  // 初始收入
-		TotalExpense: 0,
-		Balance: cycle.Income,
+		TotalIncome: cycle.Income, // 初始收入
+		Currency:    cycle.Currency(),
 	}
 
-	// 计算总收入和总支出
-	for _, record := range cycle.Records {
-		if record.Amount > 0 {
-			summary.TotalIncome += record.Amount
-		} else {
-			summary.TotalExpense += -record.Amount  // 支出是负数，取负后为正
-		}
+	income, expense, byCurrency, err := l.getTotals(cycle)
+	if err != nil {
+		log.Printf("读取收支合计失败: %v", err)
 	}
-	
+	summary.TotalIncome += income
+	summary.TotalExpense = expense
+	summary.ByCurrency = byCurrency
+
 	// 计算余额
 	summary.Balance = summary.TotalIncome - summary.TotalExpense
 
@@ -325,14 +329,50 @@ func (l *AccountingLogic) calculateSummary(cycle *model.AccountingCycle) *model.
 		summary.DaysRemaining = 0
 	}
 
+	calculateForecast(summary, cycle, now)
+
 	return summary
 }
 
+// 计算日均支出、每日可用金额和周期结束时的预计余额
+// 已过天数按自然天向上取整（开始当天算 1 天），与向下取整的剩余天数相加正好是周期总天数
+func calculateForecast(summary *model.AccountingSummary, cycle *model.AccountingCycle, now time.Time) {
+	end := now
+	if cycle.EndTime.Before(end) {
+		end = cycle.EndTime
+	}
+	elapsed := int(math.Ceil(end.Sub(cycle.StartTime).Hours() / 24))
+	if elapsed < 1 {
+		elapsed = 1
+	}
+	summary.AverageDailySpend = summary.TotalExpense.MulDiv(1, int64(elapsed))
+	summary.ProjectedBalance = summary.Balance
+
+	// 周期已结束时没有可用金额和预测
+	if !cycle.EndTime.After(now) {
+		return
+	}
+	// 最后不足一天时按一天计算
+	if summary.Balance > 0 {
+		summary.DailyAllowance = summary.Balance.MulDiv(1, int64(max(summary.DaysRemaining, 1)))
+	}
+	summary.ProjectedBalance = summary.Balance - summary.AverageDailySpend*model.Money(summary.DaysRemaining)
+
+	if summary.ProjectedBalance >= 0 || summary.AverageDailySpend <= 0 {
+		return
+	}
+	if summary.Balance <= 0 {
+		summary.OverdrawDate = now
+		return
+	}
+	days := float64(summary.Balance) / float64(summary.AverageDailySpend)
+	summary.OverdrawDate = now.Add(time.Duration(days * 24 * float64(time.Hour)))
+}
+
 // HasActiveAccountingCycle 检查用户是否有活跃的记账周期
 func (l *AccountingLogic) HasActiveAccountingCycle(chatID int64, userID int64) (bool, error) {
 	// 获取当前活跃的记账周期ID
-	activeKey := fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
-	cycleID, err := l.svcCtx.Redis.Get(activeKey)
+	cycleID, err := l.svcCtx.Ledger.ActiveCycleID(l.ctx, chatID, userID)
 	if err != nil {
 		return false, err
 	}
@@ -343,11 +383,11 @@ func (l *AccountingLogic) HasActiveAccountingCycle(chatID int64, userID int64) (
 	}
 	
 	// 尝试获取周期详情，确认是否有效
-	_, err = l.getAccountingCycle(cycleID)
+	_, err = l.getCycleMeta(cycleID)
 	if err != nil {
 		// 如果获取失败，说明记录可能已经被删除
 		// 清除活跃标记
-		_, _ = l.svcCtx.Redis.Del(activeKey)
+		_ = l.svcCtx.Ledger.ClearActiveCycleID(l.ctx, chatID, userID)
 		return false, nil
 	}
 	
@@ -356,22 +396,22 @@ func (l *AccountingLogic) HasActiveAccountingCycle(chatID int64, userID int64) (
 
 // GetAccountingHistory 获取用户的历史记账记录
 func (l *AccountingLogic) GetAccountingHistory(chatID int64, userID int64) error {
+	return l.sendHistory(chatID, userID, userID)
+}
+
+// 发送 ownerID 的历史记账记录，按查看者的隐私设置发送
+func (l *AccountingLogic) sendHistory(chatID int64, viewerID int64, ownerID int64) error {
 	// 获取用户所有的记账周期
-	historyKey := fmt.Sprintf("accounting:history:%d:%d", chatID, userID)
-	data, err := l.svcCtx.Redis.Get(historyKey)
+	cycleIDs, err := l.getHistoryCycleIDs(chatID, ownerID)
 	if err != nil {
-		return fmt.Errorf("获取历史记录失败: %v", err)
-	}
-	
-	var cycleIDs []string
-	if data != "" {
-		if err := json.Unmarshal([]byte(data), &cycleIDs); err != nil {
-			return fmt.Errorf("解析历史记录失败: %v", err)
-		}
+		return err
 	}
 	
 	if len(cycleIDs) == 0 {
 		msg := tgbotapi.NewMessage(chatID, "您还没有任何记账记录")
+		if viewerID != ownerID {
+			msg.Text = "对方还没有任何记账记录"
+		}
 		_, err = l.svcCtx.Bot.Send(msg)
 		return err
 	}
@@ -385,7 +425,7 @@ func (l *AccountingLogic) GetAccountingHistory(chatID int64, userID int64) error
 	
 	// 按周期顺序显示简要信息
 	for i, cycleID := range cycleIDs {
-		cycle, err := l.getAccountingCycle(cycleID)
+		cycle, err := l.getCycleMeta(cycleID)
 		if err != nil {
 			// 如果某个周期获取失败，跳过
 			continue
@@ -398,10 +438,10 @@ func (l *AccountingLogic) GetAccountingHistory(chatID int64, userID int64) error
 			i+1,
 			cycle.StartTime.Format("2006-01-02"),
 			cycle.EndTime.Format("2006-01-02")))
-		msgText.WriteString(fmt.Sprintf("   收入: %.2f元, 支出: %.2f元, 余额: %.2f元\n\n", 
-			summary.TotalIncome,
-			summary.TotalExpense,
-			summary.Balance))
+		msgText.WriteString(fmt.Sprintf("   收入: %s, 支出: %s, 余额: %s\n\n", 
+			formatMoney(summary.TotalIncome, summary.Currency),
+			formatMoney(summary.TotalExpense, summary.Currency),
+			formatMoney(summary.Balance, summary.Currency)))
 		
 		// 为每个周期创建一个按钮
 		button := tgbotapi.NewInlineKeyboardButtonData(
@@ -413,17 +453,19 @@ func (l *AccountingLogic) GetAccountingHistory(chatID int64, userID int64) error
 	
 	msg := tgbotapi.NewMessage(chatID, msgText.String())
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = l.svcCtx.Bot.Send(msg)
-	return err
+	return l.sendView(chatID, viewerID, msg)
 }
 
-// GetAccountingCycleById 根据ID获取并显示特定记账周期的详情
-func (l *AccountingLogic) GetAccountingCycleById(chatID int64, cycleID string) error {
+// GetAccountingCycleById 根据ID获取并显示特定记账周期的详情，只有所有者和被共享的用户可以查看
+func (l *AccountingLogic) GetAccountingCycleById(chatID int64, userID int64, cycleID string) error {
 	// 获取周期详情
 	cycle, err := l.getAccountingCycle(cycleID)
 	if err != nil {
 		return fmt.Errorf("获取记账周期失败: %v", err)
 	}
+	if !l.canViewCycle(cycle, userID) {
+		return fmt.Errorf("无权查看该记账周期")
+	}
 	
 	// 计算摘要
 	summary := l.calculateSummary(cycle)
@@ -433,77 +475,163 @@ func (l *AccountingLogic) GetAccountingCycleById(chatID int64, cycleID string) e
 	msgText.WriteString(fmt.Sprintf(
 		"📊 记账周期详情:\n"+
 			"📅 周期: %s 至 %s\n"+
-			"💰 总收入: %.2f 元\n"+
-			"💸 总支出: %.2f 元\n"+
-			"💵 最终余额: %.2f 元\n\n",
+			"💰 总收入: %s\n"+
+			"💸 总支出: %s\n"+
+			"💵 最终余额: %s\n%s\n",
 		cycle.StartTime.Format("2006-01-02"),
 		cycle.EndTime.Format("2006-01-02"),
-		summary.TotalIncome,
-		summary.TotalExpense,
-		summary.Balance,
+		formatMoney(summary.TotalIncome, summary.Currency),
+		formatMoney(summary.TotalExpense, summary.Currency),
+		formatMoney(summary.Balance, summary.Currency),
+		formatCurrencyBreakdown(summary),
 	))
 	
 	// 添加记录明细
-	if len(cycle.Records) > 0 {
-		msgText.WriteString("📝 记账明细:\n")
-		for i, record := range cycle.Records {
-			if record.Amount < 0 {
-				// 支出记录
-				msgText.WriteString(fmt.Sprintf("%d. %s - 支出 %.2f 元 - %s\n", 
-					i+1, 
-					record.Date.Format("01-02 15:04"), 
-					-record.Amount,
-					record.Description,
-				))
-			} else {
-				// 收入记录
-				msgText.WriteString(fmt.Sprintf("%d. %s - 收入 %.2f 元 - %s\n", 
-					i+1, 
-					record.Date.Format("01-02 15:04"), 
-					record.Amount,
-					record.Description,
-				))
-			}
-		}
-	} else {
-		msgText.WriteString("暂无记账记录")
-	}
+	writeRecords(&msgText, cycle)
 	
 	// 发送消息
 	msg := tgbotapi.NewMessage(chatID, msgText.String())
-	_, err = l.svcCtx.Bot.Send(msg)
-	return err
+	return l.sendView(chatID, userID, msg)
 }
 
-// 添加记账周期到历史记录
-func (l *AccountingLogic) addToHistory(chatID int64, userID int64, cycleID string) error {
-	historyKey := fmt.Sprintf("accounting:history:%d:%d", chatID, userID)
-	
-	// 获取现有历史记录
-	data, err := l.svcCtx.Redis.Get(historyKey)
-	var cycleIDs []string
-	
-	if err == nil && data != "" {
-		if err := json.Unmarshal([]byte(data), &cycleIDs); err != nil {
-			return fmt.Errorf("解析历史记录失败: %v", err)
+
+// 格式化金额，人民币显示为"元"，其他币种显示代码
+func formatMoney(amount model.Money, currency string) string {
+	if currency == "" || currency == model.DefaultCurrency {
+		return fmt.Sprintf("%s 元", amount)
+	}
+	return fmt.Sprintf("%s %s", amount, currency)
+}
+
+// 格式化记录金额，外币记录附带原始金额；sign 为 -1 时显示绝对值
+func formatRecordAmount(record *model.AccountingRecord, base string, sign model.Money) string {
+	text := formatMoney(record.Amount*sign, base)
+	if currency := record.OriginalCurrency(base); currency != base {
+		text += fmt.Sprintf(" (%s)", formatMoney(record.OriginalAmount*sign, currency))
+	}
+	return text
+}
+
+// 按原始币种输出收支明细，只有出现外币记录时才显示
+func formatCurrencyBreakdown(summary *model.AccountingSummary) string {
+	foreign := false
+	for _, total := range summary.ByCurrency {
+		if total.Currency != summary.Currency {
+			foreign = true
+			break
 		}
 	}
-	
-	// 检查是否已存在
-	for _, id := range cycleIDs {
-		if id == cycleID {
-			return nil // 已存在，不需要添加
+	if !foreign {
+		return ""
+	}
+
+	var text strings.Builder
+	text.WriteString("🌐 按原始币种:\n")
+	for _, total := range summary.ByCurrency {
+		text.WriteString(fmt.Sprintf("   %s: 收入 %s, 支出 %s\n",
+			total.Currency,
+			formatMoney(total.Income, total.Currency),
+			formatMoney(total.Expense, total.Currency)))
+	}
+	return text.String()
+}
+
+// 每日可用金额、日均支出和周期结束时的预计余额，预计透支时附带提醒
+func formatForecast(summary *model.AccountingSummary, cycle *model.AccountingCycle) string {
+	text := fmt.Sprintf("🎯 每日可用: %s\n📉 日均支出: %s\n🔮 预计结余: %s\n",
+		formatMoney(summary.DailyAllowance, summary.Currency),
+		formatMoney(summary.AverageDailySpend, summary.Currency),
+		formatMoney(summary.ProjectedBalance, summary.Currency))
+	switch {
+	case summary.OverdrawDate.IsZero():
+	case summary.Balance <= 0:
+		text += "⚠️ 余额已用完，请控制支出\n"
+	default:
+		text += fmt.Sprintf("⚠️ 按当前速度，余额将在 %s 左右用完，早于周期结束日 %s\n",
+			summary.OverdrawDate.Format("01-02"), cycle.EndTime.Format("01-02"))
+	}
+	return text
+}
+
+// 报销状态标记
+func formatReimbursementMark(record *model.AccountingRecord) string {
+	if record.Reimbursement == nil {
+		return ""
+	}
+	return fmt.Sprintf(" [🧾%s]", model.ReimbursementStatusName(record.Reimbursement.Status))
+}
+
+// 小票附件标记
+func formatReceiptMark(record *model.AccountingRecord) string {
+	if record.ReceiptFileID == "" {
+		return ""
+	}
+	return " 📎"
+}
+
+// 按记录日期排序，补记的账显示在对应的日期，明细中的序号以此为准
+func sortedRecords(cycle *model.AccountingCycle) []*model.AccountingRecord {
+	records := make([]*model.AccountingRecord, len(cycle.Records))
+	copy(records, cycle.Records)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	return records
+}
+
+// 输出周期的记账明细
+func writeRecords(msgText *strings.Builder, cycle *model.AccountingCycle) {
+	if len(cycle.Records) == 0 {
+		msgText.WriteString("暂无记账记录")
+		return
+	}
+
+	records := sortedRecords(cycle)
+	base := cycle.Currency()
+	msgText.WriteString("📝 记账明细:\n")
+	for i, record := range records {
+		if record.Amount < 0 {
+			// 支出记录
+			msgText.WriteString(fmt.Sprintf("%d. %s - 支出 %s - %s%s%s\n",
+				i+1,
+				record.Date.Format("01-02 15:04"),
+				formatRecordAmount(record, base, -1),
+				record.Description,
+				formatReimbursementMark(record),
+				formatReceiptMark(record),
+			))
+		} else {
+			// 收入记录
+			msgText.WriteString(fmt.Sprintf("%d. %s - 收入 %s - %s%s\n",
+				i+1,
+				record.Date.Format("01-02 15:04"),
+				formatRecordAmount(record, base, 1),
+				record.Description,
+				formatReceiptMark(record),
+			))
 		}
 	}
-	
-	// 添加新的周期ID
-	cycleIDs = append(cycleIDs, cycleID)
-	
-	// 保存到Redis
-	updatedData, err := json.Marshal(cycleIDs)
+}
+
+// 加载用户全部历史记账周期，获取失败的周期直接跳过
+func (l *AccountingLogic) getHistoryCycles(chatID int64, userID int64) ([]*model.AccountingCycle, error) {
+	cycleIDs, err := l.getHistoryCycleIDs(chatID, userID)
 	if err != nil {
-		return fmt.Errorf("序列化历史记录失败: %v", err)
+		return nil, err
 	}
-	
-	return l.svcCtx.Redis.Set(historyKey, string(updatedData))
-} 
\ No newline at end of file
+
+	cycles := make([]*model.AccountingCycle, 0, len(cycleIDs))
+	for _, cycleID := range cycleIDs {
+		cycle, err := l.getAccountingCycle(cycleID)
+		if err != nil {
+			continue
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles, nil
+}
+
+// 生成短ID，按钮回调数据最长 64 字节，使用 12 位十六进制
+func newShortID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+}
@@ -0,0 +1,57 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+func TestCalculateForecast(t *testing.T) {
+	// 2026-10-14 12:00
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		expense    model.Money
+		balance    model.Money
+		started    int // 已过天数
+		remaining  int // 剩余天数，负数表示周期已结束
+		average    model.Money
+		allowance  model.Money
+		projected  model.Money
+		overdrawOn string // 预计透支的日期，不透支时为空
+	}{
+		{"整除", 9000, 40000, 3, 4, 3000, 10000, 28000, ""},
+		{"日均向下舍入", 10000, 20000, 3, 4, 3333, 5000, 6668, ""},
+		{"日均向上进位", 20000, 20000, 3, 3, 6667, 6667, -1, "10-17"},
+		{"每日可用四舍五入", 3000, 10000, 3, 3, 1000, 3333, 7000, ""},
+		{"预计透支", 20000, 10000, 3, 4, 6667, 2500, -16668, "10-15"},
+		{"余额已用完", 30000, -500, 3, 4, 10000, 0, -40500, "10-14"},
+		{"开始不足一天", 1000, 9000, 0, 6, 1000, 1500, 3000, ""},
+		{"周期已结束", 10000, 5000, 7, -1, 1667, 0, 5000, ""},
+	}
+	for _, tt := range tests {
+		cycle := &model.AccountingCycle{
+			StartTime: now.AddDate(0, 0, -tt.started),
+			EndTime:   now.AddDate(0, 0, tt.remaining),
+		}
+		summary := &model.AccountingSummary{
+			TotalExpense:  tt.expense,
+			Balance:       tt.balance,
+			DaysRemaining: max(tt.remaining, 0),
+		}
+		calculateForecast(summary, cycle, now)
+
+		if summary.AverageDailySpend != tt.average || summary.DailyAllowance != tt.allowance || summary.ProjectedBalance != tt.projected {
+			t.Errorf("%s: 日均 %s 每日可用 %s 预计结余 %s, want %s %s %s", tt.name,
+				summary.AverageDailySpend, summary.DailyAllowance, summary.ProjectedBalance, tt.average, tt.allowance, tt.projected)
+		}
+		overdrawOn := ""
+		if !summary.OverdrawDate.IsZero() {
+			overdrawOn = summary.OverdrawDate.Format("01-02")
+		}
+		if overdrawOn != tt.overdrawOn {
+			t.Errorf("%s: 预计透支日期 %q, want %q", tt.name, overdrawOn, tt.overdrawOn)
+		}
+	}
+}
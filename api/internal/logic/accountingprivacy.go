@@ -0,0 +1,209 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// SetPrivacy 设置账本的查看方式，对用户所在的所有群生效
+// 改为私聊发送前先发一条私聊消息，确认用户已经和机器人开始过对话
+func (l *AccountingLogic) SetPrivacy(chatID int64, userID int64, mode string) error {
+	if mode != model.PrivacyDM && mode != model.PrivacyGroup {
+		return fmt.Errorf("无效的查看方式: %s", mode)
+	}
+	if mode == model.PrivacyDM {
+		msg := tgbotapi.NewMessage(userID, "🔒 之后的账单摘要、历史和明细会通过私聊发送给你")
+		if _, err := l.svcCtx.Bot.Send(msg); err != nil {
+			return fmt.Errorf("无法私聊发送，请先私聊机器人发送 /start: %v", err)
+		}
+	}
+	if err := l.svcCtx.Redis.Set(privacyKey(userID), mode); err != nil {
+		return fmt.Errorf("保存隐私设置失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 账单摘要、历史和明细将%s", model.PrivacyName(mode)))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ShowPrivacy 显示当前的查看方式和账本共享情况
+func (l *AccountingLogic) ShowPrivacy(chatID int64, userID int64) error {
+	shares, err := l.getShares(shareKey(chatID, userID))
+	if err != nil {
+		return err
+	}
+	shared, err := l.getShares(sharedKey(chatID, userID))
+	if err != nil {
+		return err
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("🔒 账单摘要、历史和明细: %s\n", model.PrivacyName(l.getPrivacy(userID))))
+	msgText.WriteString("使用 /privacy dm 或 /privacy group 修改\n")
+	if len(shares) > 0 {
+		msgText.WriteString("\n👀 可以查看你账本的人:\n")
+		for _, share := range shares {
+			msgText.WriteString(fmt.Sprintf("• %s\n", share.ViewerName))
+		}
+	}
+	msgText.WriteString("\n回复对方的消息发送 /accounting_share 共享账本，/accounting_share remove 取消共享\n")
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if len(shared) > 0 {
+		msgText.WriteString("\n📖 共享给你的账本:\n")
+		for _, share := range shared {
+			msgText.WriteString(fmt.Sprintf("• %s\n", share.OwnerName))
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("查看 %s 的历史", share.OwnerName),
+				fmt.Sprintf("shared_history_%d", share.OwnerID))))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	if len(keyboard) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	}
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ShareLedger 共享或取消共享自己在本群的账本，被共享的用户只能查看，不能记账
+func (l *AccountingLogic) ShareLedger(chatID int64, share *model.LedgerShare, revoke bool) error {
+	if share.OwnerID == share.ViewerID {
+		return fmt.Errorf("不能共享给自己")
+	}
+	share.ChatID = chatID
+	ownerKey, viewerKey := shareKey(chatID, share.OwnerID), sharedKey(chatID, share.ViewerID)
+	ownerField, viewerField := strconv.FormatInt(share.ViewerID, 10), strconv.FormatInt(share.OwnerID, 10)
+
+	text := fmt.Sprintf("👀 %s 现在可以查看 %s 的账本，使用 /privacy 查看共享给你的账本", share.ViewerName, share.OwnerName)
+	if revoke {
+		if _, err := l.svcCtx.Redis.Hdel(ownerKey, ownerField); err != nil {
+			return fmt.Errorf("取消共享失败: %v", err)
+		}
+		if _, err := l.svcCtx.Redis.Hdel(viewerKey, viewerField); err != nil {
+			return fmt.Errorf("取消共享失败: %v", err)
+		}
+		text = fmt.Sprintf("🔒 已取消向 %s 共享账本", share.ViewerName)
+	} else {
+		data, err := json.Marshal(share)
+		if err != nil {
+			return fmt.Errorf("序列化共享设置失败: %v", err)
+		}
+		if err := l.svcCtx.Redis.Hset(ownerKey, ownerField, string(data)); err != nil {
+			return fmt.Errorf("保存共享设置失败: %v", err)
+		}
+		if err := l.svcCtx.Redis.Hset(viewerKey, viewerField, string(data)); err != nil {
+			return fmt.Errorf("保存共享设置失败: %v", err)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ViewSharedHistory 查看共享给自己的账本的历史记录
+func (l *AccountingLogic) ViewSharedHistory(chatID int64, viewerID int64, ownerID int64) error {
+	if !l.isSharedWith(chatID, ownerID, viewerID) {
+		return fmt.Errorf("对方没有向你共享账本")
+	}
+	return l.sendHistory(chatID, viewerID, ownerID)
+}
+
+// 判断用户能否查看记账周期：所有者本人，或者所有者在该群共享过账本的用户
+func (l *AccountingLogic) canViewCycle(cycle *model.AccountingCycle, userID int64) bool {
+	return cycle.UserID == userID || l.isSharedWith(cycle.ChatID, cycle.UserID, userID)
+}
+
+func (l *AccountingLogic) isSharedWith(chatID int64, ownerID int64, viewerID int64) bool {
+	ok, err := l.svcCtx.Redis.Hexists(shareKey(chatID, ownerID), strconv.FormatInt(viewerID, 10))
+	if err != nil {
+		log.Printf("获取共享设置失败: %v", err)
+		return false
+	}
+	return ok
+}
+
+func (l *AccountingLogic) getShares(key string) ([]*model.LedgerShare, error) {
+	data, err := l.svcCtx.Redis.Hgetall(key)
+	if err != nil {
+		return nil, fmt.Errorf("获取共享设置失败: %v", err)
+	}
+	shares := make([]*model.LedgerShare, 0, len(data))
+	for _, value := range data {
+		var share model.LedgerShare
+		if err := json.Unmarshal([]byte(value), &share); err != nil {
+			log.Printf("解析共享设置失败: %v", err)
+			continue
+		}
+		shares = append(shares, &share)
+	}
+	return shares, nil
+}
+
+func (l *AccountingLogic) getPrivacy(userID int64) string {
+	mode, err := l.svcCtx.Redis.Get(privacyKey(userID))
+	if err != nil || mode == "" {
+		return model.PrivacyGroup
+	}
+	return mode
+}
+
+// 账本内容的发送目标：私聊中直接发送；用户设置了私聊发送时发给用户本人
+func (l *AccountingLogic) viewChat(chatID int64, userID int64) int64 {
+	if chatID == userID || l.getPrivacy(userID) != model.PrivacyDM {
+		return chatID
+	}
+	return userID
+}
+
+// 发送账本内容，按隐私设置改为私聊时在原聊天中留一条提示
+func (l *AccountingLogic) sendView(chatID int64, userID int64, msg tgbotapi.MessageConfig) error {
+	target := l.viewChat(chatID, userID)
+	msg.ChatID = target
+	return l.sendViewTo(chatID, target, msg)
+}
+
+// 发送已按 viewChat 设置好目标的账本内容，用于照片等非文本消息
+func (l *AccountingLogic) sendViewTo(chatID int64, target int64, c tgbotapi.Chattable) error {
+	if _, err := l.svcCtx.Bot.Send(c); err != nil {
+		if target != chatID {
+			return fmt.Errorf("私聊发送失败，请先私聊机器人发送 /start: %v", err)
+		}
+		return err
+	}
+	l.notifyViewSent(chatID, target)
+	return nil
+}
+
+// 内容已私聊发送时在原聊天中提示
+func (l *AccountingLogic) notifyViewSent(chatID int64, target int64) {
+	if target == chatID {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, "📬 已通过私聊发送")
+	if _, err := l.svcCtx.Bot.Send(msg); err != nil {
+		log.Printf("发送私聊提示失败: %v", err)
+	}
+}
+
+func privacyKey(userID int64) string {
+	return fmt.Sprintf("accounting:privacy:%d", userID)
+}
+
+// 所有者共享给了哪些人
+func shareKey(chatID int64, ownerID int64) string {
+	return fmt.Sprintf("accounting:share:%d:%d", chatID, ownerID)
+}
+
+// 哪些人共享给了该用户
+func sharedKey(chatID int64, viewerID int64) string {
+	return fmt.Sprintf("accounting:shared:%d:%d", chatID, viewerID)
+}
@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// SendReceipt 重新发送记录的小票照片
+// ref 为 /accounting_status 明细中的序号，或者记录ID（可查找历史周期）；为空时列出当前周期所有带小票的记录
+func (l *AccountingLogic) SendReceipt(chatID int64, userID int64, ref string) error {
+	if ref == "" {
+		return l.listReceipts(chatID, userID)
+	}
+
+	record, base, err := l.findReceiptRecord(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	if record.ReceiptFileID == "" {
+		return fmt.Errorf("该记录没有小票照片")
+	}
+
+	sign := model.Money(1)
+	if record.Amount < 0 {
+		sign = -1
+	}
+	target := l.viewChat(chatID, userID)
+	photo := tgbotapi.NewPhoto(target, tgbotapi.FileID(record.ReceiptFileID))
+	photo.Caption = fmt.Sprintf("📎 %s %s - %s",
+		record.Date.Format("2006-01-02 15:04"), formatRecordAmount(record, base, sign), record.Description)
+	return l.sendViewTo(chatID, target, photo)
+}
+
+// 列出当前周期带小票的记录，序号与 /accounting_status 明细一致
+func (l *AccountingLogic) listReceipts(chatID int64, userID int64) error {
+	cycle, err := l.getActiveCycle(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	var msgText strings.Builder
+	for i, record := range sortedRecords(cycle) {
+		if record.ReceiptFileID == "" {
+			continue
+		}
+		msgText.WriteString(fmt.Sprintf("%d. %s - %s %s\n",
+			i+1, record.Date.Format("01-02 15:04"), record.Description, formatMoney(record.Amount.Abs(), cycle.Currency())))
+	}
+
+	text := "当前周期没有带小票的记录\n\n回复机器人消息时发送照片，并在说明中写上账目（例如: 超市 -86.5）即可保存小票"
+	if msgText.Len() > 0 {
+		text = "📎 带小票的记录:\n" + msgText.String() + "\n使用 /receipt <序号> 查看小票"
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	return l.sendView(chatID, userID, msg)
+}
+
+// 先按当前周期明细序号查找，再按记录ID在历史周期中查找
+func (l *AccountingLogic) findReceiptRecord(chatID int64, userID int64, ref string) (*model.AccountingRecord, string, error) {
+	if index, err := strconv.Atoi(ref); err == nil {
+		cycle, err := l.getActiveCycle(chatID, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		records := sortedRecords(cycle)
+		if index < 1 || index > len(records) {
+			return nil, "", fmt.Errorf("序号超出范围，当前周期共 %d 条记录", len(records))
+		}
+		return records[index-1], cycle.Currency(), nil
+	}
+
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, cycle := range cycles {
+		for _, record := range cycle.Records {
+			if record.ID == ref {
+				return record, cycle.Currency(), nil
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("找不到记录 %s", ref)
+}
@@ -0,0 +1,186 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 报销列表中最多带按钮的条目数，避免键盘过长
+const reimbursementListLimit = 20
+
+// ListReimbursements 列出所有未到账的报销记录及合计，每条记录附带状态按钮
+// 列表可能按隐私设置私聊发送，按钮中带上账本所在的聊天ID: reimb_<操作>_<聊天ID>_<记录ID>
+func (l *AccountingLogic) ListReimbursements(chatID int64, userID int64) error {
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	type item struct {
+		cycle  *model.AccountingCycle
+		record *model.AccountingRecord
+	}
+	var items []item
+	totals := make(map[string]map[string]model.Money)
+	for _, cycle := range cycles {
+		for _, record := range cycle.Records {
+			if record.Reimbursement == nil || record.Reimbursement.Status == model.ReimbursePaid {
+				continue
+			}
+			items = append(items, item{cycle, record})
+			status := record.Reimbursement.Status
+			if totals[status] == nil {
+				totals[status] = make(map[string]model.Money)
+			}
+			totals[status][cycle.Currency()] += record.Amount.Abs()
+		}
+	}
+
+	if len(items) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "🧾 没有待报销的记录\n\n记账时在描述后加上备注即可登记，例如: 打车-50（公司报销）")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString("🧾 待报销记录:\n\n")
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for i, it := range items {
+		if i >= reimbursementListLimit {
+			msgText.WriteString(fmt.Sprintf("... 其余 %d 条未显示\n", len(items)-reimbursementListLimit))
+			break
+		}
+		reimb := it.record.Reimbursement
+		payer := ""
+		if reimb.Payer != "" {
+			payer = "，报销方: " + reimb.Payer
+		}
+		msgText.WriteString(fmt.Sprintf("%d. %s %s - %s [%s%s]\n",
+			i+1,
+			it.record.Date.Format("01-02"),
+			formatRecordAmount(it.record, it.cycle.Currency(), -1),
+			it.record.Description,
+			model.ReimbursementStatusName(reimb.Status),
+			payer,
+		))
+
+		var row []tgbotapi.InlineKeyboardButton
+		if reimb.Status == model.ReimbursePending {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📤 %d 已提交", i+1),
+				fmt.Sprintf("reimb_submit_%d_%s", chatID, it.record.ID)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("💰 %d 已到账", i+1),
+			fmt.Sprintf("reimb_paid_%d_%s", chatID, it.record.ID)))
+		keyboard = append(keyboard, row)
+	}
+
+	msgText.WriteString("\n合计:\n")
+	for _, status := range []string{model.ReimbursePending, model.ReimburseSubmitted} {
+		for currency, amount := range totals[status] {
+			msgText.WriteString(fmt.Sprintf("%s: %s\n", model.ReimbursementStatusName(status), formatMoney(amount, currency)))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return l.sendView(chatID, userID, msg)
+}
+
+// SubmitReimbursement 将报销记录标记为已提交，ledgerChatID 为账本所在的聊天
+func (l *AccountingLogic) SubmitReimbursement(chatID int64, ledgerChatID int64, userID int64, recordID string) error {
+	cycle, record, err := l.findReimbursement(ledgerChatID, userID, recordID)
+	if err != nil {
+		return err
+	}
+	if record.Reimbursement.Status != model.ReimbursePending {
+		return fmt.Errorf("该记录%s", model.ReimbursementStatusName(record.Reimbursement.Status))
+	}
+
+	record.Reimbursement.Status = model.ReimburseSubmitted
+	record.Reimbursement.UpdatedAt = time.Now()
+	if err := l.updateRecord(cycle, record); err != nil {
+		return err
+	}
+	l.audit(cycle, userID, model.AuditEdit, record.ID,
+		model.ReimbursementStatusName(model.ReimbursePending), model.ReimbursementStatusName(model.ReimburseSubmitted),
+		"报销状态: "+record.Description)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📤 已标记为已提交报销: %s - %s",
+		formatRecordAmount(record, cycle.Currency(), -1), record.Description))
+	return l.sendView(chatID, userID, msg)
+}
+
+// MarkReimbursed 将报销记录标记为已到账，并生成对应的收入记录
+// 收入记入当前活跃周期；没有活跃周期时记入原支出所在的周期，ledgerChatID 为账本所在的聊天
+func (l *AccountingLogic) MarkReimbursed(chatID int64, ledgerChatID int64, userID int64, recordID string) error {
+	cycle, record, err := l.findReimbursement(ledgerChatID, userID, recordID)
+	if err != nil {
+		return err
+	}
+	if record.Reimbursement.Status == model.ReimbursePaid {
+		return fmt.Errorf("该记录已报销")
+	}
+
+	target := cycle
+	if active, err := l.getActiveCycle(ledgerChatID, userID); err == nil && active.ID != cycle.ID {
+		target = active
+	}
+
+	// 报销款按原支出金额记入，目标周期本位币不同时按今天的汇率折算
+	now := time.Now()
+	amount := record.Amount.Abs()
+	if target.Currency() != cycle.Currency() {
+		amount, _, err = l.fx.Convert(userID, amount, cycle.Currency(), target.Currency(), now)
+		if err != nil {
+			return err
+		}
+	}
+
+	description := fmt.Sprintf("报销到账: %s", record.Description)
+	income := &model.AccountingRecord{
+		ID:          newShortID(),
+		Amount:      amount,
+		Description: description,
+		Category:    model.CategoryIncome,
+		Date:        now,
+		CreatedAt:   now,
+	}
+	if err := l.appendRecords(target, income); err != nil {
+		return err
+	}
+	l.auditRecord(target, userID, income, "报销到账")
+
+	before := model.ReimbursementStatusName(record.Reimbursement.Status)
+	record.Reimbursement.Status = model.ReimbursePaid
+	record.Reimbursement.IncomeRecordID = income.ID
+	record.Reimbursement.UpdatedAt = now
+	if err := l.updateRecord(cycle, record); err != nil {
+		return err
+	}
+	l.audit(cycle, userID, model.AuditEdit, record.ID, before, model.ReimbursementStatusName(model.ReimbursePaid),
+		"报销状态: "+record.Description)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("💰 报销已到账: %s - %s\n已记录收入 %s",
+		formatRecordAmount(record, cycle.Currency(), -1), record.Description, formatMoney(amount, target.Currency())))
+	return l.sendView(chatID, userID, msg)
+}
+
+// 在用户的所有周期中查找带报销信息的记录
+func (l *AccountingLogic) findReimbursement(chatID int64, userID int64, recordID string) (*model.AccountingCycle, *model.AccountingRecord, error) {
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, cycle := range cycles {
+		for _, record := range cycle.Records {
+			if record.ID == recordID && record.Reimbursement != nil {
+				return cycle, record, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("找不到该报销记录")
+}
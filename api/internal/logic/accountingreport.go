@@ -0,0 +1,182 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+const (
+	// 报告中列出的支出分类数
+	reportTopCategories = 5
+	// 报告中列出的最大单笔支出数
+	reportTopExpenses = 5
+)
+
+// 一个统计周期内的汇总
+type reportTotals struct {
+	income     model.Money
+	expense    model.Money
+	count      int
+	categories map[string]model.Money        // 按分类的支出
+	months     map[time.Month][2]model.Money // 按月的收入和支出，年报使用
+	expenses   []*model.AccountingRecord     // 所有支出记录
+	currencies map[string]bool
+}
+
+// SendReport 按自然月或自然年汇总所有记账周期的记录并发送报告
+// 记录按自身的 Date 归入统计周期，跨月的记账周期会被正确拆分；
+// 周期的初始收入没有对应的记录，按周期开始时间计入
+func (l *AccountingLogic) SendReport(chatID int64, userID int64, req *model.AccountingReportRequest) error {
+	cycles, err := l.getHistoryCycles(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	current := aggregateReport(cycles, req.Start, req.End())
+	previous := aggregateReport(cycles, req.Previous(), req.Start)
+	if current.count == 0 && current.income == 0 {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%s 没有记账记录", req.Label()))
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	// 报告使用最近一个周期的本位币
+	currency := model.DefaultCurrency
+	if len(cycles) > 0 {
+		currency = cycles[len(cycles)-1].Currency()
+	}
+	previousName := "上月"
+	if req.Period == model.ReportYear {
+		previousName = "去年"
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("📅 %s账单报告\n\n", req.Label()))
+	msgText.WriteString(fmt.Sprintf("💰 收入: %s（较%s %s）\n",
+		formatMoney(current.income, currency), previousName, formatChange(current.income, previous.income, currency)))
+	msgText.WriteString(fmt.Sprintf("💸 支出: %s（较%s %s）\n",
+		formatMoney(current.expense, currency), previousName, formatChange(current.expense, previous.expense, currency)))
+	msgText.WriteString(fmt.Sprintf("💵 结余: %s\n", formatMoney(current.income-current.expense, currency)))
+	msgText.WriteString(fmt.Sprintf("📝 记录: %d 笔，日均支出 %s\n",
+		current.count, formatMoney(current.expense/model.Money(reportDays(req)), currency)))
+
+	if req.Period == model.ReportYear {
+		msgText.WriteString("\n📆 每月收支:\n")
+		for month := time.January; month <= time.December; month++ {
+			totals, ok := current.months[month]
+			if !ok {
+				continue
+			}
+			msgText.WriteString(fmt.Sprintf("%2d月: 收入 %s，支出 %s\n",
+				month, formatMoney(totals[0], currency), formatMoney(totals[1], currency)))
+		}
+	}
+
+	if current.expense > 0 {
+		msgText.WriteString(fmt.Sprintf("\n🏷 支出分类 Top %d:\n", reportTopCategories))
+		for i, category := range sortedCategories(current.categories) {
+			if i == reportTopCategories {
+				break
+			}
+			amount := current.categories[category]
+			msgText.WriteString(fmt.Sprintf("%d. %s %s (%.1f%%)，较%s %s\n",
+				i+1, category, formatMoney(amount, currency),
+				float64(amount)*100/float64(current.expense),
+				previousName, formatChange(amount, previous.categories[category], currency)))
+		}
+
+		sort.SliceStable(current.expenses, func(i, j int) bool {
+			return current.expenses[i].Amount < current.expenses[j].Amount
+		})
+		msgText.WriteString(fmt.Sprintf("\n💥 最大单笔支出 Top %d:\n", reportTopExpenses))
+		for i, record := range current.expenses {
+			if i == reportTopExpenses {
+				break
+			}
+			msgText.WriteString(fmt.Sprintf("%d. %s %s - %s\n",
+				i+1, record.Date.Format("01-02"), formatMoney(-record.Amount, currency), record.Description))
+		}
+	}
+
+	if len(current.currencies) > 1 {
+		msgText.WriteString("\n⚠️ 包含多个本位币的周期，金额未统一折算")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.TrimRight(msgText.String(), "\n"))
+	return l.sendView(chatID, userID, msg)
+}
+
+// 汇总 [start, end) 内的记录
+func aggregateReport(cycles []*model.AccountingCycle, start time.Time, end time.Time) *reportTotals {
+	totals := &reportTotals{
+		categories: make(map[string]model.Money),
+		months:     make(map[time.Month][2]model.Money),
+		currencies: make(map[string]bool),
+	}
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(start) && t.Before(end)
+	}
+
+	for _, cycle := range cycles {
+		if cycle.Income > 0 && inPeriod(cycle.StartTime) {
+			totals.income += cycle.Income
+			totals.addMonth(cycle.StartTime, cycle.Income)
+			totals.currencies[cycle.Currency()] = true
+		}
+		for _, record := range cycle.Records {
+			if !inPeriod(record.Date) {
+				continue
+			}
+			totals.count++
+			totals.currencies[cycle.Currency()] = true
+			totals.addMonth(record.Date, record.Amount)
+			if record.Amount > 0 {
+				totals.income += record.Amount
+				continue
+			}
+			totals.expense += -record.Amount
+			totals.categories[record.CategoryOf()] += -record.Amount
+			totals.expenses = append(totals.expenses, record)
+		}
+	}
+	return totals
+}
+
+func (t *reportTotals) addMonth(date time.Time, amount model.Money) {
+	month := t.months[date.Month()]
+	if amount > 0 {
+		month[0] += amount
+	} else {
+		month[1] += -amount
+	}
+	t.months[date.Month()] = month
+}
+
+// 按金额从大到小排列分类
+func sortedCategories(totals map[string]model.Money) []string {
+	categories := make([]string, 0, len(totals))
+	for category := range totals {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if totals[categories[i]] != totals[categories[j]] {
+			return totals[categories[i]] > totals[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	return categories
+}
+
+// 统计周期的天数，当前周期只算到今天
+func reportDays(req *model.AccountingReportRequest) int {
+	end := req.End()
+	if now := time.Now(); now.Before(end) {
+		end = dayStart(now).AddDate(0, 0, 1)
+	}
+	return max(1, int(end.Sub(req.Start).Hours()/24+0.5))
+}
@@ -0,0 +1,169 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+const (
+	// 搜索条件的保存时间（秒），过期后无法翻页
+	searchExpireSeconds = 24 * 60 * 60
+	// 每页显示的记录数
+	searchPageSize = 10
+)
+
+// SearchRecords 在用户所有记账周期中搜索记录，发送第一页结果
+func (l *AccountingLogic) SearchRecords(chatID int64, userID int64, query *model.AccountingSearchQuery) error {
+	query.ID = newShortID()
+	query.ChatID = chatID
+	query.UserID = userID
+	query.CreatedAt = time.Now()
+
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("序列化搜索条件失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Setex(searchKey(query.ID), string(payload), searchExpireSeconds); err != nil {
+		return fmt.Errorf("保存搜索条件失败: %v", err)
+	}
+
+	text, keyboard, err := l.searchPage(query, 0)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	return l.sendView(chatID, userID, msg)
+}
+
+// SearchPage 翻页，在原消息上显示指定页的结果
+func (l *AccountingLogic) SearchPage(chatID int64, userID int64, messageID int, queryID string, page int) error {
+	data, err := l.svcCtx.Redis.Get(searchKey(queryID))
+	if err != nil {
+		return fmt.Errorf("获取搜索条件失败: %v", err)
+	}
+	if data == "" {
+		return fmt.Errorf("搜索已过期，请重新搜索")
+	}
+	var query model.AccountingSearchQuery
+	if err := json.Unmarshal([]byte(data), &query); err != nil {
+		return fmt.Errorf("解析搜索条件失败: %v", err)
+	}
+	if query.UserID != userID {
+		return fmt.Errorf("只能翻看自己的搜索结果")
+	}
+
+	// 旧的搜索条件没有记录聊天
+	if query.ChatID == 0 {
+		query.ChatID = chatID
+	}
+	text, keyboard, err := l.searchPage(&query, page)
+	if err != nil {
+		return err
+	}
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = keyboard
+	_, err = l.svcCtx.Bot.Send(edit)
+	return err
+}
+
+// 生成一页搜索结果和翻页按钮，只有一页时没有按钮
+func (l *AccountingLogic) searchPage(query *model.AccountingSearchQuery, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	cycles, err := l.getHistoryCycles(query.ChatID, query.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 按周期本位币分别汇总，金额已折算为各自周期的本位币
+	type match struct {
+		record *model.AccountingRecord
+		base   string
+	}
+	var matches []match
+	income := make(map[string]model.Money)
+	expense := make(map[string]model.Money)
+	var currencies []string
+	for _, cycle := range cycles {
+		base := cycle.Currency()
+		for _, record := range cycle.Records {
+			if !query.Match(record) {
+				continue
+			}
+			if _, ok := income[base]; !ok {
+				currencies = append(currencies, base)
+			}
+			matches = append(matches, match{record, base})
+			if record.Amount > 0 {
+				income[base] += record.Amount
+			} else {
+				expense[base] += -record.Amount
+			}
+		}
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("🔍 搜索: %s\n", query.Describe()))
+	if len(matches) == 0 {
+		msgText.WriteString("没有找到符合条件的记录")
+		return msgText.String(), nil, nil
+	}
+
+	// 最新的记录在前
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].record.Date.After(matches[j].record.Date)
+	})
+
+	pages := (len(matches) + searchPageSize - 1) / searchPageSize
+	page = max(0, min(page, pages-1))
+
+	msgText.WriteString(fmt.Sprintf("共 %d 条", len(matches)))
+	for _, currency := range currencies {
+		msgText.WriteString(fmt.Sprintf("，收入 %s，支出 %s",
+			formatMoney(income[currency], currency), formatMoney(expense[currency], currency)))
+	}
+	msgText.WriteString("\n\n")
+
+	start := page * searchPageSize
+	end := min(start+searchPageSize, len(matches))
+	for i, m := range matches[start:end] {
+		kind, sign := "支出", model.Money(-1)
+		if m.record.Amount > 0 {
+			kind, sign = "收入", 1
+		}
+		msgText.WriteString(fmt.Sprintf("%d. %s - %s %s - %s%s%s\n",
+			start+i+1,
+			m.record.Date.Format("2006-01-02 15:04"),
+			kind,
+			formatRecordAmount(m.record, m.base, sign),
+			m.record.Description,
+			formatReimbursementMark(m.record),
+			formatReceiptMark(m.record),
+		))
+	}
+	msgText.WriteString(fmt.Sprintf("\n第 %d/%d 页", page+1, pages))
+
+	if pages == 1 {
+		return msgText.String(), nil, nil
+	}
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("⬅️ 上一页", fmt.Sprintf("search_%s_%d", query.ID, page-1)))
+	}
+	if page < pages-1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("下一页 ➡️", fmt.Sprintf("search_%s_%d", query.ID, page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons)
+	return msgText.String(), &keyboard, nil
+}
+
+func searchKey(queryID string) string {
+	return fmt.Sprintf("accounting:search:%s", queryID)
+}
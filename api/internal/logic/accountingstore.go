@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"fmt"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 记账周期和记录通过 svcCtx.Ledger 读写，存储方式见 store 包，由配置 Ledger.Store 选择
+
+// 获取记账周期的基本信息，不加载记录
+func (l *AccountingLogic) getCycleMeta(cycleID string) (*model.AccountingCycle, error) {
+	return l.svcCtx.Ledger.GetCycle(l.ctx, cycleID)
+}
+
+// 获取记账周期，包括按日期排列的全部记录
+func (l *AccountingLogic) getAccountingCycle(cycleID string) (*model.AccountingCycle, error) {
+	cycle, err := l.getCycleMeta(cycleID)
+	if err != nil {
+		return nil, err
+	}
+	cycle.Records, err = l.svcCtx.Ledger.LoadRecords(l.ctx, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	return cycle, nil
+}
+
+// 保存记账周期的基本信息，记录通过 appendRecords 和 updateRecord 单独保存
+func (l *AccountingLogic) saveAccountingCycle(cycle *model.AccountingCycle) error {
+	return l.svcCtx.Ledger.SaveCycle(l.ctx, cycle)
+}
+
+// 追加记录并更新合计和钱包余额，只写入新记录，与周期已有的记录数无关
+// 没有指定钱包的记录使用周期所有者的默认钱包
+func (l *AccountingLogic) appendRecords(cycle *model.AccountingCycle, records ...*model.AccountingRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	defaultWallet := l.getDefaultWallet(cycle.ChatID, cycle.UserID)
+	for _, record := range records {
+		if record.Wallet == "" {
+			record.Wallet = defaultWallet
+		}
+	}
+	if err := l.svcCtx.Ledger.AppendRecords(l.ctx, cycle, records...); err != nil {
+		return err
+	}
+	l.applyWalletRecords(cycle, records)
+	cycle.Records = append(cycle.Records, records...)
+	return nil
+}
+
+// 保存金额不变的记录修改，例如报销状态
+func (l *AccountingLogic) updateRecord(cycle *model.AccountingCycle, record *model.AccountingRecord) error {
+	return l.svcCtx.Ledger.UpdateRecord(l.ctx, cycle, record)
+}
+
+// 读取维护好的合计，按原始币种的合计中本位币排在最前
+func (l *AccountingLogic) getTotals(cycle *model.AccountingCycle) (model.Money, model.Money, []*model.CurrencyTotal, error) {
+	totals, err := l.svcCtx.Ledger.GetTotals(l.ctx, cycle)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return totals.Income, totals.Expense, totals.ByCurrency, nil
+}
+
+// 添加记账周期到历史记录，按开始时间排序，重复添加不会产生重复项
+func (l *AccountingLogic) addToHistory(cycle *model.AccountingCycle) error {
+	return l.svcCtx.Ledger.AddToHistory(l.ctx, cycle)
+}
+
+// 获取用户历史记账周期ID列表，按开始顺序排列
+func (l *AccountingLogic) getHistoryCycleIDs(chatID int64, userID int64) ([]string, error) {
+	return l.svcCtx.Ledger.HistoryCycleIDs(l.ctx, chatID, userID)
+}
+
+// 获取当前活跃的记账周期ID，没有活跃周期时返回错误
+func (l *AccountingLogic) getActiveCycleID(chatID int64, userID int64) (string, error) {
+	cycleID, err := l.svcCtx.Ledger.ActiveCycleID(l.ctx, chatID, userID)
+	if err != nil {
+		return "", err
+	}
+	if cycleID == "" {
+		return "", fmt.Errorf("找不到活跃的记账周期，请先使用 /accounting_start 命令开始记账")
+	}
+	return cycleID, nil
+}
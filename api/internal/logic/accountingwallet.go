@@ -0,0 +1,257 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 钱包余额跨记账周期保存，每条记录按原始币种计入所属钱包，转账只在钱包之间移动余额，不计入收支
+
+// /wallets 显示的最近转账数
+const transferShowLimit = 5
+
+// ShowWallets 显示各钱包的余额和最近的转账
+func (l *AccountingLogic) ShowWallets(chatID int64, userID int64) error {
+	balances, err := l.getWalletBalances(chatID, userID)
+	if err != nil {
+		return err
+	}
+	defaultWallet := l.getDefaultWallet(chatID, userID)
+
+	var msgText strings.Builder
+	msgText.WriteString("👛 钱包余额:\n")
+	for _, wallet := range model.Wallets {
+		mark := ""
+		if wallet == defaultWallet {
+			mark = "（默认）"
+		}
+		amounts := balances[wallet]
+		if len(amounts) == 0 {
+			msgText.WriteString(fmt.Sprintf("• %s%s: %s\n", model.WalletName(wallet), mark, formatMoney(0, l.walletCurrency(chatID, userID, wallet))))
+			continue
+		}
+		currencies := make([]string, 0, len(amounts))
+		for currency := range amounts {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		parts := make([]string, 0, len(currencies))
+		for _, currency := range currencies {
+			parts = append(parts, formatMoney(amounts[currency], currency))
+		}
+		msgText.WriteString(fmt.Sprintf("• %s%s: %s\n", model.WalletName(wallet), mark, strings.Join(parts, "，")))
+	}
+
+	transfers, err := l.getTransfers(chatID, userID, transferShowLimit)
+	if err != nil {
+		return err
+	}
+	if len(transfers) > 0 {
+		msgText.WriteString("\n🔁 最近转账:\n")
+		for _, transfer := range transfers {
+			msgText.WriteString(fmt.Sprintf("%s %s\n", transfer.Time.Format("01-02 15:04"), describeTransfer(transfer)))
+		}
+	}
+	msgText.WriteString("\n记账时加上 #支付宝 等标记指定钱包，/transfer 在钱包之间转账")
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	return l.sendView(chatID, userID, msg)
+}
+
+// SetDefaultWallet 设置记账默认使用的钱包
+func (l *AccountingLogic) SetDefaultWallet(chatID int64, userID int64, wallet string) error {
+	if err := l.svcCtx.Redis.Set(defaultWalletKey(chatID, userID), wallet); err != nil {
+		return fmt.Errorf("设置默认钱包失败: %v", err)
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 默认钱包已设置为%s，没有标记钱包的记录都会记入%s", model.WalletName(wallet), model.WalletName(wallet)))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// SetWalletBalance 校准钱包余额，例如开始使用时录入银行卡的现有余额
+// currency 为空时使用钱包固定的币种或当前周期的本位币
+func (l *AccountingLogic) SetWalletBalance(chatID int64, userID int64, wallet string, amount model.Money, currency string) error {
+	if currency == "" {
+		currency = l.walletCurrency(chatID, userID, wallet)
+	}
+	field := walletField(wallet, currency)
+	before, _ := l.svcCtx.Redis.Hget(walletsKey(chatID, userID), field)
+	if err := l.svcCtx.Redis.Hset(walletsKey(chatID, userID), field, strconv.FormatInt(int64(amount), 10)); err != nil {
+		return fmt.Errorf("设置钱包余额失败: %v", err)
+	}
+
+	if cycle, err := l.getActiveCycle(chatID, userID); err == nil {
+		note := fmt.Sprintf("%s余额", model.WalletName(wallet))
+		if value, err := strconv.ParseInt(before, 10, 64); err == nil {
+			l.audit(cycle, userID, model.AuditEdit, "", formatMoney(model.Money(value), currency), formatMoney(amount, currency), note)
+		} else {
+			l.audit(cycle, userID, model.AuditEdit, "", "", formatMoney(amount, currency), note)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ %s余额已设置为 %s", model.WalletName(wallet), formatMoney(amount, currency)))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// Transfer 在钱包之间转账，转出和转入的金额分别计入两个钱包，不计入收支
+// ToAmount 为 0 时转入金额与转出金额相同；ToCurrency 为空时与转出币种相同
+func (l *AccountingLogic) Transfer(chatID int64, userID int64, transfer *model.WalletTransfer) error {
+	if transfer.From == transfer.To {
+		return fmt.Errorf("转出和转入的钱包不能相同")
+	}
+	if transfer.Amount <= 0 {
+		return fmt.Errorf("转账金额必须大于 0")
+	}
+	if transfer.Currency == "" {
+		transfer.Currency = l.walletCurrency(chatID, userID, transfer.From)
+	}
+	if transfer.ToCurrency == "" {
+		transfer.ToCurrency = model.WalletCurrency(transfer.To)
+		if transfer.ToCurrency == "" {
+			transfer.ToCurrency = transfer.Currency
+		}
+	}
+	if transfer.ToAmount == 0 {
+		if transfer.ToCurrency != transfer.Currency {
+			return fmt.Errorf("跨币种转账需要写明到账金额，例如 /transfer 3600 银行卡 usdt 500U")
+		}
+		transfer.ToAmount = transfer.Amount
+	}
+	transfer.ID = newShortID()
+	transfer.Time = time.Now()
+
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("序列化转账记录失败: %v", err)
+	}
+	key := walletsKey(chatID, userID)
+	if _, err := l.svcCtx.Redis.Hincrby(key, walletField(transfer.From, transfer.Currency), -int(transfer.Amount)); err != nil {
+		return fmt.Errorf("保存转账失败: %v", err)
+	}
+	if _, err := l.svcCtx.Redis.Hincrby(key, walletField(transfer.To, transfer.ToCurrency), int(transfer.ToAmount)); err != nil {
+		return fmt.Errorf("保存转账失败: %v", err)
+	}
+	if _, err := l.svcCtx.Redis.Rpush(transfersKey(chatID, userID), string(data)); err != nil {
+		log.Printf("保存转账记录失败: %v", err)
+	}
+	if cycle, err := l.getActiveCycle(chatID, userID); err == nil {
+		l.audit(cycle, userID, model.AuditTransfer, transfer.ID, "", describeTransfer(transfer), transfer.Note)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🔁 已转账: %s\n不计入收支，使用 /wallets 查看余额", describeTransfer(transfer)))
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 获取各钱包按币种的余额
+func (l *AccountingLogic) getWalletBalances(chatID int64, userID int64) (map[string]map[string]model.Money, error) {
+	fields, err := l.svcCtx.Redis.Hgetall(walletsKey(chatID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取钱包余额失败: %v", err)
+	}
+	balances := make(map[string]map[string]model.Money)
+	for field, text := range fields {
+		wallet, currency, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			continue
+		}
+		if balances[wallet] == nil {
+			balances[wallet] = make(map[string]model.Money)
+		}
+		balances[wallet][currency] = model.Money(value)
+	}
+	return balances, nil
+}
+
+// 获取最近的转账，最新的在前
+func (l *AccountingLogic) getTransfers(chatID int64, userID int64, limit int) ([]*model.WalletTransfer, error) {
+	values, err := l.svcCtx.Redis.Lrange(transfersKey(chatID, userID), -limit, -1)
+	if err != nil {
+		return nil, fmt.Errorf("获取转账记录失败: %v", err)
+	}
+	transfers := make([]*model.WalletTransfer, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		var transfer model.WalletTransfer
+		if err := json.Unmarshal([]byte(values[i]), &transfer); err != nil {
+			log.Printf("解析转账记录失败: %v", err)
+			continue
+		}
+		transfers = append(transfers, &transfer)
+	}
+	return transfers, nil
+}
+
+// 获取默认钱包，没有设置时为现金
+func (l *AccountingLogic) getDefaultWallet(chatID int64, userID int64) string {
+	wallet, err := l.svcCtx.Redis.Get(defaultWalletKey(chatID, userID))
+	if err != nil || wallet == "" {
+		return model.DefaultWallet
+	}
+	return wallet
+}
+
+// 钱包默认的币种：钱包固定的币种，否则为当前周期的本位币
+func (l *AccountingLogic) walletCurrency(chatID int64, userID int64, wallet string) string {
+	if currency := model.WalletCurrency(wallet); currency != "" {
+		return currency
+	}
+	if cycleID, err := l.svcCtx.Ledger.ActiveCycleID(l.ctx, chatID, userID); err == nil && cycleID != "" {
+		if cycle, err := l.getCycleMeta(cycleID); err == nil {
+			return cycle.Currency()
+		}
+	}
+	return model.DefaultCurrency
+}
+
+// 将新记录计入所属钱包的余额，没有指定钱包的记录使用默认钱包
+// 余额只用于 /wallets 显示，更新失败时只记录日志，不影响记账本身
+func (l *AccountingLogic) applyWalletRecords(cycle *model.AccountingCycle, records []*model.AccountingRecord) {
+	base := cycle.Currency()
+	key := walletsKey(cycle.ChatID, cycle.UserID)
+	for _, record := range records {
+		field := walletField(record.RecordWallet(), record.OriginalCurrency(base))
+		if _, err := l.svcCtx.Redis.Hincrby(key, field, int(record.OriginalValue(base))); err != nil {
+			log.Printf("更新钱包余额失败: %v", err)
+		}
+	}
+}
+
+// 转账的文字描述，例如 "银行卡 → 支付宝 500.00 元"
+func describeTransfer(transfer *model.WalletTransfer) string {
+	text := fmt.Sprintf("%s → %s %s", model.WalletName(transfer.From), model.WalletName(transfer.To),
+		formatMoney(transfer.Amount, transfer.Currency))
+	if transfer.ToCurrency != transfer.Currency || transfer.ToAmount != transfer.Amount {
+		text += fmt.Sprintf("（到账 %s）", formatMoney(transfer.ToAmount, transfer.ToCurrency))
+	}
+	return text
+}
+
+func walletField(wallet string, currency string) string {
+	return wallet + ":" + currency
+}
+
+// 钱包余额，field 为 <钱包>:<币种>
+func walletsKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:wallets:%d:%d", chatID, userID)
+}
+
+func defaultWalletKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:wallet:default:%d:%d", chatID, userID)
+}
+
+func transfersKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:transfers:%d:%d", chatID, userID)
+}
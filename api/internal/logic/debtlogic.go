@@ -0,0 +1,317 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/svc"
+)
+
+// 借款列表中最多带按钮的条目数
+const debtListLimit = 20
+
+// 借款按钮的操作
+const (
+	DebtActionConfirm  = "confirm"  // 确认借款
+	DebtActionReject   = "reject"   // 拒绝借款
+	DebtActionSettle   = "settle"   // 申请结清
+	DebtActionSettleOK = "settleok" // 确认结清
+	DebtActionSettleNo = "settleno" // 拒绝结清
+)
+
+// DebtLogic 记录两人之间的借款，每一笔借款和结清都需要另一方确认
+type DebtLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDebtLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DebtLogic {
+	return &DebtLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// AddDebt 记录一笔借款，lent 为 true 表示对方欠自己，否则自己欠对方
+// 借款在对方确认前不计入余额
+func (l *DebtLogic) AddDebt(chatID int64, actor model.DebtParty, counterparty model.DebtParty, lent bool, amount model.Money, currency string, description string) error {
+	if counterparty.Is(actor.UserID, actor.Username) {
+		return fmt.Errorf("不能和自己记账")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("金额必须大于 0")
+	}
+
+	now := time.Now()
+	debt := &model.Debt{
+		ID:          newShortID(),
+		ChatID:      chatID,
+		Debtor:      actor,
+		Creditor:    counterparty,
+		Amount:      amount,
+		Currency:    currency,
+		Description: description,
+		Status:      model.DebtPending,
+		CreatedBy:   actor.UserID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	text := fmt.Sprintf("🤝 %s 记录了欠 %s %s（%s）", actor.Display(), counterparty.Display(), formatMoney(amount, currency), description)
+	if lent {
+		debt.Debtor, debt.Creditor = counterparty, actor
+		text = fmt.Sprintf("🤝 %s 记录了借给 %s %s（%s）", actor.Display(), counterparty.Display(), formatMoney(amount, currency), description)
+	}
+	if err := l.saveDebt(debt); err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%s\n\n请 %s 确认，确认前不计入欠款", text, counterparty.Display()))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ 确认", debtCallback(DebtActionConfirm, debt.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ 不认", debtCallback(DebtActionReject, debt.ID)),
+	))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// HandleAction 处理借款按钮，只有需要确认的一方可以确认或拒绝
+func (l *DebtLogic) HandleAction(chatID int64, actor model.DebtParty, action string, debtID string) error {
+	debt, err := l.getDebt(chatID, debtID)
+	if err != nil {
+		return err
+	}
+	if !debt.Involves(actor.UserID, actor.Username) {
+		return fmt.Errorf("这笔账与你无关")
+	}
+	debt.Bind(actor.UserID, actor.Username, actor.Name)
+	other := debt.Counterparty(actor.UserID, actor.Username)
+
+	var text string
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	switch action {
+	case DebtActionConfirm, DebtActionReject:
+		if debt.Status != model.DebtPending {
+			return fmt.Errorf("这笔账%s", model.DebtStatusName(debt.Status))
+		}
+		if debt.CreatedBy == actor.UserID {
+			return fmt.Errorf("需要 %s 确认", other.Display())
+		}
+		debt.Status = model.DebtConfirmed
+		text = fmt.Sprintf("✅ %s 已确认: %s", actor.Display(), formatDebt(debt))
+		if action == DebtActionReject {
+			debt.Status = model.DebtRejected
+			text = fmt.Sprintf("❌ %s 不认这笔账: %s", actor.Display(), formatDebt(debt))
+		}
+
+	case DebtActionSettle:
+		if debt.Status != model.DebtConfirmed {
+			return fmt.Errorf("这笔账%s", model.DebtStatusName(debt.Status))
+		}
+		debt.SettleBy = actor.UserID
+		text = fmt.Sprintf("💸 %s 申请结清: %s\n\n请 %s 确认", actor.Display(), formatDebt(debt), other.Display())
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 已结清", debtCallback(DebtActionSettleOK, debt.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 还没有", debtCallback(DebtActionSettleNo, debt.ID)),
+		))
+		keyboard = &markup
+
+	case DebtActionSettleOK, DebtActionSettleNo:
+		if debt.Status != model.DebtConfirmed || debt.SettleBy == 0 {
+			return fmt.Errorf("没有待确认的结清申请")
+		}
+		if debt.SettleBy == actor.UserID {
+			return fmt.Errorf("需要 %s 确认", other.Display())
+		}
+		if action == DebtActionSettleOK {
+			debt.Status = model.DebtSettled
+			text = fmt.Sprintf("🎉 %s 确认已结清: %s", actor.Display(), formatDebt(debt))
+		} else {
+			text = fmt.Sprintf("❌ %s 表示还没有结清: %s", actor.Display(), formatDebt(debt))
+		}
+		debt.SettleBy = 0
+
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+
+	debt.UpdatedAt = time.Now()
+	if err := l.saveDebt(debt); err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListDebts 按人汇总未结清的借款，并列出待自己确认的借款
+func (l *DebtLogic) ListDebts(chatID int64, actor model.DebtParty) error {
+	debts, err := l.getDebts(chatID)
+	if err != nil {
+		return err
+	}
+
+	// 正数表示对方欠自己
+	type balance struct {
+		party  *model.DebtParty
+		totals map[string]model.Money
+	}
+	balances := make(map[string]*balance)
+	var keys []string
+	var open, waiting []*model.Debt
+	for _, debt := range debts {
+		if !debt.Involves(actor.UserID, actor.Username) {
+			continue
+		}
+		switch debt.Status {
+		case model.DebtConfirmed:
+			other := debt.Counterparty(actor.UserID, actor.Username)
+			b, ok := balances[other.Key()]
+			if !ok {
+				b = &balance{party: other, totals: make(map[string]model.Money)}
+				balances[other.Key()] = b
+				keys = append(keys, other.Key())
+			}
+			if debt.Creditor.Is(actor.UserID, actor.Username) {
+				b.totals[debt.Currency] += debt.Amount
+			} else {
+				b.totals[debt.Currency] -= debt.Amount
+			}
+			open = append(open, debt)
+		case model.DebtPending:
+			if debt.CreatedBy != actor.UserID {
+				waiting = append(waiting, debt)
+			}
+		}
+	}
+
+	if len(open) == 0 && len(waiting) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "🤝 没有未结清的借款\n\n使用 /owe @对方 50 奶茶 或 /lent @对方 200 记录")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	var msgText strings.Builder
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if len(open) > 0 {
+		msgText.WriteString(fmt.Sprintf("🤝 %s 的借款:\n", actor.Display()))
+		for _, key := range keys {
+			b := balances[key]
+			for currency, total := range b.totals {
+				switch {
+				case total > 0:
+					msgText.WriteString(fmt.Sprintf("%s 欠你 %s\n", b.party.Display(), formatMoney(total, currency)))
+				case total < 0:
+					msgText.WriteString(fmt.Sprintf("你欠 %s %s\n", b.party.Display(), formatMoney(-total, currency)))
+				default:
+					msgText.WriteString(fmt.Sprintf("与 %s 两清\n", b.party.Display()))
+				}
+			}
+		}
+
+		msgText.WriteString("\n📝 未结清明细:\n")
+		for i, debt := range open {
+			if i >= debtListLimit {
+				msgText.WriteString(fmt.Sprintf("... 其余 %d 笔未显示\n", len(open)-debtListLimit))
+				break
+			}
+			mark := ""
+			if debt.SettleBy != 0 {
+				mark = "（结清待确认）"
+			}
+			msgText.WriteString(fmt.Sprintf("%d. %s%s\n", i+1, formatDebt(debt), mark))
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("💸 %d 已结清", i+1), debtCallback(DebtActionSettle, debt.ID)),
+			))
+		}
+	}
+
+	if len(waiting) > 0 {
+		msgText.WriteString("\n⏳ 等待你确认:\n")
+		for i, debt := range waiting {
+			if i >= debtListLimit {
+				break
+			}
+			msgText.WriteString(fmt.Sprintf("• %s\n", formatDebt(debt)))
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ 确认 "+debt.Description, debtCallback(DebtActionConfirm, debt.ID)),
+				tgbotapi.NewInlineKeyboardButtonData("❌ 不认", debtCallback(DebtActionReject, debt.ID)),
+			))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	if len(keyboard) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	}
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 按创建时间获取群内所有借款
+func (l *DebtLogic) getDebts(chatID int64) ([]*model.Debt, error) {
+	data, err := l.svcCtx.Redis.Hgetall(debtKey(chatID))
+	if err != nil {
+		return nil, fmt.Errorf("获取借款记录失败: %v", err)
+	}
+
+	debts := make([]*model.Debt, 0, len(data))
+	for _, value := range data {
+		var debt model.Debt
+		if err := json.Unmarshal([]byte(value), &debt); err != nil {
+			log.Printf("解析借款记录失败: %v", err)
+			continue
+		}
+		debts = append(debts, &debt)
+	}
+	sort.Slice(debts, func(i, j int) bool {
+		return debts[i].CreatedAt.Before(debts[j].CreatedAt)
+	})
+	return debts, nil
+}
+
+func (l *DebtLogic) getDebt(chatID int64, debtID string) (*model.Debt, error) {
+	data, err := l.svcCtx.Redis.Hget(debtKey(chatID), debtID)
+	if err != nil || data == "" {
+		return nil, fmt.Errorf("找不到这笔借款")
+	}
+	var debt model.Debt
+	if err := json.Unmarshal([]byte(data), &debt); err != nil {
+		return nil, fmt.Errorf("解析借款记录失败: %v", err)
+	}
+	return &debt, nil
+}
+
+func (l *DebtLogic) saveDebt(debt *model.Debt) error {
+	data, err := json.Marshal(debt)
+	if err != nil {
+		return fmt.Errorf("序列化借款记录失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Hset(debtKey(debt.ChatID), debt.ID, string(data)); err != nil {
+		return fmt.Errorf("保存借款记录失败: %v", err)
+	}
+	return nil
+}
+
+func debtKey(chatID int64) string {
+	return fmt.Sprintf("accounting:debt:%d", chatID)
+}
+
+func debtCallback(action string, debtID string) string {
+	return fmt.Sprintf("debt_%s_%s", action, debtID)
+}
+
+// 例如 "@alice 欠 @bob 50.00 元（奶茶）"
+func formatDebt(debt *model.Debt) string {
+	return fmt.Sprintf("%s 欠 %s %s（%s）",
+		debt.Debtor.Display(), debt.Creditor.Display(), formatMoney(debt.Amount, debt.Currency), debt.Description)
+}
@@ -0,0 +1,355 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/svc"
+)
+
+const (
+	// 所有订阅了账单摘要的 chatID:userID
+	digestOwnersKey = "accounting:digest:owners"
+	// 摘要中最多列出的记录数
+	digestRecordLimit = 30
+)
+
+// DigestLogic 管理每日、每周账单摘要的订阅和发送
+type DigestLogic struct {
+	ctx        context.Context
+	svcCtx     *svc.ServiceContext
+	accounting *AccountingLogic
+}
+
+func NewDigestLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DigestLogic {
+	return &DigestLogic{
+		ctx:        ctx,
+		svcCtx:     svcCtx,
+		accounting: NewAccountingLogic(ctx, svcCtx),
+	}
+}
+
+// Subscribe 订阅或更新摘要，同一频率只保留一个设置
+func (l *DigestLogic) Subscribe(chatID int64, userID int64, setting *model.DigestSetting) error {
+	now := time.Now()
+	setting.ChatID = chatID
+	setting.UserID = userID
+	// 今天的发送时间已过时从下一次开始
+	setting.LastSent = now
+	setting.CreatedAt = now
+	if setting.Frequency == model.FrequencyWeekly && setting.Weekday == 0 {
+		setting.Weekday = 7
+	}
+
+	if setting.Target == model.DigestTargetDM && chatID != userID {
+		// 用户没有私聊过机器人时无法发送私信，提前告知
+		msg := tgbotapi.NewMessage(userID, fmt.Sprintf("📬 %s将通过私聊发送: %s", setting.Name(), setting.Schedule()))
+		if _, err := l.svcCtx.Bot.Send(msg); err != nil {
+			return fmt.Errorf("无法私聊发送摘要，请先私聊机器人发送 /start 后重试: %v", err)
+		}
+	}
+
+	if err := l.saveSetting(setting); err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Sadd(digestOwnersKey, ownerMember(chatID, userID)); err != nil {
+		return fmt.Errorf("保存摘要设置失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📬 已开启%s: %s，发送到%s\n使用 /digest off 关闭",
+		setting.Name(), setting.Schedule(), digestTargetName(setting.Target)))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// Unsubscribe 关闭摘要，frequency 为空时关闭全部
+func (l *DigestLogic) Unsubscribe(chatID int64, userID int64, frequency string) error {
+	fields := []string{model.FrequencyDaily, model.FrequencyWeekly}
+	if frequency != "" {
+		fields = []string{frequency}
+	}
+	if _, err := l.svcCtx.Redis.Hdel(digestKey(chatID, userID), fields...); err != nil {
+		return fmt.Errorf("关闭摘要失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "📭 已关闭账单摘要")
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListSettings 展示当前的摘要设置
+func (l *DigestLogic) ListSettings(chatID int64, userID int64) error {
+	settings, err := l.getSettings(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	text := "还没有开启账单摘要，使用 /digest daily 21:00 开启每日摘要"
+	if len(settings) > 0 {
+		var msgText strings.Builder
+		msgText.WriteString("📬 账单摘要:\n")
+		for _, setting := range settings {
+			msgText.WriteString(fmt.Sprintf("• %s: %s，发送到%s\n",
+				setting.Name(), setting.Schedule(), digestTargetName(setting.Target)))
+		}
+		text = msgText.String()
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// SendNow 立即发送一份摘要，按隐私设置发送到当前聊天或私聊，不影响定时发送
+func (l *DigestLogic) SendNow(chatID int64, userID int64, frequency string) error {
+	setting := &model.DigestSetting{ChatID: chatID, UserID: userID, Frequency: frequency}
+	text, err := l.buildDigest(setting, time.Now())
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	return l.accounting.sendView(chatID, userID, msg)
+}
+
+// Register 在调度器中注册摘要发送，每次触发时检查到期的订阅
+func (l *DigestLogic) Register(s *Scheduler) {
+	s.Add("digest", EveryTick(), l.runDue)
+}
+
+// 发送所有到期的摘要
+func (l *DigestLogic) runDue(now time.Time) {
+	owners, err := l.svcCtx.Redis.Smembers(digestOwnersKey)
+	if err != nil {
+		log.Printf("获取摘要订阅用户失败: %v", err)
+		return
+	}
+
+	for _, owner := range owners {
+		chatID, userID, ok := parseOwnerMember(owner)
+		if !ok {
+			continue
+		}
+		settings, err := l.getSettings(chatID, userID)
+		if err != nil {
+			log.Printf("获取摘要设置失败: %v", err)
+			continue
+		}
+		if len(settings) == 0 {
+			_, _ = l.svcCtx.Redis.Srem(digestOwnersKey, owner)
+			continue
+		}
+		for _, setting := range settings {
+			if !setting.Due(now) {
+				continue
+			}
+			if err := l.send(setting, now); err != nil {
+				log.Printf("发送%s失败 %s: %v", setting.Name(), owner, err)
+			}
+		}
+	}
+}
+
+// 发送一份定时摘要并记录发送时间
+// 先保存发送时间再发送，发送失败时不会每分钟重试
+func (l *DigestLogic) send(setting *model.DigestSetting, now time.Time) error {
+	setting.LastSent = now
+	if err := l.saveSetting(setting); err != nil {
+		return err
+	}
+
+	text, err := l.buildDigest(setting, now)
+	if err != nil {
+		return err
+	}
+	target := setting.ChatID
+	if setting.Target == model.DigestTargetDM {
+		target = setting.UserID
+	}
+	msg := tgbotapi.NewMessage(target, text)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 生成摘要：本期记录、本期收支、与上一期的对比以及当前周期的整体情况
+func (l *DigestLogic) buildDigest(setting *model.DigestSetting, now time.Time) (string, error) {
+	cycles, err := l.accounting.getHistoryCycles(setting.ChatID, setting.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	var active *model.AccountingCycle
+	for _, cycle := range cycles {
+		if cycle.IsActive {
+			active = cycle
+		}
+	}
+	base := model.DefaultCurrency
+	if active != nil {
+		base = active.Currency()
+	}
+
+	start, end := setting.Period(now)
+	previousStart := start.AddDate(0, 0, -1)
+	previousName := "昨天"
+	title := start.Format("2006-01-02")
+	if setting.Frequency == model.FrequencyWeekly {
+		previousStart = start.AddDate(0, 0, -7)
+		previousName = "上周"
+		title = fmt.Sprintf("%s 至 %s", start.Format("01-02"), end.AddDate(0, 0, -1).Format("01-02"))
+	}
+
+	// 不同本位币的周期无法直接相加，只统计与当前周期本位币相同的记录
+	var records []*model.AccountingRecord
+	var income, expense, previousIncome, previousExpense model.Money
+	for _, cycle := range cycles {
+		if cycle.Currency() != base {
+			continue
+		}
+		for _, record := range cycle.Records {
+			switch {
+			case !record.Date.Before(start) && record.Date.Before(end):
+				records = append(records, record)
+				if record.Amount > 0 {
+					income += record.Amount
+				} else {
+					expense += -record.Amount
+				}
+			case !record.Date.Before(previousStart) && record.Date.Before(start):
+				if record.Amount > 0 {
+					previousIncome += record.Amount
+				} else {
+					previousExpense += -record.Amount
+				}
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+
+	var msgText strings.Builder
+	msgText.WriteString(fmt.Sprintf("📬 %s %s\n\n", setting.Name(), title))
+
+	if len(records) == 0 {
+		msgText.WriteString("本期没有记账记录\n")
+	} else {
+		msgText.WriteString(fmt.Sprintf("📝 本期记录（%d 笔）:\n", len(records)))
+		for i, record := range records {
+			if i == digestRecordLimit {
+				msgText.WriteString(fmt.Sprintf("… 还有 %d 笔\n", len(records)-digestRecordLimit))
+				break
+			}
+			kind, sign := "支出", model.Money(-1)
+			if record.Amount > 0 {
+				kind, sign = "收入", 1
+			}
+			msgText.WriteString(fmt.Sprintf("%s - %s %s - %s\n",
+				record.Date.Format("01-02 15:04"), kind, formatRecordAmount(record, base, sign), record.Description))
+		}
+	}
+
+	msgText.WriteString(fmt.Sprintf("\n💰 本期收入: %s\n💸 本期支出: %s\n📈 支出较%s: %s\n",
+		formatMoney(income, base),
+		formatMoney(expense, base),
+		previousName,
+		formatChange(expense, previousExpense, base)))
+	if income > 0 || previousIncome > 0 {
+		msgText.WriteString(fmt.Sprintf("📈 收入较%s: %s\n", previousName, formatChange(income, previousIncome, base)))
+	}
+
+	if active == nil {
+		msgText.WriteString("\n当前没有活跃的记账周期")
+		return msgText.String(), nil
+	}
+	summary := l.accounting.calculateSummary(active)
+	msgText.WriteString(fmt.Sprintf("\n📊 当前周期（%s 至 %s）:\n"+
+		"💰 总收入: %s\n"+
+		"💸 总支出: %s（占收入 %s）\n"+
+		"💵 剩余金额: %s\n"+
+		"⏰ 剩余天数: %d 天",
+		active.StartTime.Format("01-02"),
+		active.EndTime.Format("01-02"),
+		formatMoney(summary.TotalIncome, base),
+		formatMoney(summary.TotalExpense, base),
+		formatPercent(summary.TotalExpense, summary.TotalIncome),
+		formatMoney(summary.Balance, base),
+		summary.DaysRemaining))
+	return msgText.String(), nil
+}
+
+// 按发送频率获取用户的摘要设置，每日在前
+func (l *DigestLogic) getSettings(chatID int64, userID int64) ([]*model.DigestSetting, error) {
+	data, err := l.svcCtx.Redis.Hgetall(digestKey(chatID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取摘要设置失败: %v", err)
+	}
+
+	settings := make([]*model.DigestSetting, 0, len(data))
+	for _, frequency := range []string{model.FrequencyDaily, model.FrequencyWeekly} {
+		value, ok := data[frequency]
+		if !ok {
+			continue
+		}
+		var setting model.DigestSetting
+		if err := json.Unmarshal([]byte(value), &setting); err != nil {
+			log.Printf("解析摘要设置失败: %v", err)
+			continue
+		}
+		settings = append(settings, &setting)
+	}
+	return settings, nil
+}
+
+func (l *DigestLogic) saveSetting(setting *model.DigestSetting) error {
+	data, err := json.Marshal(setting)
+	if err != nil {
+		return fmt.Errorf("序列化摘要设置失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Hset(digestKey(setting.ChatID, setting.UserID), setting.Frequency, string(data)); err != nil {
+		return fmt.Errorf("保存摘要设置失败: %v", err)
+	}
+	return nil
+}
+
+func digestKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:digest:%d:%d", chatID, userID)
+}
+
+func digestTargetName(target string) string {
+	if target == model.DigestTargetDM {
+		return "私聊"
+	}
+	return "本群"
+}
+
+// 本期相对上期的变化，例如 "+12.00 元（+15%）"
+func formatChange(current model.Money, previous model.Money, currency string) string {
+	diff := current - previous
+	switch {
+	case previous == 0 && current == 0:
+		return "持平"
+	case previous == 0:
+		return fmt.Sprintf("+%s（上期为 0）", formatMoney(diff, currency))
+	case diff == 0:
+		return "持平"
+	}
+	sign := ""
+	if diff > 0 {
+		sign = "+"
+	}
+	return fmt.Sprintf("%s%s（%s%.0f%%）", sign, formatMoney(diff, currency), sign, float64(diff)/float64(previous)*100)
+}
+
+// part 占 total 的百分比，total 为 0 时返回 "-"
+func formatPercent(part model.Money, total model.Money) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(part)/float64(total)*100)
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +12,7 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/google/uuid"
 	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/parser"
 	"github.com/qx/syft_robot/api/internal/svc"
 )
 
@@ -415,7 +414,9 @@ func (l *DinnerLogic) cleanInvalidGroupIDs() {
 	log.Printf("已清理 %d 个无效群组", len(invalidGroups))
 }
 
-func (l *DinnerLogic) StartReminder(testMode bool) {
+// RegisterReminder 在调度器中注册喝水提醒
+// 正常模式在 9-12 点和 14-18 点的整点发送，测试模式每次触发都发送
+func (l *DinnerLogic) RegisterReminder(s *Scheduler, testMode bool) {
 	// 加载已保存的群组ID
 	l.LoadGroupIDs()
 	
@@ -428,116 +429,60 @@ func (l *DinnerLogic) StartReminder(testMode bool) {
 	groupMu.RUnlock()
 	log.Printf("已加载 %d 个群组", groupCount)
 	
-	ticker := time.NewTicker(time.Minute)
 	if testMode {
-		// 测试模式下使用10秒间隔
-		ticker = time.NewTicker(10 * time.Second)
-		log.Printf("测试模式已启动，每10秒发送一次提醒")
+		log.Printf("测试模式已启动，每次调度都发送提醒")
+		s.Add("reminder", EveryTick(), func(now time.Time) {
+			l.sendReminder("⏰ 测试模式提醒\n\n")
+		})
+		return
 	}
 	
-	go func() {
-		for range ticker.C {
-			now := time.Now()
-			hour := now.Hour()
-			minute := now.Minute()
-
-			// 测试模式下不检查时间，直接发送提醒
-			if testMode {
-				// 向所有群组发送提醒
-				groupMu.RLock()
-				groupCount := len(groupIDs)
-				log.Printf("测试模式：准备向 %d 个群组发送提醒", groupCount)
-				
-				invalidGroups := make([]int64, 0)
-				for chatID := range groupIDs {
-					log.Printf("测试模式：正在向群组 %d 发送提醒", chatID)
-					msg := tgbotapi.NewMessage(chatID,
-						fmt.Sprintf("⏰ 测试模式提醒\n\n"+
-							"深夜饭堂提醒大家：\n"+
-							"该喝水了 💧\n"+
-							"该摸鱼了 🐟\n"+
-							"该抽烟了 🚬\n\n"+
-							"工作是人家的，命是自己的！\n"+
-							"每天8杯水，bug减一半——亲测无效，但至少能续命！"))
-					_, err := l.svcCtx.Bot.Send(msg)
-					if err != nil {
-						log.Printf("测试模式：向群组 %d 发送提醒失败: %v", chatID, err)
-						invalidGroups = append(invalidGroups, chatID)
-					} else {
-						log.Printf("测试模式：成功向群组 %d 发送提醒", chatID)
-					}
-				}
-				groupMu.RUnlock()
-				
-				// 如果有无效群组，移除它们
-				if len(invalidGroups) > 0 {
-					groupMu.Lock()
-					for _, chatID := range invalidGroups {
-						delete(groupIDs, chatID)
-						log.Printf("已移除无效群组 %d", chatID)
-					}
-					groupMu.Unlock()
-					
-					// 保存更新后的群组列表到Redis
-					key := "bot:groups"
-					data, err := json.Marshal(groupIDs)
-					if err == nil {
-						l.svcCtx.Redis.Set(key, string(data))
-						log.Printf("已更新Redis中的群组列表: %v", groupIDs)
-					}
-				}
-				
-				continue
-			}
-
-			// 正常模式下检查是否在指定时间段内（9-12点和14-18点）
-			if ((hour >= 9 && hour < 12) || (hour >= 14 && hour < 18)) && minute == 0 {
-				// 向所有群组发送提醒
-				groupMu.RLock()
-				groupCount := len(groupIDs)
-				log.Printf("正常模式：准备向 %d 个群组发送提醒", groupCount)
-				
-				invalidGroups := make([]int64, 0)
-				for chatID := range groupIDs {
-					log.Printf("正常模式：正在向群组 %d 发送提醒", chatID)
-					msg := tgbotapi.NewMessage(chatID,
-						fmt.Sprintf("⏰ 当前时间为%d时%d分\n\n"+
-							"深夜饭堂提醒大家：\n"+
-							"该喝水了 💧\n"+
-							"该摸鱼了 🐟\n"+
-							"该抽烟了 🚬\n\n"+
-							"工作是人家的，命是自己的！\n"+
-							"每天8杯水，bug减一半——亲测无效，但至少能续命！", hour, minute))
-					_, err := l.svcCtx.Bot.Send(msg)
-					if err != nil {
-						log.Printf("正常模式：向群组 %d 发送提醒失败: %v", chatID, err)
-						invalidGroups = append(invalidGroups, chatID)
-					} else {
-						log.Printf("正常模式：成功向群组 %d 发送提醒", chatID)
-					}
-				}
-				groupMu.RUnlock()
-				
-				// 如果有无效群组，移除它们
-				if len(invalidGroups) > 0 {
-					groupMu.Lock()
-					for _, chatID := range invalidGroups {
-						delete(groupIDs, chatID)
-						log.Printf("已移除无效群组 %d", chatID)
-					}
-					groupMu.Unlock()
-					
-					// 保存更新后的群组列表到Redis
-					key := "bot:groups"
-					data, err := json.Marshal(groupIDs)
-					if err == nil {
-						l.svcCtx.Redis.Set(key, string(data))
-						log.Printf("已更新Redis中的群组列表: %v", groupIDs)
-					}
-				}
-			}
+	s.Add("reminder", AtMinute(0, 9, 10, 11, 14, 15, 16, 17), func(now time.Time) {
+		l.sendReminder(fmt.Sprintf("⏰ 当前时间为%d时%d分\n\n", now.Hour(), now.Minute()))
+	})
+}
+
+// 向所有群组发送提醒，发送失败的群组会被移除
+func (l *DinnerLogic) sendReminder(header string) {
+	groupMu.RLock()
+	log.Printf("准备向 %d 个群组发送提醒", len(groupIDs))
+	
+	invalidGroups := make([]int64, 0)
+	for chatID := range groupIDs {
+		msg := tgbotapi.NewMessage(chatID, header+
+			"深夜饭堂提醒大家：\n"+
+			"该喝水了 💧\n"+
+			"该摸鱼了 🐟\n"+
+			"该抽烟了 🚬\n\n"+
+			"工作是人家的，命是自己的！\n"+
+			"每天8杯水，bug减一半——亲测无效，但至少能续命！")
+		_, err := l.svcCtx.Bot.Send(msg)
+		if err != nil {
+			log.Printf("向群组 %d 发送提醒失败: %v", chatID, err)
+			invalidGroups = append(invalidGroups, chatID)
+		} else {
+			log.Printf("成功向群组 %d 发送提醒", chatID)
 		}
-	}()
+	}
+	groupMu.RUnlock()
+	
+	// 如果有无效群组，移除它们
+	if len(invalidGroups) == 0 {
+		return
+	}
+	groupMu.Lock()
+	defer groupMu.Unlock()
+	for _, chatID := range invalidGroups {
+		delete(groupIDs, chatID)
+		log.Printf("已移除无效群组 %d", chatID)
+	}
+	
+	// 保存更新后的群组列表到Redis
+	data, err := json.Marshal(groupIDs)
+	if err == nil {
+		l.svcCtx.Redis.Set("bot:groups", string(data))
+		log.Printf("已更新Redis中的群组列表: %v", groupIDs)
+	}
 }
 
 func (l *DinnerLogic) QuitDinner(chatID int64, userID int64, firstName string) error {
@@ -582,76 +527,18 @@ func (l *DinnerLogic) QuitDinner(chatID int64, userID int64, firstName string) e
 	return l.sendMenu(chatID, userID)
 }
 
-func parseExpenseAmountAndDescription(text string) (float64, string, error) {
-	// 移除所有空格
-	text = strings.ReplaceAll(text, " ", "")
-	
-	// 尝试匹配带括号的格式
-	re := regexp.MustCompile(`^(.+?)([+-]?\d+(?:\.\d+)?)(?:\((.+?)\))?$`)
-	matches := re.FindStringSubmatch(text)
-	if len(matches) >= 3 {
-		description := matches[1]
-		amountStr := matches[2]
-		note := ""
-		if len(matches) > 3 && matches[3] != "" {
-			note = "(" + matches[3] + ")"
-		}
-		
-		// 如果金额字符串包含负号，直接按负数处理
-		if strings.Contains(amountStr, "-") {
-			amountStr = strings.Replace(amountStr, "-", "", 1)
-			amount, err := strconv.ParseFloat(amountStr, 64)
-			if err == nil {
-				return -amount, description + note, nil
-			}
-		} else if strings.Contains(amountStr, "+") {
-			// 如果包含加号，按正数处理
-			amountStr = strings.Replace(amountStr, "+", "", 1)
-			amount, err := strconv.ParseFloat(amountStr, 64)
-			if err == nil {
-				return amount, description + note, nil
-			}
-		} else {
-			// 如果没有符号，默认为支出（负数）
-			amount, err := strconv.ParseFloat(amountStr, 64)
-			if err == nil {
-				return -amount, description + note, nil
-			}
-		}
-	}
-	
-	// 如果没有匹配到带括号的格式，尝试其他格式
-	parts := strings.Split(text, "-")
-	if len(parts) == 2 {
-		description := parts[0]
-		amount, err := strconv.ParseFloat(parts[1], 64)
-		if err == nil {
-			return -amount, description, nil
-		}
-	}
-	
-	// 尝试匹配标准格式：金额 描述
-	parts = strings.Split(text, " ")
-	if len(parts) == 2 {
-		amount, err := strconv.ParseFloat(parts[0], 64)
-		if err == nil {
-			// 如果没有负号，默认为支出（负数）
-			return -amount, parts[1], nil
-		}
-	}
-	
-	return 0, "", fmt.Errorf("无法解析金额和描述，请使用以下格式之一：\n1. 100 午餐（默认为支出）\n2. 午餐-100\n3. -100 午餐\n4. 午餐 -100\n5. 买菜-10(未报销)\n6. 工资+5000（收入需要加+号）")
-}
-
 // HandleExpenseReply 处理支出回复
 func (l *DinnerLogic) HandleExpenseReply(chatID int64, userID int64, text string) error {
-	amount, description, err := parseExpenseAmountAndDescription(text)
+	// 解析金额、描述和日期，例如 "昨天 打车-30"、"午餐 -12 USD"
+	entry, err := parser.ParseEntry(text, time.Now())
 	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("%v\n\n%s", err, parser.Usage))
+		_, err := l.svcCtx.Bot.Send(msg)
 		return err
 	}
 
 	// 添加记录
-	if err := l.accountingLogic.AddExpense(chatID, userID, amount, description); err != nil {
+	if err := l.accountingLogic.AddExpense(chatID, userID, entry.Request()); err != nil {
 		return err
 	}
 
@@ -0,0 +1,142 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/svc"
+)
+
+// ExchangeRateLogic 维护用户自己的离线汇率表
+// 每个币种一个 Redis Hash，字段为生效日期(2006-01-02)，值为 1 单位外币折合的人民币
+type ExchangeRateLogic struct {
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewExchangeRateLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ExchangeRateLogic {
+	return &ExchangeRateLogic{
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SetRate 设置某个币种在指定日期的汇率
+func (l *ExchangeRateLogic) SetRate(chatID int64, userID int64, currency string, rate model.Rate, date time.Time) error {
+	if currency == model.DefaultCurrency {
+		msg := tgbotapi.NewMessage(chatID, "人民币是汇率基准，无需设置")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	rateKey := fmt.Sprintf("accounting:fx:%d:%s", userID, currency)
+	if err := l.svcCtx.Redis.Hset(rateKey, date.Format("2006-01-02"), rate.String()); err != nil {
+		return fmt.Errorf("保存汇率失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 已设置汇率: 1 %s = %s CNY（自 %s 起生效）",
+		currency, rate, date.Format("2006-01-02")))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListRates 显示用户各币种的最新汇率
+func (l *ExchangeRateLogic) ListRates(chatID int64, userID int64) error {
+	var msgText strings.Builder
+	msgText.WriteString("💱 当前汇率（1 单位折合人民币）:\n")
+
+	found := false
+	for _, currency := range model.SupportedCurrencies {
+		if currency == model.DefaultCurrency {
+			continue
+		}
+		rates, err := l.getRates(userID, currency)
+		if err != nil {
+			return err
+		}
+		if len(rates) == 0 {
+			continue
+		}
+		latest := rates[len(rates)-1]
+		msgText.WriteString(fmt.Sprintf("%s: %s（%s 设置，共 %d 条历史）\n",
+			currency, latest.Rate, latest.Date.Format("2006-01-02"), len(rates)))
+		found = true
+	}
+	if !found {
+		msgText.WriteString("暂未设置任何汇率，使用 /fx_set USD 7.2 设置")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// RateOn 获取币种在指定日期生效的汇率
+// 取该日期及之前最近的一条；若都在该日期之后，则取最早的一条
+func (l *ExchangeRateLogic) RateOn(userID int64, currency string, date time.Time) (model.Rate, error) {
+	if currency == model.DefaultCurrency {
+		return model.BaseRate, nil
+	}
+
+	rates, err := l.getRates(userID, currency)
+	if err != nil {
+		return 0, err
+	}
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("未设置 %s 汇率，请先使用 /fx_set %s <汇率> 设置", currency, currency)
+	}
+
+	day := date.Format("2006-01-02")
+	rate := rates[0].Rate
+	for _, r := range rates {
+		if r.Date.Format("2006-01-02") > day {
+			break
+		}
+		rate = r.Rate
+	}
+	return rate, nil
+}
+
+// Convert 将金额从一种币种折算为另一种币种
+func (l *ExchangeRateLogic) Convert(userID int64, amount model.Money, from string, to string, date time.Time) (model.Money, model.Rate, error) {
+	fromRate, err := l.RateOn(userID, from, date)
+	if err != nil {
+		return 0, 0, err
+	}
+	toRate, err := l.RateOn(userID, to, date)
+	if err != nil {
+		return 0, 0, err
+	}
+	return model.ConvertMoney(amount, fromRate, toRate), fromRate, nil
+}
+
+// 获取某币种的全部历史汇率，按日期升序
+func (l *ExchangeRateLogic) getRates(userID int64, currency string) ([]*model.ExchangeRate, error) {
+	rateKey := fmt.Sprintf("accounting:fx:%d:%s", userID, currency)
+	data, err := l.svcCtx.Redis.Hgetall(rateKey)
+	if err != nil {
+		return nil, fmt.Errorf("获取汇率失败: %v", err)
+	}
+
+	rates := make([]*model.ExchangeRate, 0, len(data))
+	for day, value := range data {
+		date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+		if err != nil {
+			continue
+		}
+		rate, err := model.ParseRate(value)
+		if err != nil {
+			continue
+		}
+		rates = append(rates, &model.ExchangeRate{Currency: currency, Rate: rate, Date: date})
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
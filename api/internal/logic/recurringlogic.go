@@ -0,0 +1,320 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/qx/syft_robot/api/internal/svc"
+)
+
+const (
+	// 所有设置了周期性记账的 chatID:userID
+	recurringOwnersKey = "accounting:recurring:owners"
+	// 机器人停机后补记的最大次数，避免一次补记过多
+	recurringCatchUpLimit = 31
+)
+
+// RecurringLogic 管理周期性记账规则，并在到期时自动记入活跃周期
+type RecurringLogic struct {
+	ctx        context.Context
+	svcCtx     *svc.ServiceContext
+	accounting *AccountingLogic
+}
+
+func NewRecurringLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RecurringLogic {
+	return &RecurringLogic{
+		ctx:        ctx,
+		svcCtx:     svcCtx,
+		accounting: NewAccountingLogic(ctx, svcCtx),
+	}
+}
+
+// AddRule 保存一条新的周期性记账规则
+func (l *RecurringLogic) AddRule(chatID int64, userID int64, rule *model.RecurringRule) error {
+	now := time.Now()
+	if err := rule.ValidateDay(now); err != nil {
+		return err
+	}
+	rule.ID = newShortID()
+	rule.ChatID = chatID
+	rule.UserID = userID
+	rule.NextRun = rule.NextAfter(now)
+	rule.CreatedAt = now
+
+	if err := l.saveRule(rule); err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Sadd(recurringOwnersKey, ownerMember(chatID, userID)); err != nil {
+		return fmt.Errorf("保存周期性记账失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🔁 已添加周期性记账: %s %s，%s\n下次入账: %s",
+		rule.Description, formatRuleAmount(rule), rule.Schedule(), rule.NextRun.Format("2006-01-02 15:04")))
+	_, err := l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// ListRules 列出用户的全部周期性记账规则
+func (l *RecurringLogic) ListRules(chatID int64, userID int64) error {
+	rules, err := l.getRules(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "暂无周期性记账\n\n使用 /recurring add 房租 -3500 monthly 5 添加")
+		_, err := l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	var msgText strings.Builder
+	msgText.WriteString("🔁 周期性记账:\n\n")
+	for i, rule := range rules {
+		status := "下次: " + rule.NextRun.Format("01-02")
+		if rule.Paused {
+			status = "⏸ 已暂停"
+		}
+		msgText.WriteString(fmt.Sprintf("%d. %s %s，%s（%s）\n",
+			i+1, rule.Description, formatRuleAmount(rule), rule.Schedule(), status))
+	}
+	msgText.WriteString("\n使用 /recurring pause|resume|delete <序号> 管理")
+
+	msg := tgbotapi.NewMessage(chatID, msgText.String())
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// SetPaused 暂停或恢复一条规则，ref 为列表中的序号或规则ID
+func (l *RecurringLogic) SetPaused(chatID int64, userID int64, ref string, paused bool) error {
+	rule, err := l.findRule(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	if rule.Paused == paused {
+		return fmt.Errorf("该规则已经是%s状态", map[bool]string{true: "暂停", false: "启用"}[paused])
+	}
+
+	rule.Paused = paused
+	text := fmt.Sprintf("⏸ 已暂停: %s", rule.Description)
+	if !paused {
+		// 恢复时从现在起重新计算，暂停期间的不补记
+		rule.NextRun = rule.NextAfter(time.Now())
+		text = fmt.Sprintf("▶️ 已恢复: %s，下次入账 %s", rule.Description, rule.NextRun.Format("2006-01-02"))
+	}
+	if err := l.saveRule(rule); err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// DeleteRule 删除一条规则
+func (l *RecurringLogic) DeleteRule(chatID int64, userID int64, ref string) error {
+	rule, err := l.findRule(chatID, userID, ref)
+	if err != nil {
+		return err
+	}
+	if _, err := l.svcCtx.Redis.Hdel(recurringKey(chatID, userID), rule.ID); err != nil {
+		return fmt.Errorf("删除周期性记账失败: %v", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 已删除周期性记账: %s", rule.Description))
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// Register 在调度器中注册周期性记账，每次触发时检查到期的规则
+func (l *RecurringLogic) Register(s *Scheduler) {
+	s.Add("recurring", EveryTick(), l.runDue)
+}
+
+// 处理所有到期的规则
+func (l *RecurringLogic) runDue(now time.Time) {
+	owners, err := l.svcCtx.Redis.Smembers(recurringOwnersKey)
+	if err != nil {
+		log.Printf("获取周期性记账用户失败: %v", err)
+		return
+	}
+
+	for _, owner := range owners {
+		chatID, userID, ok := parseOwnerMember(owner)
+		if !ok {
+			continue
+		}
+		rules, err := l.getRules(chatID, userID)
+		if err != nil {
+			log.Printf("获取周期性记账规则失败: %v", err)
+			continue
+		}
+		if len(rules) == 0 {
+			_, _ = l.svcCtx.Redis.Srem(recurringOwnersKey, owner)
+			continue
+		}
+		for _, rule := range rules {
+			if rule.Paused || rule.NextRun.After(now) {
+				continue
+			}
+			if err := l.post(rule, now); err != nil {
+				log.Printf("周期性记账 %s 入账失败: %v", rule.ID, err)
+			}
+		}
+	}
+}
+
+// 将规则所有到期的入账记入活跃周期，并推进下次入账时间
+func (l *RecurringLogic) post(rule *model.RecurringRule, now time.Time) error {
+	var due []time.Time
+	for next := rule.NextRun; !next.After(now) && len(due) < recurringCatchUpLimit; next = rule.NextAfter(next) {
+		due = append(due, next)
+	}
+	rule.NextRun = rule.NextAfter(now)
+	rule.LastRun = now
+
+	cycle, err := l.accounting.getActiveCycle(rule.ChatID, rule.UserID)
+	if err != nil {
+		// 没有活跃周期时跳过本次入账，只推进时间
+		if err := l.saveRule(rule); err != nil {
+			return err
+		}
+		msg := tgbotapi.NewMessage(l.accounting.viewChat(rule.ChatID, rule.UserID), fmt.Sprintf("🔁 周期性记账「%s」已到期，但没有活跃的记账周期，本次未入账", rule.Description))
+		_, err = l.svcCtx.Bot.Send(msg)
+		return err
+	}
+
+	var posted []*model.AccountingRecord
+	req := &model.AccountingExpenseRequest{Amount: rule.Amount, Currency: rule.Currency, Description: rule.Description}
+	for _, date := range due {
+		// 以规则ID和到期日去重，防止保存规则前异常退出导致重复入账
+		externalID := fmt.Sprintf("recurring:%s:%s", rule.ID, date.Format("20060102"))
+		if hasExternalID(cycle.Records, externalID) {
+			continue
+		}
+		record, err := l.accounting.newRecord(rule.UserID, cycle, req, date)
+		if err != nil {
+			return err
+		}
+		record.Source = "recurring"
+		record.ExternalID = externalID
+		posted = append(posted, record)
+	}
+
+	if err := l.accounting.appendRecords(cycle, posted...); err != nil {
+		return err
+	}
+	for _, record := range posted {
+		l.accounting.auditRecord(cycle, rule.UserID, record, "周期性记账")
+	}
+	if err := l.saveRule(rule); err != nil {
+		return err
+	}
+	if len(posted) == 0 {
+		return nil
+	}
+
+	summary := l.accounting.calculateSummary(cycle)
+	base := cycle.Currency()
+	text := fmt.Sprintf("🔁 已自动记账: %s %s", rule.Description, formatRuleAmount(rule))
+	if len(posted) > 1 {
+		text += fmt.Sprintf("（补记 %d 次）", len(posted))
+	}
+	text += fmt.Sprintf("\n\n💵 剩余金额: %s\n下次入账: %s", formatMoney(summary.Balance, base), rule.NextRun.Format("2006-01-02"))
+	// 定时入账没有触发的消息，按隐私设置私聊发送时不在群里留提示
+	msg := tgbotapi.NewMessage(l.accounting.viewChat(rule.ChatID, rule.UserID), text)
+	_, err = l.svcCtx.Bot.Send(msg)
+	return err
+}
+
+// 按创建时间获取用户的全部规则
+func (l *RecurringLogic) getRules(chatID int64, userID int64) ([]*model.RecurringRule, error) {
+	data, err := l.svcCtx.Redis.Hgetall(recurringKey(chatID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取周期性记账失败: %v", err)
+	}
+
+	rules := make([]*model.RecurringRule, 0, len(data))
+	for _, value := range data {
+		var rule model.RecurringRule
+		if err := json.Unmarshal([]byte(value), &rule); err != nil {
+			log.Printf("解析周期性记账规则失败: %v", err)
+			continue
+		}
+		rules = append(rules, &rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules, nil
+}
+
+// 根据列表序号或规则ID查找规则
+func (l *RecurringLogic) findRule(chatID int64, userID int64, ref string) (*model.RecurringRule, error) {
+	rules, err := l.getRules(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if index, err := strconv.Atoi(ref); err == nil && index >= 1 && index <= len(rules) {
+		return rules[index-1], nil
+	}
+	for _, rule := range rules {
+		if rule.ID == ref {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("找不到周期性记账 %s，请使用 /recurring list 查看序号", ref)
+}
+
+func (l *RecurringLogic) saveRule(rule *model.RecurringRule) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("序列化周期性记账失败: %v", err)
+	}
+	if err := l.svcCtx.Redis.Hset(recurringKey(rule.ChatID, rule.UserID), rule.ID, string(data)); err != nil {
+		return fmt.Errorf("保存周期性记账失败: %v", err)
+	}
+	return nil
+}
+
+func recurringKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:recurring:%d:%d", chatID, userID)
+}
+
+func ownerMember(chatID int64, userID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, userID)
+}
+
+func parseOwnerMember(member string) (int64, int64, bool) {
+	parts := strings.Split(member, ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	chatID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	userID, err2 := strconv.ParseInt(parts[1], 10, 64)
+	return chatID, userID, err1 == nil && err2 == nil
+}
+
+// 规则金额，带正负号和币种
+func formatRuleAmount(rule *model.RecurringRule) string {
+	text := formatMoney(rule.Amount, rule.Currency)
+	if rule.Amount > 0 {
+		text = "+" + text
+	}
+	return text
+}
+
+func hasExternalID(records []*model.AccountingRecord, externalID string) bool {
+	for _, record := range records {
+		if record.ExternalID == externalID {
+			return true
+		}
+	}
+	return false
+}
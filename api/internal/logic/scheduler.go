@@ -0,0 +1,127 @@
+package logic
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Schedule 判断任务在某个时刻是否需要执行
+type Schedule func(now time.Time) bool
+
+// EveryTick 每次触发都执行
+func EveryTick() Schedule {
+	return func(time.Time) bool { return true }
+}
+
+// AtMinute 在指定小时的第 minute 分钟执行，不指定小时表示每小时
+func AtMinute(minute int, hours ...int) Schedule {
+	return func(now time.Time) bool {
+		if now.Minute() != minute {
+			return false
+		}
+		if len(hours) == 0 {
+			return true
+		}
+		for _, hour := range hours {
+			if now.Hour() == hour {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type scheduledJob struct {
+	name     string
+	schedule Schedule
+	run      func(now time.Time)
+	running  bool
+}
+
+// Scheduler 定时任务调度器
+// 触发时间对齐到整分钟（间隔小于一分钟时对齐到间隔），
+// 每个任务单独运行，上一次还没结束时跳过本次，任务 panic 不影响其他任务
+type Scheduler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	jobs     []*scheduledJob
+	stop     chan struct{}
+}
+
+func NewScheduler(interval time.Duration) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Add 注册一个任务，需在 Start 之前调用
+func (s *Scheduler) Add(name string, schedule Schedule, run func(now time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &scheduledJob{name: name, schedule: schedule, run: run})
+}
+
+// Start 在后台开始调度
+func (s *Scheduler) Start() {
+	go func() {
+		// 先等到下一个对齐的时刻，保证 AtMinute 之类的判断准确
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(s.interval).Add(s.interval).Sub(now))
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+		s.tick(time.Now())
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.tick(now)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止调度，正在运行的任务不会被中断
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	now = now.Truncate(time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if !job.schedule(now) {
+			continue
+		}
+		if job.running {
+			log.Printf("定时任务 %s 上一次还未结束，跳过本次", job.name)
+			continue
+		}
+		job.running = true
+		go s.runJob(job, now)
+	}
+}
+
+func (s *Scheduler) runJob(job *scheduledJob, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("定时任务 %s 异常: %v\n%s", job.name, r, debug.Stack())
+		}
+		s.mu.Lock()
+		job.running = false
+		s.mu.Unlock()
+	}()
+	job.run(now)
+}
@@ -241,9 +241,9 @@ func (l *WelfareLogic) HandleWelfareOrder(callback *tgbotapi.CallbackQuery) erro
 			"支付完成后，我们会安排上门服务。\n\n"+
 			"福利信息：\n"+
 			"名称：%s\n"+
-			"年龄：%s\n"+
-			"身高：%s\n"+
-			"体重：%s\n\n"+
+			"年龄：%d\n"+
+			"身高：%d\n"+
+			"体重：%d\n\n"+
 			"请点击下方按钮支付押金：",
 			welfare.Name, welfare.Age, welfare.Height, welfare.Weight))
 	msg.ParseMode = "HTML"
@@ -290,9 +290,9 @@ func (l *WelfareLogic) HandleWelfarePay(callback *tgbotapi.CallbackQuery) error
 			"支付完成后，我们会安排上门服务。\n\n"+
 			"福利信息：\n"+
 			"名称：%s\n"+
-			"年龄：%s\n"+
-			"身高：%s\n"+
-			"体重：%s",
+			"年龄：%d\n"+
+			"身高：%d\n"+
+			"体重：%d",
 			welfare.Name, welfare.Age, welfare.Height, welfare.Weight))
 	msg.ParseMode = "HTML"
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
@@ -322,9 +322,9 @@ func (l *WelfareLogic) HandleWelfareOrderAddress(chatID int64, welfareID string,
 			"您的地址已收到，我们已安排车辆前往。\n\n"+
 			"福利信息：\n"+
 			"名称：%s\n"+
-			"年龄：%s\n"+
-			"身高：%s\n"+
-			"体重：%s\n\n"+
+			"年龄：%d\n"+
+			"身高：%d\n"+
+			"体重：%d\n\n"+
 			"预计到达时间：30分钟内\n"+
 			"请保持电话畅通，司机将提前联系您。",
 			welfare.Name, welfare.Age, welfare.Height, welfare.Weight))
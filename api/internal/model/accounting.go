@@ -6,39 +6,164 @@ import (
 
 // AccountingCycle 表示一个记账周期
 type AccountingCycle struct {
-	ID        string                 `json:"id"`          // 唯一标识符
-	ChatID    int64                  `json:"chat_id"`     // 聊天ID
-	UserID    int64                  `json:"user_id"`     // 用户ID
-	StartTime time.Time              `json:"start_time"`  // 开始时间
-	EndTime   time.Time              `json:"end_time"`    // 结束时间（预计）
-	Income    float64                `json:"income"`      // 周期内的收入
-	Records   []*AccountingRecord    `json:"records"`     // 支出记录
-	IsActive  bool                   `json:"is_active"`   // 是否是当前活跃的周期
-	CreatedAt time.Time              `json:"created_at"`  // 创建时间
+	ID            string              `json:"id"`                       // 唯一标识符
+	ChatID        int64               `json:"chat_id"`                  // 聊天ID
+	UserID        int64               `json:"user_id"`                  // 用户ID
+	StartTime     time.Time           `json:"start_time"`               // 开始时间
+	EndTime       time.Time           `json:"end_time"`                 // 结束时间（预计）
+	Income        Money               `json:"income"`                   // 周期内的收入
+	BaseCurrency  string              `json:"base_currency,omitempty"`  // 本位币，为空时为人民币
+	Records       []*AccountingRecord `json:"records,omitempty"`        // 记录，单独保存，加载周期时按日期排列
+	IsActive      bool                `json:"is_active"`                // 是否是当前活跃的周期
+	GoalAllocated Money               `json:"goal_allocated,omitempty"` // 结余中已存入储蓄目标的金额
+	CreatedAt     time.Time           `json:"created_at"`               // 创建时间
 }
 
 // AccountingRecord 表示一条支出记录
 type AccountingRecord struct {
-	Amount      float64    `json:"amount"`     // 金额（正数为收入，负数为支出）
-	Description string     `json:"description"` // 描述
-	Date        time.Time  `json:"date"`       // 日期
-	CreatedAt   time.Time  `json:"created_at"` // 创建时间
+	ID             string         `json:"id,omitempty"`              // 记录ID
+	Amount         Money          `json:"amount"`                    // 金额（正数为收入，负数为支出），已折算为本位币
+	Currency       string         `json:"currency,omitempty"`        // 原始币种，为空时与本位币相同
+	OriginalAmount Money          `json:"original_amount,omitempty"` // 原始币种金额
+	Rate           Rate           `json:"rate,omitempty"`            // 记账时使用的汇率（1 单位原始币种折合人民币）
+	Description    string         `json:"description"`               // 描述
+	Category       string         `json:"category,omitempty"`        // 分类
+	Source         string         `json:"source,omitempty"`          // 来源（alipay、wechat、recurring），手工记账为空
+	ExternalID     string         `json:"external_id,omitempty"`     // 来源账单中的交易单号
+	Reimbursement  *Reimbursement `json:"reimbursement,omitempty"`   // 报销信息，不需要报销时为空
+	ReceiptFileID  string         `json:"receipt_file_id,omitempty"` // 小票照片的 Telegram 文件ID
+	Wallet         string         `json:"wallet,omitempty"`          // 钱包，为空时为默认钱包（升级前的记录）
+	Date           time.Time      `json:"date"`                      // 日期
+	CreatedAt      time.Time      `json:"created_at"`                // 创建时间
+}
+
+// Currency 返回周期的本位币
+func (c *AccountingCycle) Currency() string {
+	if c.BaseCurrency == "" {
+		return DefaultCurrency
+	}
+	return c.BaseCurrency
+}
+
+// OriginalCurrency 返回记录的原始币种
+func (r *AccountingRecord) OriginalCurrency(base string) string {
+	if r.Currency == "" {
+		return base
+	}
+	return r.Currency
+}
+
+// OriginalValue 返回记录在原始币种下的金额
+func (r *AccountingRecord) OriginalValue(base string) Money {
+	if r.OriginalCurrency(base) == base {
+		return r.Amount
+	}
+	return r.OriginalAmount
 }
 
 // AccountingStartRequest 开始记账周期的请求
 type AccountingStartRequest struct {
-	Income float64 `json:"income"` // 本周期收入
+	Income       Money  `json:"income"`        // 本周期收入
+	BaseCurrency string `json:"base_currency"` // 本位币
 }
 
 // AccountingExpenseRequest 记录支出的请求
 type AccountingExpenseRequest struct {
-	Amount float64 `json:"amount"` // 支出金额
+	Amount        Money     `json:"amount"`          // 金额（正数为收入，负数为支出），原始币种
+	Currency      string    `json:"currency"`        // 原始币种，为空时使用周期本位币
+	Description   string    `json:"description"`     // 描述
+	Date          time.Time `json:"date"`            // 记录日期，为空时使用记账时间
+	ReceiptFileID string    `json:"receipt_file_id"` // 小票照片的 Telegram 文件ID
+	Wallet        string    `json:"wallet"`          // 钱包，为空时使用用户的默认钱包
+	Category      string    `json:"category"`        // 分类，为空时按描述推断
 }
 
 // AccountingSummary 记账周期的摘要
 type AccountingSummary struct {
-	TotalIncome   float64 `json:"total_income"`   // 总收入
-	TotalExpense  float64 `json:"total_expense"`  // 总支出
-	Balance       float64 `json:"balance"`        // 余额
-	DaysRemaining int     `json:"days_remaining"` // 剩余天数
-} 
\ No newline at end of file
+	TotalIncome       Money            `json:"total_income"`            // 总收入
+	TotalExpense      Money            `json:"total_expense"`           // 总支出
+	Balance           Money            `json:"balance"`                 // 余额
+	DaysRemaining     int              `json:"days_remaining"`          // 剩余天数
+	DailyAllowance    Money            `json:"daily_allowance"`         // 每日可用金额（余额 / 剩余天数）
+	AverageDailySpend Money            `json:"average_daily_spend"`     // 周期开始以来的日均支出
+	ProjectedBalance  Money            `json:"projected_balance"`       // 按日均支出推算的周期结束余额
+	OverdrawDate      time.Time        `json:"overdraw_date,omitempty"` // 按日均支出余额耗尽的时间，周期结束前不会透支时为零值
+	Currency          string           `json:"currency"`                // 本位币
+	ByCurrency        []*CurrencyTotal `json:"by_currency,omitempty"`   // 按原始币种的收支
+}
+
+// CurrencyTotal 某一原始币种下的收支合计
+type CurrencyTotal struct {
+	Currency string `json:"currency"` // 币种
+	Income   Money  `json:"income"`   // 收入（原始币种）
+	Expense  Money  `json:"expense"`  // 支出（原始币种）
+}
+
+// AccountingExportRequest 导出记账记录的请求
+// 指定 CycleID 时导出该周期，否则按 From/To 日期范围导出，两者都为空时导出当前（或最近）周期
+type AccountingExportRequest struct {
+	CycleID string    `json:"cycle_id"` // 记账周期ID
+	From    time.Time `json:"from"`     // 开始日期（含）
+	To      time.Time `json:"to"`       // 结束日期（含）
+	Format  string    `json:"format"`   // csv 或 xlsx
+}
+
+// 账单报告的统计周期
+const (
+	ReportMonth = "month"
+	ReportYear  = "year"
+)
+
+// AccountingReportRequest 按自然月或自然年统计的账单报告请求
+type AccountingReportRequest struct {
+	Period string    `json:"period"` // ReportMonth 或 ReportYear
+	Start  time.Time `json:"start"`  // 统计开始时间（当月或当年第一天零点）
+}
+
+// End 统计结束时间（不含）
+func (r *AccountingReportRequest) End() time.Time {
+	if r.Period == ReportYear {
+		return r.Start.AddDate(1, 0, 0)
+	}
+	return r.Start.AddDate(0, 1, 0)
+}
+
+// Previous 上一个统计周期的开始时间
+func (r *AccountingReportRequest) Previous() time.Time {
+	if r.Period == ReportYear {
+		return r.Start.AddDate(-1, 0, 0)
+	}
+	return r.Start.AddDate(0, -1, 0)
+}
+
+// Label 统计周期的名称，例如 "2026年9月"、"2026年"
+func (r *AccountingReportRequest) Label() string {
+	if r.Period == ReportYear {
+		return r.Start.Format("2006年")
+	}
+	return r.Start.Format("2006年1月")
+}
+
+// AccountingBatch 等待确认的批量记账，一条消息中有多条记账时先预览再写入
+type AccountingBatch struct {
+	ID        string                      `json:"id"`         // 批次ID
+	ChatID    int64                       `json:"chat_id"`    // 聊天ID
+	UserID    int64                       `json:"user_id"`    // 发起记账的用户
+	Text      string                      `json:"text"`       // 原始消息，修改时供用户复制
+	MessageID int                         `json:"message_id"` // 原始消息的ID，修改提示回复这条消息
+	Requests  []*AccountingExpenseRequest `json:"requests"`   // 解析出的记账请求
+	CreatedAt time.Time                   `json:"created_at"` // 创建时间
+}
+
+// AccountingImport 等待确认的账单导入
+type AccountingImport struct {
+	ID         string              `json:"id"`         // 导入ID
+	ChatID     int64               `json:"chat_id"`    // 聊天ID
+	UserID     int64               `json:"user_id"`    // 发起导入的用户
+	Source     string              `json:"source"`     // 账单来源
+	FileName   string              `json:"file_name"`  // 原始文件名
+	Records    []*AccountingRecord `json:"records"`    // 解析出的记录（人民币）
+	Duplicates []bool              `json:"duplicates"` // 与 Records 一一对应，是否疑似重复
+	Skipped    int                 `json:"skipped"`    // 因状态或收支类型被忽略的行数
+	CreatedAt  time.Time           `json:"created_at"` // 创建时间
+}
@@ -0,0 +1,409 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 口语金额解析
+//
+// 支持的写法：
+//   - 阿拉伯数字：12、12.5、2k、1.5w、3千、1.2万
+//   - 中文数字：三十五、一百二（=120）、一千五（=1500）、两万五千
+//   - 货币单位：元/块/块钱/圆，毛/角，分，例如 三十五块、五毛、3块5、三块零五分、1元2角3分
+//
+// 中文数字在文本中出现时，只有带货币单位或带十/百/千/万时才视为金额，
+// 避免把 "一个"、"三明治" 之类的描述当作金额
+
+var chineseDigits = map[rune]int64{
+	'零': 0, '〇': 0, '一': 1, '壹': 1, '二': 2, '贰': 2, '两': 2, '三': 3, '叁': 3, '四': 4, '肆': 4,
+	'五': 5, '伍': 5, '六': 6, '陆': 6, '七': 7, '柒': 7, '八': 8, '捌': 8, '九': 9, '玖': 9,
+}
+
+var chinesePlaces = map[rune]int64{
+	'十': 10, '拾': 10, '百': 100, '佰': 100, '千': 1000, '仟': 1000, '万': 10000,
+}
+
+// 表示"元"的单位，按长度从长到短匹配
+var yuanUnits = []string{"块钱", "块", "元", "圆"}
+
+// AmountToken 文本中识别出的一个金额
+type AmountToken struct {
+	Text  string // 原文，包含符号和单位
+	Start int    // 在文本中的起始字节位置
+	End   int    // 结束字节位置（不含）
+	Value Money  // 金额的绝对值
+	Sign  int    // 写明的符号：1 为 "+"，-1 为 "-"，0 为未写
+	Err   error  // 看起来是金额但无法解析，例如 "12.345"
+}
+
+// ParseAmount 将整段文本解析为一个金额，允许带 +/- 符号
+// 例如 "三十五块"、"-2k"、"+1.5w"、"五毛"
+func ParseAmount(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("金额不能为空")
+	}
+
+	runes := []rune(s)
+	sign, start := signAt(runes, 0)
+	value, end, _, err := scanAmount(runes, start)
+	if err != nil {
+		return 0, err
+	}
+	if end == start || end != len(runes) {
+		return 0, fmt.Errorf("无效的金额: %s", s)
+	}
+	if sign < 0 {
+		value = -value
+	}
+	return value, nil
+}
+
+// FindAmounts 按出现顺序找出文本中的所有金额
+func FindAmounts(text string) []AmountToken {
+	runes := []rune(text)
+	offsets := runeOffsets(text)
+
+	var tokens []AmountToken
+	for i := 0; i < len(runes); {
+		sign, start := signAt(runes, i)
+		if !amountCanStart(runes, start) || (start > 0 && sign == 0 && continuesNumber(runes[start-1], runes[start])) {
+			i++
+			continue
+		}
+
+		value, end, explicit, err := scanAmount(runes, start)
+		if end == start {
+			i++
+			continue
+		}
+		if err != nil {
+			tokens = append(tokens, AmountToken{Text: string(runes[i:end]), Start: offsets[i], End: offsets[end], Sign: sign, Err: err})
+			i = end
+			continue
+		}
+		if !explicit && (isChineseNumeral(runes[start]) && !hasPlace(runes[start:end]) || followedByNonMoneyUnit(runes, end)) {
+			// 单独的中文数字（"一个"、"三明治"）以及 "5kg"、"5分钟"、"3月"、"2斤" 不是金额
+			i = end
+			continue
+		}
+
+		tokens = append(tokens, AmountToken{
+			Text:  string(runes[i:end]),
+			Start: offsets[i],
+			End:   offsets[end],
+			Value: value,
+			Sign:  sign,
+		})
+		i = end
+	}
+	return tokens
+}
+
+// 读取 i 处的正负号，返回符号和数字开始的位置
+func signAt(runes []rune, i int) (int, int) {
+	if i < len(runes) {
+		switch runes[i] {
+		case '+', '＋':
+			return 1, i + 1
+		case '-', '－', '−':
+			return -1, i + 1
+		}
+	}
+	return 0, i
+}
+
+func amountCanStart(runes []rune, i int) bool {
+	if i >= len(runes) {
+		return false
+	}
+	r := runes[i]
+	_, digit := chineseDigits[r]
+	return isASCIIDigit(r) || digit || r == '十' || r == '拾'
+}
+
+// 前一个字符与当前字符属于同一个数字时不能从中间开始识别，例如 "12" 中的 "2"
+func continuesNumber(prev rune, cur rune) bool {
+	if isASCIIDigit(cur) {
+		return isASCIIDigit(prev) || prev == '.' || unicode.IsLetter(prev) && prev < unicode.MaxASCII
+	}
+	return isChineseNumeral(prev)
+}
+
+// 从 i 开始扫描一个不带符号的金额，返回金额、结束位置以及是否带货币单位
+func scanAmount(runes []rune, i int) (Money, int, bool, error) {
+	var cents int64
+	var end int
+	var err error
+	chinese := i < len(runes) && isChineseNumeral(runes[i])
+	if chinese {
+		var n int64
+		n, end = scanChineseNumber(runes, i)
+		cents = n * MoneyScale
+	} else {
+		cents, end, err = scanArabicNumber(runes, i)
+		if err != nil {
+			return 0, end, false, err
+		}
+	}
+	if end == i {
+		return 0, i, false, nil
+	}
+
+	explicit := false
+	if unitEnd, ok := matchYuan(runes, end); ok {
+		// 3块5、三块五毛、三块零五分
+		explicit = true
+		end = unitEnd
+		jiao, fen, fracEnd := scanFraction(runes, end)
+		cents += jiao*10 + fen
+		end = fracEnd
+	} else if end < len(runes) && isJiao(runes[end]) {
+		// 五毛、5毛5
+		explicit = true
+		cents = cents / MoneyScale * 10
+		end++
+		if d, ok := digitAt(runes, end); ok {
+			cents += d
+			end++
+			if end < len(runes) && runes[end] == '分' {
+				end++
+			}
+		}
+	} else if end < len(runes) && runes[end] == '分' && !(end+1 < len(runes) && runes[end+1] == '钟') {
+		// "5分钟" 不是金额单位
+		explicit = true
+		cents = cents / MoneyScale
+		end++
+	}
+
+	if cents > int64(MaxMoney) || cents < 0 {
+		return 0, end, explicit, fmt.Errorf("金额超出范围: %s", string(runes[i:end]))
+	}
+	return Money(cents), end, explicit, nil
+}
+
+// 扫描阿拉伯数字，支持小数和 k/w/千/万 后缀，返回以分为单位的值
+func scanArabicNumber(runes []rune, i int) (int64, int, error) {
+	j := i
+	for j < len(runes) && isASCIIDigit(runes[j]) {
+		j++
+	}
+	if j == i {
+		return 0, i, nil
+	}
+	intPart := string(runes[i:j])
+	fracPart := ""
+	if j+1 < len(runes) && runes[j] == '.' && isASCIIDigit(runes[j+1]) {
+		k := j + 1
+		for k < len(runes) && isASCIIDigit(runes[k]) {
+			k++
+		}
+		fracPart = string(runes[j+1 : k])
+		j = k
+	}
+
+	multiplier := int64(1)
+	if j < len(runes) {
+		switch runes[j] {
+		case 'k', 'K', '千':
+			multiplier = 1000
+		case 'w', 'W', '万':
+			multiplier = 10000
+		}
+		// "2kg"、"3km" 之类的后缀不是金额单位
+		if multiplier > 1 && j+1 < len(runes) && runes[j+1] < unicode.MaxASCII && unicode.IsLetter(runes[j+1]) {
+			multiplier = 1
+		}
+		if multiplier > 1 {
+			j++
+		}
+	}
+
+	text := string(runes[i:j])
+	if len(strings.TrimLeft(intPart, "0")) > 10 {
+		return 0, j, fmt.Errorf("金额超出范围: %s", text)
+	}
+	var yuan int64
+	for _, r := range intPart {
+		yuan = yuan*10 + int64(r-'0')
+	}
+
+	// 小数部分乘以倍数后必须精确到分，例如 1.5w 可以，1.234 不行
+	scale := multiplier * MoneyScale
+	var frac int64
+	denominator := int64(1)
+	for _, r := range fracPart {
+		if denominator > 1e12 {
+			return 0, j, fmt.Errorf("金额最多保留两位小数: %s", text)
+		}
+		frac = frac*10 + int64(r-'0')
+		denominator *= 10
+	}
+	if frac*scale%denominator != 0 {
+		return 0, j, fmt.Errorf("金额最多保留两位小数: %s", text)
+	}
+	if yuan > int64(MaxMoney)/scale {
+		return 0, j, fmt.Errorf("金额超出范围: %s", text)
+	}
+	return yuan*scale + frac*scale/denominator, j, nil
+}
+
+// 扫描中文数字，支持 "一百二" 这类省略末位单位的口语写法
+func scanChineseNumber(runes []rune, i int) (int64, int) {
+	var total, section, number int64
+	var lastPlace int64
+	afterZero := false
+	j := i
+	for ; j < len(runes); j++ {
+		r := runes[j]
+		if d, ok := chineseDigits[r]; ok {
+			if d == 0 {
+				afterZero = true
+			}
+			number = d
+			continue
+		}
+		place, ok := chinesePlaces[r]
+		if !ok {
+			break
+		}
+		if number == 0 && place == 10 {
+			// "十五"、"一百十五" 中的十表示一十
+			number = 1
+		}
+		if place == 10000 {
+			section += number
+			total += section * place
+			section = 0
+		} else {
+			section += number * place
+		}
+		number = 0
+		lastPlace = place
+		afterZero = false
+	}
+
+	// 一百二 = 120、一千五 = 1500、一万二 = 12000
+	if number > 0 && !afterZero && lastPlace >= 100 && j-i >= 3 && isChinesePlace(runes[j-2]) {
+		number *= lastPlace / 10
+	}
+	return total + section + number, j
+}
+
+// 匹配 i 处的 元/块/块钱/圆
+func matchYuan(runes []rune, i int) (int, bool) {
+	for _, unit := range yuanUnits {
+		u := []rune(unit)
+		if i+len(u) <= len(runes) && string(runes[i:i+len(u)]) == unit {
+			return i + len(u), true
+		}
+	}
+	return i, false
+}
+
+// 扫描 "块" 之后的角分：5、五毛、零五分、5角6分
+func scanFraction(runes []rune, i int) (int64, int64, int) {
+	var jiao, fen int64
+	j := i
+	if j < len(runes) && (runes[j] == '零' || runes[j] == '〇') {
+		// 三块零五分
+		if d, ok := digitAt(runes, j+1); ok && j+2 < len(runes) && runes[j+2] == '分' {
+			return 0, d, j + 3
+		}
+		return 0, 0, i
+	}
+	d, ok := digitAt(runes, j)
+	if !ok {
+		return 0, 0, i
+	}
+	// 后面紧跟数字说明不是角，例如 "3块50"
+	if _, more := digitAt(runes, j+1); more {
+		return 0, 0, i
+	}
+	jiao = d
+	j++
+	if j < len(runes) && isJiao(runes[j]) {
+		j++
+	} else if j < len(runes) && runes[j] == '分' {
+		return 0, d, j + 1
+	}
+	if d, ok := digitAt(runes, j); ok && j+1 < len(runes) && runes[j+1] == '分' {
+		fen = d
+		j += 2
+	}
+	return jiao, fen, j
+}
+
+// 读取 i 处的单个数字（阿拉伯或中文）
+func digitAt(runes []rune, i int) (int64, bool) {
+	if i >= len(runes) {
+		return 0, false
+	}
+	if isASCIIDigit(runes[i]) {
+		return int64(runes[i] - '0'), true
+	}
+	d, ok := chineseDigits[runes[i]]
+	return d, ok
+}
+
+// 紧跟在数字后面、说明该数字是日期、时间或数量而不是金额的字
+var quantityUnits = map[rune]bool{
+	'年': true, '月': true, '日': true, '号': true, '点': true, '天': true, '周': true, '岁': true,
+	'个': true, '次': true, '件': true, '杯': true, '份': true, '张': true, '瓶': true, '斤': true, '人': true,
+	'时': true, '晚': true, '间': true, '箱': true, '包': true, '盒': true, '双': true, '袋': true, '只': true,
+	'公': true, '升': true, '克': true, '碗': true, '本': true, '台': true, '条': true, '顿': true,
+}
+
+// 数字后紧跟英文单位、"分钟"、"小时" 或数量词
+func followedByNonMoneyUnit(runes []rune, i int) bool {
+	if i >= len(runes) {
+		return false
+	}
+	r := runes[i]
+	if r < unicode.MaxASCII && unicode.IsLetter(r) {
+		return true
+	}
+	if i+1 < len(runes) && (r == '分' && runes[i+1] == '钟' || r == '小' && runes[i+1] == '时') {
+		return true
+	}
+	return quantityUnits[r]
+}
+
+func hasPlace(runes []rune) bool {
+	for _, r := range runes {
+		if isChinesePlace(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJiao(r rune) bool {
+	return r == '毛' || r == '角'
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isChinesePlace(r rune) bool {
+	_, ok := chinesePlaces[r]
+	return ok
+}
+
+func isChineseNumeral(r rune) bool {
+	_, digit := chineseDigits[r]
+	return digit || isChinesePlace(r)
+}
+
+// 每个 rune 在字符串中的字节位置，最后多一项为字符串长度
+func runeOffsets(text string) []int {
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	return append(offsets, len(text))
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		// 阿拉伯数字
+		{"0", 0, false},
+		{"12", 1200, false},
+		{"12.5", 1250, false},
+		{"12.50", 1250, false},
+		{"0.01", 1, false},
+		{"+35", 3500, false},
+		{"-35", -3500, false},
+		{"－35", -3500, false},
+		{"＋35", 3500, false},
+		{"007", 700, false},
+		{"12.345", 0, true},
+		{"12.", 0, true},
+		{".5", 0, true},
+		{"1e3", 0, true},
+		{"1,000", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+		{"abc", 0, true},
+
+		// k/w/千/万 后缀
+		{"2k", 200000, false},
+		{"2K", 200000, false},
+		{"1.5k", 150000, false},
+		{"1.25k", 125000, false},
+		{"1.5w", 1500000, false},
+		{"1.5W", 1500000, false},
+		{"3w", 3000000, false},
+		{"0.35w", 350000, false},
+		{"1.23456w", 1234560, false},
+		{"1.2345678w", 0, true},
+		{"3千", 300000, false},
+		{"1.2万", 1200000, false},
+		{"-2k", -200000, false},
+		{"2kg", 0, true},
+		{"100000w", 100000000000, false},
+		{"100001w", 0, true},
+
+		// 货币单位
+		{"35块", 3500, false},
+		{"35元", 3500, false},
+		{"35块钱", 3500, false},
+		{"35圆", 3500, false},
+		{"3块5", 350, false},
+		{"3元5角", 350, false},
+		{"1元2角3分", 123, false},
+		{"5毛", 50, false},
+		{"5角", 50, false},
+		{"5毛5", 55, false},
+		{"8分", 8, false},
+		{"12.5元", 1250, false},
+		{"2k块", 200000, false},
+		{"3块50", 0, true},
+
+		// 中文数字
+		{"一", 100, false},
+		{"两", 200, false},
+		{"十", 1000, false},
+		{"十五", 1500, false},
+		{"二十", 2000, false},
+		{"三十五", 3500, false},
+		{"一百", 10000, false},
+		{"一百零五", 10500, false},
+		{"一百一十", 11000, false},
+		{"一百十五", 11500, false},
+		{"一千零一", 100100, false},
+		{"两千三百四十五", 234500, false},
+		{"一万", 1000000, false},
+		{"十万", 10000000, false},
+		{"两万五千", 2500000, false},
+		{"壹佰贰拾", 12000, false},
+		{"零", 0, false},
+
+		// 口语省略末位单位
+		{"一百二", 12000, false},
+		{"三百五", 35000, false},
+		{"一千五", 150000, false},
+		{"一万二", 1200000, false},
+		{"两万五", 2500000, false},
+		{"两万五千三", 2530000, false},
+		{"一百零二", 10200, false},
+		{"三十二", 3200, false},
+
+		// 中文数字加单位
+		{"三十五块", 3500, false},
+		{"一百二十块钱", 12000, false},
+		{"五毛", 50, false},
+		{"两毛五", 25, false},
+		{"三块五", 350, false},
+		{"三块五毛", 350, false},
+		{"三块零五分", 305, false},
+		{"十块零八分", 1008, false},
+		{"一块二毛五分", 125, false},
+		{"九分", 9, false},
+		{"-三十五块", -3500, false},
+		{"+一百二", 12000, false},
+
+		// 带多余内容
+		{"35块 午饭", 0, true},
+		{"三十五个", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.input)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseAmount(%q) = %s, want error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAmount(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFindAmounts(t *testing.T) {
+	type token struct {
+		Text  string
+		Value Money
+		Sign  int
+	}
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{"午饭35", []token{{"35", 3500, 0}}},
+		{"午饭-35", []token{{"-35", 3500, -1}}},
+		{"工资+8000", []token{{"+8000", 800000, 1}}},
+		{"打车 三十五块", []token{{"三十五块", 3500, 0}}},
+		{"奶茶五毛", []token{{"五毛", 50, 0}}},
+		{"房租2k", []token{{"2k", 200000, 0}}},
+		{"奖金+1.5w", []token{{"+1.5w", 1500000, 1}}},
+		{"午饭35, 打车-20", []token{{"35", 3500, 0}, {"-20", 2000, -1}}},
+		{"买菜一百二", []token{{"一百二", 12000, 0}}},
+		{"停车3块5", []token{{"3块5", 350, 0}}},
+		{"冰棍三块五毛", []token{{"三块五毛", 350, 0}}},
+		{"一个苹果5块", []token{{"5块", 500, 0}}},
+		{"三明治12", []token{{"12", 1200, 0}}},
+		{"iPhone15壳 39", []token{{"39", 3900, 0}}},
+		{"大米5kg 60", []token{{"60", 6000, 0}}},
+		{"等车5分钟 打车30", []token{{"30", 3000, 0}}},
+		{"11点 夜宵 80", []token{{"80", 8000, 0}}},
+		{"十一点吃饭 三十", []token{{"三十", 3000, 0}}},
+		{"流量包10G 30元", []token{{"30元", 3000, 0}}},
+		{"3月100元", []token{{"100元", 10000, 0}}},
+		{"两杯奶茶 三十", []token{{"三十", 3000, 0}}},
+		{"水果2斤 15", []token{{"15", 1500, 0}}},
+		{"停车2小时 10", []token{{"10", 1000, 0}}},
+		{"矿泉水3箱 45", []token{{"45", 4500, 0}}},
+		{"没有金额", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		var got []token
+		for _, tok := range FindAmounts(tt.input) {
+			if tt.input[tok.Start:tok.End] != tok.Text {
+				t.Errorf("FindAmounts(%q) token %q has offsets [%d,%d)", tt.input, tok.Text, tok.Start, tok.End)
+			}
+			if tok.Err != nil {
+				t.Errorf("FindAmounts(%q) token %q unexpected error: %v", tt.input, tok.Text, tok.Err)
+			}
+			got = append(got, token{tok.Text, tok.Value, tok.Sign})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindAmounts(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFindAmountsInvalid(t *testing.T) {
+	for _, input := range []string{"午饭12.345", "房租99999999999", "奖金1.2345678w"} {
+		tokens := FindAmounts(input)
+		if len(tokens) != 1 || tokens[0].Err == nil {
+			t.Errorf("FindAmounts(%q) = %v, want one invalid token", input, tokens)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 审计事件类型
+const (
+	AuditStart    = "start"    // 开始周期
+	AuditAdd      = "add"      // 新增记录
+	AuditEdit     = "edit"     // 修改记录或周期，例如报销状态、结余存入目标
+	AuditEnd      = "end"      // 结束周期
+	AuditImport   = "import"   // 批量导入账单
+	AuditTransfer = "transfer" // 钱包之间转账
+)
+
+var auditActionNames = map[string]string{
+	AuditStart:    "开始周期",
+	AuditAdd:      "新增",
+	AuditEdit:     "修改",
+	AuditEnd:      "结束周期",
+	AuditImport:   "导入",
+	AuditTransfer: "转账",
+}
+
+// AuditActionName 返回审计事件类型的中文名称
+func AuditActionName(action string) string {
+	if name, ok := auditActionNames[action]; ok {
+		return name
+	}
+	return action
+}
+
+// AuditEvent 记账周期的一次变更，只追加不修改
+// Before 和 After 是变更前后的值的文字描述，新增时 Before 为空，删除时 After 为空
+type AuditEvent struct {
+	CycleID  string    `json:"cycle_id"`
+	Action   string    `json:"action"`
+	ActorID  int64     `json:"actor_id"` // 操作人，自动任务（例如周期性记账）为周期所有者
+	RecordID string    `json:"record_id,omitempty"`
+	Before   string    `json:"before,omitempty"`
+	After    string    `json:"after,omitempty"`
+	Note     string    `json:"note,omitempty"` // 补充说明，例如 "周期性记账"
+	Time     time.Time `json:"time"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import "strings"
+
+// 记账分类
+const (
+	CategoryFood          = "餐饮"
+	CategoryTransport     = "交通"
+	CategoryShopping      = "购物"
+	CategoryHousing       = "居住"
+	CategoryCommunication = "通讯"
+	CategoryEntertainment = "娱乐"
+	CategoryMedical       = "医疗"
+	CategorySalary        = "工资"
+	CategoryIncome        = "其他收入"
+	CategoryOther         = "其他"
+)
+
+// categoryKeywords 按顺序匹配描述中的关键词，先匹配到的优先
+var categoryKeywords = []struct {
+	Category string
+	Keywords []string
+}{
+	{CategorySalary, []string{"工资", "薪水", "奖金", "年终"}},
+	{CategoryFood, []string{"早餐", "早饭", "午餐", "午饭", "晚餐", "晚饭", "夜宵", "宵夜", "外卖", "奶茶", "咖啡", "饭", "菜", "餐", "吃", "水果", "零食", "饮料"}},
+	{CategoryTransport, []string{"交通", "出行", "打车", "滴滴", "出租", "地铁", "公交", "高铁", "火车", "机票", "飞机", "加油", "停车", "油费", "过路费"}},
+	{CategoryHousing, []string{"住房", "房租", "租金", "水费", "电费", "燃气", "物业", "宽带"}},
+	{CategoryCommunication, []string{"通讯", "话费", "流量", "手机费"}},
+	{CategoryEntertainment, []string{"娱乐", "电影", "游戏", "KTV", "旅游", "门票", "会员", "订阅"}},
+	{CategoryMedical, []string{"医疗", "医院", "药", "挂号", "体检"}},
+	{CategoryShopping, []string{"超市", "百货", "淘宝", "京东", "拼多多", "衣服", "鞋", "日用"}},
+}
+
+// Categories 所有可用分类
+var Categories = []string{
+	CategoryFood, CategoryTransport, CategoryShopping, CategoryHousing, CategoryCommunication,
+	CategoryEntertainment, CategoryMedical, CategorySalary, CategoryIncome, CategoryOther,
+}
+
+// Categorize 根据描述关键词推断分类
+func Categorize(description string, income bool) string {
+	upper := strings.ToUpper(description)
+	for _, item := range categoryKeywords {
+		for _, keyword := range item.Keywords {
+			if strings.Contains(upper, keyword) {
+				return item.Category
+			}
+		}
+	}
+	if income {
+		return CategoryIncome
+	}
+	return CategoryOther
+}
+
+// CategoryOf 返回记录的分类，旧记录没有分类时按描述推断
+func (r *AccountingRecord) CategoryOf() string {
+	if r.Category != "" {
+		return r.Category
+	}
+	return Categorize(r.Description, r.Amount > 0)
+}
+
+// SplitNote 将 "买菜(未报销)" 拆分为描述 "买菜" 和备注 "未报销"
+func SplitNote(description string) (string, string) {
+	trimmed := strings.TrimSpace(description)
+	for _, pair := range [][2]string{{"(", ")"}, {"（", "）"}} {
+		if !strings.HasSuffix(trimmed, pair[1]) {
+			continue
+		}
+		if i := strings.LastIndex(trimmed, pair[0]); i > 0 {
+			return strings.TrimSpace(trimmed[:i]), trimmed[i+len(pair[0]) : len(trimmed)-len(pair[1])]
+		}
+	}
+	return trimmed, ""
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency 记账周期默认的本位币
+const DefaultCurrency = "CNY"
+
+// SupportedCurrencies 支持的币种
+var SupportedCurrencies = []string{"CNY", "USD", "USDT", "HKD"}
+
+// currencyAliases 币种别名，键统一为大写
+var currencyAliases = map[string]string{
+	"CNY":  "CNY",
+	"RMB":  "CNY",
+	"人民币":  "CNY",
+	"USD":  "USD",
+	"US$":  "USD",
+	"$":    "USD",
+	"美元":   "USD",
+	"美金":   "USD",
+	"USDT": "USDT",
+	"U":    "USDT",
+	"HKD":  "HKD",
+	"HK$":  "HKD",
+	"港币":   "HKD",
+	"港元":   "HKD",
+}
+
+// currencyPattern 匹配文本中的币种标记，英文代码两侧不能紧贴字母
+// "U" 只在紧跟数字时才视为 USDT，例如 "100U"
+var currencyPattern = regexp.MustCompile(`(?i)(?:^|[^A-Za-z$])(USDT|USD|HKD|CNY|RMB|HK\$|US\$|\$|美元|美金|港币|港元|人民币)(?:$|[^A-Za-z])|\d(U)(?:$|[^A-Za-z])`)
+
+// NormalizeCurrency 将币种别名转换为标准代码
+func NormalizeCurrency(s string) (string, bool) {
+	code, ok := currencyAliases[strings.ToUpper(strings.TrimSpace(s))]
+	return code, ok
+}
+
+// ExtractCurrency 从记账文本中提取币种标记，返回标准代码和去掉标记后的文本
+// 没有币种标记时返回空字符串和原文本
+func ExtractCurrency(text string) (string, string) {
+	loc := currencyPattern.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return "", text
+	}
+
+	start, end := loc[2], loc[3]
+	if start < 0 {
+		start, end = loc[4], loc[5]
+	}
+	code, ok := NormalizeCurrency(text[start:end])
+	if !ok {
+		return "", text
+	}
+	return code, strings.TrimSpace(text[:start] + " " + text[end:])
+}
+
+// RateScale 汇率的精度（六位小数）
+const RateScale = 1000000
+
+// Rate 汇率，表示 1 单位外币折合多少人民币，按 RateScale 放大保存
+type Rate int64
+
+// BaseRate 人民币自身的汇率
+const BaseRate Rate = RateScale
+
+// ParseRate 解析正数汇率，最多六位小数
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+		if fracPart == "" {
+			return 0, fmt.Errorf("无效的汇率: %s", s)
+		}
+	}
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) || len(fracPart) > 6 || len(intPart) > 6 {
+		return 0, fmt.Errorf("无效的汇率: %s", s)
+	}
+
+	whole, _ := strconv.ParseInt(intPart, 10, 64)
+	var frac int64
+	if fracPart != "" {
+		frac, _ = strconv.ParseInt((fracPart + "000000")[:6], 10, 64)
+	}
+	r := Rate(whole*RateScale + frac)
+	if r <= 0 {
+		return 0, fmt.Errorf("汇率必须大于0: %s", s)
+	}
+	return r, nil
+}
+
+// String 以去掉多余零的十进制形式输出汇率
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%06d", int64(r)/RateScale, int64(r)%RateScale)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON 以字符串形式保存汇率
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON 解析字符串形式的汇率
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// ConvertMoney 按两种币种各自的人民币汇率折算金额
+func ConvertMoney(amount Money, from, to Rate) Money {
+	return amount.MulDiv(int64(from), int64(to))
+}
+
+// ExchangeRate 某一天设置的汇率
+type ExchangeRate struct {
+	Currency string    `json:"currency"` // 币种
+	Rate     Rate      `json:"rate"`     // 1 单位折合人民币
+	Date     time.Time `json:"date"`     // 生效日期
+}
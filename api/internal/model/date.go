@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 相对日期，值为距今天的天数
+var relativeDays = map[string]int{
+	"今天":  0,
+	"今日":  0,
+	"昨天":  1,
+	"昨日":  1,
+	"前天":  2,
+	"大前天": 3,
+}
+
+var weekdayNumbers = map[string]int{
+	"一": 1, "二": 2, "三": 3, "四": 4, "五": 5, "六": 6, "日": 7, "天": 7,
+}
+
+var (
+	relativeDatePattern = regexp.MustCompile(`^(大前天|前天|昨天|昨日|今天|今日)`)
+	weekdayPattern      = regexp.MustCompile(`^(上)?(?:周|星期|礼拜)([一二三四五六日天])`)
+	fullDatePattern     = regexp.MustCompile(`^(\d{4})[-/.年](\d{1,2})[-/.月](\d{1,2})[日号]?`)
+	monthDayPattern     = regexp.MustCompile(`^(\d{1,2})(?:/|月)(\d{1,2})[日号]?`)
+)
+
+// ExtractDate 识别文本开头的日期表达式，返回记录日期和去掉日期后的文本
+// 支持 今天/昨天/前天/大前天、周五/星期五/上周五、3/14、3月14日、2026-10-01
+// 周X 指本周内不晚于今天的那一天（今天之后的则取上周）；月日不带年份时取不晚于今天的最近一次
+// 日期的时刻沿用 now，保证同一天内按记账先后排序；没有日期表达式时返回零值时间
+func ExtractDate(text string, now time.Time) (time.Time, string, error) {
+	text = strings.TrimSpace(text)
+	today := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+
+	if m := relativeDatePattern.FindStringSubmatch(text); m != nil {
+		return today.AddDate(0, 0, -relativeDays[m[1]]), trimDate(text, m[0]), nil
+	}
+
+	if m := weekdayPattern.FindStringSubmatch(text); m != nil {
+		back := (isoWeekday(today) - weekdayNumbers[m[2]] + 7) % 7
+		if m[1] != "" {
+			// 上周X：上一个自然周（周一开始）中的那一天
+			back = isoWeekday(today) - 1 + 7 - (weekdayNumbers[m[2]] - 1)
+		}
+		return today.AddDate(0, 0, -back), trimDate(text, m[0]), nil
+	}
+
+	if m := fullDatePattern.FindStringSubmatch(text); m != nil && !followedByDigit(text, m[0]) {
+		year, _ := strconv.Atoi(m[1])
+		date, err := dateOn(year, m[2], m[3], today)
+		if err != nil {
+			return time.Time{}, text, err
+		}
+		if date.After(today) {
+			return time.Time{}, text, fmt.Errorf("不能记录未来日期的账: %s", m[0])
+		}
+		return date, trimDate(text, m[0]), nil
+	}
+
+	// "3月100元" 中的 "3月10" 不是日期
+	if m := monthDayPattern.FindStringSubmatch(text); m != nil && !followedByDigit(text, m[0]) {
+		date, err := dateOn(today.Year(), m[1], m[2], today)
+		if err != nil {
+			return time.Time{}, text, err
+		}
+		if date.After(today) {
+			date = date.AddDate(-1, 0, 0)
+		}
+		return date, trimDate(text, m[0]), nil
+	}
+
+	return time.Time{}, text, nil
+}
+
+// 构造指定年月日、时刻与 now 相同的时间，日期不存在时返回错误
+func dateOn(year int, monthText string, dayText string, now time.Time) (time.Time, error) {
+	month, _ := strconv.Atoi(monthText)
+	day, _ := strconv.Atoi(dayText)
+	date := time.Date(year, time.Month(month), day, now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, fmt.Errorf("无效的日期: %d-%s-%s", year, monthText, dayText)
+	}
+	return date, nil
+}
+
+// 日期表达式后紧跟数字且没有以日/号结尾时，说明数字是金额的一部分
+func followedByDigit(text string, expr string) bool {
+	if strings.HasSuffix(expr, "日") || strings.HasSuffix(expr, "号") {
+		return false
+	}
+	rest := text[len(expr):]
+	return rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// 去掉日期表达式以及紧随其后的分隔符
+func trimDate(text string, expr string) string {
+	return strings.TrimLeft(strings.TrimPrefix(text, expr), " :：,，")
+}
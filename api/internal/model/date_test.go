@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractDate(t *testing.T) {
+	// 2026-10-14 周三 20:30
+	now := time.Date(2026, 10, 14, 20, 30, 0, 0, time.Local)
+	// 2026-01-05 周一 09:00，用于跨年
+	newYear := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+	on := func(base time.Time, year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, base.Hour(), base.Minute(), 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		input string
+		now   time.Time
+		want  time.Time
+		rest  string
+		err   bool
+	}{
+		// 相对日期
+		{"今天 午饭25", now, on(now, 2026, 10, 14), "午饭25", false},
+		{"昨天 打车-30", now, on(now, 2026, 10, 13), "打车-30", false},
+		{"前天午饭25", now, on(now, 2026, 10, 12), "午饭25", false},
+		{"大前天 电影60", now, on(now, 2026, 10, 11), "电影60", false},
+		{"昨天 跨年", newYear, on(newYear, 2026, 1, 4), "跨年", false},
+
+		// 周X：本周内不晚于今天，今天之后的取上周
+		{"周一 咖啡12", now, on(now, 2026, 10, 12), "咖啡12", false},
+		{"星期三 午饭", now, on(now, 2026, 10, 14), "午饭", false},
+		{"礼拜五 电影60", now, on(now, 2026, 10, 9), "电影60", false},
+		{"周日 买菜", now, on(now, 2026, 10, 11), "买菜", false},
+
+		// 上周X：上一个自然周
+		{"上周一 房租", now, on(now, 2026, 10, 5), "房租", false},
+		{"上周五 电影", now, on(now, 2026, 10, 9), "电影", false},
+		{"上星期日 聚餐", now, on(now, 2026, 10, 11), "聚餐", false},
+		{"上周三 跨年", newYear, on(newYear, 2025, 12, 31), "跨年", false},
+
+		// 月日
+		{"3/14 打车30", now, on(now, 2026, 3, 14), "打车30", false},
+		{"3月14日 打车30", now, on(now, 2026, 3, 14), "打车30", false},
+		{"10月14号：午饭", now, on(now, 2026, 10, 14), "午饭", false},
+		{"10/15 午饭", now, on(now, 2025, 10, 15), "午饭", false},
+		{"12/30 年夜饭", newYear, on(newYear, 2025, 12, 30), "年夜饭", false},
+		{"12月31日 跨年", newYear, on(newYear, 2025, 12, 31), "跨年", false},
+		{"2/30 午饭", now, time.Time{}, "2/30 午饭", true},
+
+		// 完整日期
+		{"2026-10-01 机票-1200", now, on(now, 2026, 10, 1), "机票-1200", false},
+		{"2025/12/31 跨年", newYear, on(newYear, 2025, 12, 31), "跨年", false},
+		{"2026年2月3日 午饭", now, on(now, 2026, 2, 3), "午饭", false},
+		{"2026-10-15 午饭", now, time.Time{}, "2026-10-15 午饭", true},
+		{"2026-02-29 午饭", now, time.Time{}, "2026-02-29 午饭", true},
+
+		// 不是日期
+		{"3月100元", now, time.Time{}, "3月100元", false},
+		{"午饭 35", now, time.Time{}, "午饭 35", false},
+	}
+	for _, tt := range tests {
+		got, rest, err := ExtractDate(tt.input, tt.now)
+		if (err != nil) != tt.err {
+			t.Errorf("ExtractDate(%q) error = %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if !got.Equal(tt.want) || rest != tt.rest {
+			t.Errorf("ExtractDate(%q) = %s, %q, want %s, %q", tt.input,
+				got.Format("2006-01-02 15:04"), rest, tt.want.Format("2006-01-02 15:04"), tt.rest)
+		}
+	}
+}
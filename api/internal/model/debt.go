@@ -0,0 +1,104 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 借款状态
+const (
+	DebtPending   = "pending"   // 等待对方确认
+	DebtConfirmed = "confirmed" // 对方已确认，未结清
+	DebtRejected  = "rejected"  // 对方拒绝
+	DebtSettled   = "settled"   // 已结清
+)
+
+var debtStatusNames = map[string]string{
+	DebtPending:   "待确认",
+	DebtConfirmed: "未结清",
+	DebtRejected:  "已拒绝",
+	DebtSettled:   "已结清",
+}
+
+// DebtStatusName 返回借款状态的中文名称
+func DebtStatusName(status string) string {
+	if name, ok := debtStatusNames[status]; ok {
+		return name
+	}
+	return status
+}
+
+// DebtParty 借款的一方
+// 通过 @用户名 指定的用户在第一次操作前不知道 UserID，此时按用户名识别
+type DebtParty struct {
+	UserID   int64  `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"` // 不含 @
+	Name     string `json:"name,omitempty"`
+}
+
+// Is 判断是否为同一用户
+func (p *DebtParty) Is(userID int64, username string) bool {
+	if p.UserID != 0 {
+		return p.UserID == userID
+	}
+	return p.Username != "" && strings.EqualFold(p.Username, username)
+}
+
+// Key 用于按人汇总的标识
+func (p *DebtParty) Key() string {
+	if p.UserID != 0 {
+		return strconv.FormatInt(p.UserID, 10)
+	}
+	return "@" + strings.ToLower(p.Username)
+}
+
+// Display 展示名称，有用户名时可以 @ 到对方
+func (p *DebtParty) Display() string {
+	if p.Username != "" {
+		return "@" + p.Username
+	}
+	if p.Name != "" {
+		return p.Name
+	}
+	return strconv.FormatInt(p.UserID, 10)
+}
+
+// Debt 两人之间的一笔借款，与记账周期无关
+type Debt struct {
+	ID          string    `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	Debtor      DebtParty `json:"debtor"`   // 欠钱的一方
+	Creditor    DebtParty `json:"creditor"` // 借出的一方
+	Amount      Money     `json:"amount"`   // 正数
+	Currency    string    `json:"currency,omitempty"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	CreatedBy   int64     `json:"created_by"`          // 记录人，需要另一方确认
+	SettleBy    int64     `json:"settle_by,omitempty"` // 申请结清的人，需要另一方确认，0 表示没有申请
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Involves 判断用户是否为借款的一方
+func (d *Debt) Involves(userID int64, username string) bool {
+	return d.Debtor.Is(userID, username) || d.Creditor.Is(userID, username)
+}
+
+// Counterparty 返回相对于用户的另一方
+func (d *Debt) Counterparty(userID int64, username string) *DebtParty {
+	if d.Debtor.Is(userID, username) {
+		return &d.Creditor
+	}
+	return &d.Debtor
+}
+
+// Bind 用户第一次操作时补全通过用户名指定的一方的 UserID
+func (d *Debt) Bind(userID int64, username string, name string) {
+	for _, party := range []*DebtParty{&d.Debtor, &d.Creditor} {
+		if party.UserID == 0 && party.Is(userID, username) {
+			party.UserID = userID
+			party.Name = name
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 账单摘要的发送位置
+const (
+	DigestTargetDM    = "dm"    // 私聊发送给用户
+	DigestTargetGroup = "group" // 发送到开启摘要的群组
+)
+
+// DigestSetting 用户订阅的每日或每周账单摘要
+type DigestSetting struct {
+	ChatID    int64     `json:"chat_id"`
+	UserID    int64     `json:"user_id"`
+	Frequency string    `json:"frequency"` // FrequencyDaily 或 FrequencyWeekly
+	Weekday   int       `json:"weekday"`   // 每周摘要的发送日，1-7（周一到周日）
+	Hour      int       `json:"hour"`
+	Minute    int       `json:"minute"`
+	Target    string    `json:"target"`    // DigestTargetDM 或 DigestTargetGroup
+	LastSent  time.Time `json:"last_sent"` // 上次发送时间
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ParseClock 解析 "21:00"、"21"、"21点"、"9点30" 形式的时间
+func ParseClock(s string) (int, int, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "分")
+	s = strings.NewReplacer("：", ":", "点", ":").Replace(s)
+	parts := strings.SplitN(s, ":", 2)
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("无效的时间: %s", s)
+	}
+	minute := 0
+	if len(parts) == 2 && parts[1] != "" {
+		minute, err = strconv.Atoi(parts[1])
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, 0, fmt.Errorf("无效的时间: %s", s)
+		}
+	}
+	return hour, minute, nil
+}
+
+// ParseWeekday 解析 "周日"、"星期五"、"5" 形式的星期，返回 1-7（周一到周日）
+func ParseWeekday(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if day, err := strconv.Atoi(s); err == nil {
+		return day, day >= 1 && day <= 7
+	}
+	for _, prefix := range []string{"周", "星期", "礼拜"} {
+		if rest := strings.TrimPrefix(s, prefix); rest != s {
+			day, ok := weekdayNumbers[rest]
+			return day, ok
+		}
+	}
+	return 0, false
+}
+
+// ScheduledAt 返回 now 所在周期（当天或当周）的计划发送时间，可能晚于 now
+func (d *DigestSetting) ScheduledAt(now time.Time) time.Time {
+	at := time.Date(now.Year(), now.Month(), now.Day(), d.Hour, d.Minute, 0, 0, now.Location())
+	if d.Frequency == FrequencyWeekly {
+		at = at.AddDate(0, 0, d.Weekday-isoWeekday(now))
+	}
+	return at
+}
+
+// Due 本周期的发送时间已到且尚未发送
+// 只看当前周期，机器人停机错过的摘要不会在之后的周期补发
+func (d *DigestSetting) Due(now time.Time) bool {
+	at := d.ScheduledAt(now)
+	return !now.Before(at) && d.LastSent.Before(at)
+}
+
+// Period 返回 now 所在的统计区间 [start, end)，每日为当天，每周为周一开始的自然周
+func (d *DigestSetting) Period(now time.Time) (time.Time, time.Time) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if d.Frequency == FrequencyWeekly {
+		start = start.AddDate(0, 0, 1-isoWeekday(now))
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Name 摘要名称，例如 "每日摘要"
+func (d *DigestSetting) Name() string {
+	if d.Frequency == FrequencyWeekly {
+		return "每周摘要"
+	}
+	return "每日摘要"
+}
+
+// Schedule 返回发送时间的中文描述，例如 "每天 21:00"、"每周日 20:00"
+func (d *DigestSetting) Schedule() string {
+	clock := fmt.Sprintf("%02d:%02d", d.Hour, d.Minute)
+	if d.Frequency == FrequencyWeekly {
+		return fmt.Sprintf("每周%s %s", weekdayNames[d.Weekday%7], clock)
+	}
+	return "每天 " + clock
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// 按平均每月天数换算月数
+const daysPerMonth = 365.25 / 12
+
+// SavingsGoal 储蓄目标，例如在 2027-02-01 前存够 10000 元旅行基金
+type SavingsGoal struct {
+	ID            string              `json:"id"`
+	ChatID        int64               `json:"chat_id"`
+	UserID        int64               `json:"user_id"`
+	Name          string              `json:"name"`
+	Target        Money               `json:"target"`             // 目标金额
+	Saved         Money               `json:"saved"`              // 已存入金额
+	Currency      string              `json:"currency"`           // 币种，与存入的记账周期本位币一致
+	Deadline      time.Time           `json:"deadline,omitempty"` // 截止日期，为空表示不限
+	Contributions []*GoalContribution `json:"contributions"`      // 存入记录
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// GoalContribution 一次存入
+type GoalContribution struct {
+	Amount  Money     `json:"amount"`
+	CycleID string    `json:"cycle_id,omitempty"` // 来源记账周期，手动存入时为空
+	Date    time.Time `json:"date"`
+}
+
+// Remaining 距离目标还差的金额
+func (g *SavingsGoal) Remaining() Money {
+	if g.Saved >= g.Target {
+		return 0
+	}
+	return g.Target - g.Saved
+}
+
+// Progress 完成百分比
+func (g *SavingsGoal) Progress() float64 {
+	if g.Target <= 0 {
+		return 100
+	}
+	return math.Min(float64(g.Saved)*100/float64(g.Target), 100)
+}
+
+// MonthlyRequired 在截止日期前完成目标每月需要存入的金额
+// 没有截止日期或已完成时返回 0，截止日期不足一个月时按一个月计算
+func (g *SavingsGoal) MonthlyRequired(now time.Time) Money {
+	if g.Deadline.IsZero() || g.Remaining() == 0 {
+		return 0
+	}
+	months := math.Max(g.Deadline.Sub(now).Hours()/24/daysPerMonth, 1)
+	return Money(math.Ceil(float64(g.Remaining()) / months))
+}
+
+// ProjectedCompletion 按创建以来的平均存入速度推算的完成日期
+// 还没有存入时无法推算，返回零值；已完成时返回最后一次存入的时间
+func (g *SavingsGoal) ProjectedCompletion(now time.Time) time.Time {
+	if g.Remaining() == 0 {
+		if n := len(g.Contributions); n > 0 {
+			return g.Contributions[n-1].Date
+		}
+		return now
+	}
+	if g.Saved <= 0 {
+		return time.Time{}
+	}
+	// 创建不足一个月时按一个月计算，避免刚存入一笔就推算出过快的速度
+	elapsed := math.Max(now.Sub(g.CreatedAt).Hours()/24/daysPerMonth, 1)
+	perMonth := float64(g.Saved) / elapsed
+	days := float64(g.Remaining()) / perMonth * daysPerMonth
+	return now.Add(time.Duration(days * 24 * float64(time.Hour)))
+}
@@ -0,0 +1,184 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以分（最小货币单位）为单位的整数，避免浮点累加误差
+//
+// 取整规则：
+//  1. 用户输入最多允许两位小数，多于两位直接报错，不做静默取整
+//  2. 运算（按比例折算、平均等）以及旧数据中的浮点金额一律四舍五入到分（远离零方向）
+type Money int64
+
+const (
+	// MoneyScale 1 元对应的分数
+	MoneyScale = 100
+	// MaxMoney 单笔金额上限（十亿元），防止溢出和误输入
+	MaxMoney Money = 1000000000 * MoneyScale
+)
+
+// ParseMoney 严格解析十进制金额字符串，例如 "12"、"-3.5"、"+0.01"
+// 不接受科学计数法、NaN、Inf、千分位以及超过两位的小数
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("金额不能为空")
+	}
+
+	negative := false
+	switch s[0] {
+	case '+':
+		s = s[1:]
+	case '-':
+		negative = true
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+		if fracPart == "" {
+			return 0, fmt.Errorf("无效的金额: %s", s)
+		}
+	}
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("无效的金额: %s", s)
+	}
+	if len(fracPart) > 2 {
+		return 0, fmt.Errorf("金额最多保留两位小数: %s", s)
+	}
+	// 去掉前导零后超过 10 位整数必然超过上限，提前拒绝避免溢出
+	if len(strings.TrimLeft(intPart, "0")) > 10 {
+		return 0, fmt.Errorf("金额超出范围: %s", s)
+	}
+
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的金额: %s", s)
+	}
+	var cents int64
+	if fracPart != "" {
+		cents, _ = strconv.ParseInt((fracPart + "0")[:2], 10, 64)
+	}
+
+	m := Money(yuan*MoneyScale + cents)
+	if m > MaxMoney {
+		return 0, fmt.Errorf("金额超出范围: %s", s)
+	}
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// MoneyFromFloat 将浮点金额四舍五入到分，仅用于迁移旧数据
+func MoneyFromFloat(f float64) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("无效的金额: %v", f)
+	}
+	if math.Abs(f) > float64(MaxMoney/MoneyScale) {
+		return 0, fmt.Errorf("金额超出范围: %v", f)
+	}
+	return Money(math.Round(f * MoneyScale)), nil
+}
+
+// Abs 返回金额的绝对值
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// MulDiv 计算 m*num/den，结果四舍五入到分
+func (m Money) MulDiv(num, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+	if den < 0 {
+		num, den = -num, -den
+	}
+	p := int64(m) * num
+	q, r := p/den, p%den
+	// 余数超过一半时远离零方向进位
+	if 2*abs64(r) >= den {
+		if p < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q)
+}
+
+// String 以 "-12.50" 的形式输出金额
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/MoneyScale, v%MoneyScale)
+}
+
+// MarshalJSON 以字符串形式保存金额，避免任何浮点解析
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON 同时兼容新的字符串格式和旧版本写入的浮点数字
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		*m = 0
+		return nil
+	}
+
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		*m = v
+		return nil
+	}
+
+	// 旧数据：float64 元
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("解析金额失败: %v", err)
+	}
+	v, err := MoneyFromFloat(f)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
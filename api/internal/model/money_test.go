@@ -0,0 +1,143 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		{"12", 1200, false},
+		{"12.5", 1250, false},
+		{"12.05", 1205, false},
+		{"+0.01", 1, false},
+		{"0.5", 50, false},
+		{"0.99", 99, false},
+		{"-3.5", -350, false},
+		{"-0.01", -1, false},
+		{" 8 ", 800, false},
+		{"1000000000", MaxMoney, false},
+		{"1000000000.01", 0, true},
+		{"99999999999", 0, true},
+		{"12.345", 0, true},
+		{"12.", 0, true},
+		{".5", 0, true},
+		{"1e3", 0, true},
+		{"1,000", 0, true},
+		{"NaN", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseMoney(%q) error = %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		input Money
+		want  string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{50, "0.50"},
+		{99, "0.99"},
+		{1250, "12.50"},
+		{-1, "-0.01"},
+		{-50, "-0.50"},
+		{-350, "-3.50"},
+		{MaxMoney, "1000000000.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.input.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.input), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		input float64
+		want  Money
+	}{
+		{12.5, 1250},
+		{0.1 + 0.2, 30},
+		{0.005, 1},
+		{0.004, 0},
+		{-0.005, -1},
+		{-12.345, -1235},
+		{19.99, 1999},
+	}
+	for _, tt := range tests {
+		got, err := MoneyFromFloat(tt.input)
+		if err != nil {
+			t.Errorf("MoneyFromFloat(%v) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MoneyFromFloat(%v) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulDiv(t *testing.T) {
+	tests := []struct {
+		m        Money
+		num, den int64
+		want     Money
+	}{
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{-1000, 2, 3, -667},
+		{5, 1, 2, 3},
+		{-5, 1, 2, -3},
+		{1000, 1, -3, -333},
+		{1000, 1, 0, 0},
+		{10000, 7200000, RateScale, 72000},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulDiv(tt.num, tt.den); got != tt.want {
+			t.Errorf("Money(%d).MulDiv(%d, %d) = %d, want %d", int64(tt.m), tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+	}{
+		{`"12.50"`, 1250},
+		{`"-0.01"`, -1},
+		{`12.5`, 1250},
+		{`0.1`, 10},
+		{`-3.456`, -346},
+		{`null`, 0},
+	}
+	for _, tt := range tests {
+		var got Money
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Errorf("Unmarshal(%s) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+
+	data, err := json.Marshal(Money(-50))
+	if err != nil || string(data) != `"-0.50"` {
+		t.Errorf("Marshal(-50) = %s, %v, want \"-0.50\"", data, err)
+	}
+}
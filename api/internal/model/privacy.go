@@ -0,0 +1,24 @@
+package model
+
+// 账本的查看方式
+const (
+	PrivacyGroup = "group" // 在发起命令的聊天中显示（默认）
+	PrivacyDM    = "dm"    // 摘要、历史和明细通过私聊发送
+)
+
+// PrivacyName 返回查看方式的中文说明
+func PrivacyName(mode string) string {
+	if mode == PrivacyDM {
+		return "私聊发送"
+	}
+	return "在群里显示"
+}
+
+// LedgerShare 账本共享，被共享的用户可以查看所有者在该群的历史记账周期
+type LedgerShare struct {
+	ChatID     int64  `json:"chat_id"`
+	OwnerID    int64  `json:"owner_id"`
+	OwnerName  string `json:"owner_name"`
+	ViewerID   int64  `json:"viewer_id"`
+	ViewerName string `json:"viewer_name"`
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 周期性记账的频率
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// RecurringHour 周期性记账在到期日的入账时间（几点）
+const RecurringHour = 9
+
+var frequencyAliases = map[string]string{
+	"daily":   FrequencyDaily,
+	"每天":      FrequencyDaily,
+	"每日":      FrequencyDaily,
+	"weekly":  FrequencyWeekly,
+	"每周":      FrequencyWeekly,
+	"monthly": FrequencyMonthly,
+	"每月":      FrequencyMonthly,
+}
+
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// RecurringRule 周期性记账规则，例如每月 5 日的房租
+type RecurringRule struct {
+	ID          string    `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	UserID      int64     `json:"user_id"`
+	Amount      Money     `json:"amount"`             // 正数为收入，负数为支出
+	Currency    string    `json:"currency,omitempty"` // 为空表示周期本位币
+	Description string    `json:"description"`
+	Frequency   string    `json:"frequency"`
+	Day         int       `json:"day"`      // 每周为 1-7（周一到周日），每月为 1-31
+	Paused      bool      `json:"paused"`   // 暂停后不再自动入账
+	NextRun     time.Time `json:"next_run"` // 下次入账时间
+	LastRun     time.Time `json:"last_run"` // 上次入账时间
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NormalizeFrequency 将 "monthly"、"每月" 等写法统一为频率常量
+func NormalizeFrequency(s string) (string, bool) {
+	frequency, ok := frequencyAliases[strings.ToLower(strings.TrimSpace(s))]
+	return frequency, ok
+}
+
+// ValidateDay 检查规则的日期并在未指定时取 now 对应的默认值
+func (r *RecurringRule) ValidateDay(now time.Time) error {
+	switch r.Frequency {
+	case FrequencyDaily:
+		r.Day = 0
+	case FrequencyWeekly:
+		if r.Day == 0 {
+			r.Day = isoWeekday(now)
+		}
+		if r.Day < 1 || r.Day > 7 {
+			return fmt.Errorf("每周的日期应为 1-7（周一到周日）")
+		}
+	case FrequencyMonthly:
+		if r.Day == 0 {
+			r.Day = now.Day()
+		}
+		if r.Day < 1 || r.Day > 31 {
+			return fmt.Errorf("每月的日期应为 1-31")
+		}
+	default:
+		return fmt.Errorf("不支持的频率: %s", r.Frequency)
+	}
+	return nil
+}
+
+// NextAfter 返回严格晚于 t 的下一次入账时间
+// 每月规则遇到较短的月份时在月末入账，例如 31 日的规则在 2 月按 28/29 日入账
+func (r *RecurringRule) NextAfter(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), RecurringHour, 0, 0, 0, t.Location())
+	switch r.Frequency {
+	case FrequencyWeekly:
+		day = day.AddDate(0, 0, (r.Day-isoWeekday(day)+7)%7)
+		if !day.After(t) {
+			day = day.AddDate(0, 0, 7)
+		}
+	case FrequencyMonthly:
+		for i := 0; ; i++ {
+			first := time.Date(t.Year(), t.Month()+time.Month(i), 1, RecurringHour, 0, 0, 0, t.Location())
+			last := first.AddDate(0, 1, -1).Day()
+			candidate := first.AddDate(0, 0, min(r.Day, last)-1)
+			if candidate.After(t) {
+				return candidate
+			}
+		}
+	default:
+		if !day.After(t) {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	return day
+}
+
+// Schedule 返回规则的中文描述，例如 "每月5日"、"每周五"
+func (r *RecurringRule) Schedule() string {
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return "每周" + weekdayNames[r.Day%7]
+	case FrequencyMonthly:
+		return fmt.Sprintf("每月%d日", r.Day)
+	}
+	return "每天"
+}
+
+// 周一为 1，周日为 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 报销状态
+const (
+	ReimbursePending   = "pending"    // 待报销
+	ReimburseSubmitted = "submitted"  // 已提交
+	ReimbursePaid      = "reimbursed" // 已报销（款项已到账）
+)
+
+// Reimbursement 支出记录的报销信息
+type Reimbursement struct {
+	Status         string    `json:"status"`                     // 报销状态
+	Payer          string    `json:"payer,omitempty"`            // 报销方，例如 "公司"
+	IncomeRecordID string    `json:"income_record_id,omitempty"` // 报销到账时生成的收入记录
+	UpdatedAt      time.Time `json:"updated_at"`                 // 状态更新时间
+}
+
+// ReimbursementStatusName 返回报销状态的中文名称
+func ReimbursementStatusName(status string) string {
+	switch status {
+	case ReimbursePending:
+		return "待报销"
+	case ReimburseSubmitted:
+		return "已提交"
+	case ReimbursePaid:
+		return "已报销"
+	}
+	return status
+}
+
+// ParseReimbursement 从备注中识别报销信息，例如 "未报销"、"公司报销"、"已提交报销"
+// 备注中不含"报销"时返回 nil
+func ParseReimbursement(note string) *Reimbursement {
+	if !strings.Contains(note, "报销") {
+		return nil
+	}
+
+	status := ReimbursePending
+	switch {
+	case strings.Contains(note, "已报销") || strings.Contains(note, "已到账"):
+		status = ReimbursePaid
+	case strings.Contains(note, "已提交") || strings.Contains(note, "已申请"):
+		status = ReimburseSubmitted
+	}
+
+	payer := strings.NewReplacer("未", "", "待", "", "已", "", "提交", "", "申请", "", "报销", "", "到账", "").Replace(note)
+	return &Reimbursement{
+		Status:    status,
+		Payer:     strings.TrimSpace(payer),
+		UpdatedAt: time.Now(),
+	}
+}
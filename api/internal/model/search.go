@@ -0,0 +1,268 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 搜索的收支类型
+const (
+	SearchKindIncome  = "income"
+	SearchKindExpense = "expense"
+)
+
+var searchKindAliases = map[string]string{
+	"income":  SearchKindIncome,
+	"收入":      SearchKindIncome,
+	"+":       SearchKindIncome,
+	"expense": SearchKindExpense,
+	"支出":      SearchKindExpense,
+	"-":       SearchKindExpense,
+}
+
+// 日期范围的分隔符
+var dateRangeSeparators = []string{"~", "～", "至", ".."}
+
+// AccountingSearchQuery 记账记录的搜索条件，零值字段表示不限
+type AccountingSearchQuery struct {
+	ID        string    `json:"id"`
+	ChatID    int64     `json:"chat_id"`    // 账本所在的聊天，结果私聊发送时翻页仍搜索原聊天
+	UserID    int64     `json:"user_id"`    // 发起搜索的用户，翻页时校验
+	Keywords  []string  `json:"keywords"`   // 描述中需要同时包含的关键词，不区分大小写
+	MinAmount Money     `json:"min_amount"` // 金额下限（含，按绝对值比较）
+	MaxAmount Money     `json:"max_amount"` // 金额上限（含，按绝对值比较），HasMax 为 false 时不限
+	HasMax    bool      `json:"has_max"`    // 是否设置了金额上限，上限可以为 0
+	From      time.Time `json:"from"`       // 开始日期（含）
+	To        time.Time `json:"to"`         // 结束日期（含）
+	Category  string    `json:"category"`   // 分类
+	Kind      string    `json:"kind"`       // SearchKindIncome 或 SearchKindExpense
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ParseSearchQuery 解析 /accounting_search 的参数，各条件以空格分隔、顺序不限:
+//
+//	收入 | 支出           收支类型
+//	餐饮                  分类（见 Categories）
+//	>100 <500 100-500 50  金额范围或精确金额，支持 2k、三十五块等写法；-50、+50 同时限定支出或收入
+//	2026-09-01~2026-09-30 日期范围，两端支持 ExtractDate 的所有写法，例如 9/1~9/30、上周一~昨天
+//	昨天                  单个日期
+//	其他文本              描述关键词
+func ParseSearchQuery(args string, now time.Time) (*AccountingSearchQuery, error) {
+	query := &AccountingSearchQuery{}
+	for _, field := range strings.Fields(args) {
+		if kind, ok := searchKindAliases[strings.ToLower(field)]; ok {
+			query.Kind = kind
+			continue
+		}
+		if isCategory(field) {
+			query.Category = field
+			continue
+		}
+
+		matched, err := query.parseAmountCondition(field)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			continue
+		}
+		matched, err = query.parseDateCondition(field, now)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			continue
+		}
+		query.Keywords = append(query.Keywords, field)
+	}
+
+	if query.HasMax && query.MinAmount > query.MaxAmount {
+		return nil, fmt.Errorf("金额下限不能大于上限")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, fmt.Errorf("结束日期不能早于开始日期")
+	}
+	return query, nil
+}
+
+// 解析 >100、<500、>=100、<=500、100-500 和单个金额
+func (q *AccountingSearchQuery) parseAmountCondition(field string) (bool, error) {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		rest := strings.TrimPrefix(field, op)
+		if rest == field {
+			continue
+		}
+		amount, err := ParseAmount(rest)
+		if err != nil || amount < 0 || (op == "<" && amount == 0) {
+			return false, fmt.Errorf("无效的金额条件: %s", field)
+		}
+		switch op {
+		case ">=":
+			q.MinAmount = amount
+		case ">":
+			q.MinAmount = amount + 1
+		case "<=":
+			q.MaxAmount, q.HasMax = amount, true
+		case "<":
+			q.MaxAmount, q.HasMax = amount-1, true
+		}
+		return true, nil
+	}
+
+	// 带符号的单个金额按绝对值匹配，符号决定收支类型
+	if amount, err := ParseAmount(field); err == nil {
+		if sign, _ := signAt([]rune(field), 0); sign < 0 {
+			q.Kind, amount = SearchKindExpense, -amount
+		} else if sign > 0 {
+			q.Kind = SearchKindIncome
+		}
+		q.MinAmount, q.MaxAmount, q.HasMax = amount, amount, true
+		return true, nil
+	}
+	if i := strings.Index(field[1:], "-"); i >= 0 {
+		low, err1 := ParseAmount(field[:i+1])
+		high, err2 := ParseAmount(field[i+2:])
+		if err1 == nil && err2 == nil {
+			q.MinAmount, q.MaxAmount, q.HasMax = low, high, true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// 解析单个日期或日期范围，范围两端可以省略其一
+func (q *AccountingSearchQuery) parseDateCondition(field string, now time.Time) (bool, error) {
+	for _, sep := range dateRangeSeparators {
+		i := strings.Index(field, sep)
+		if i < 0 {
+			continue
+		}
+		from, ok, err := parseSearchDate(field[:i], now)
+		if err != nil || (!ok && field[:i] != "") {
+			return false, fmt.Errorf("无效的日期范围: %s", field)
+		}
+		to, ok, err := parseSearchDate(field[i+len(sep):], now)
+		if err != nil || (!ok && field[i+len(sep):] != "") {
+			return false, fmt.Errorf("无效的日期范围: %s", field)
+		}
+		q.From, q.To = from, to
+		return true, nil
+	}
+
+	date, ok, err := parseSearchDate(field, now)
+	if err != nil || !ok {
+		return false, err
+	}
+	q.From, q.To = date, date
+	return true, nil
+}
+
+// 整个文本都是日期表达式时返回当天零点
+func parseSearchDate(text string, now time.Time) (time.Time, bool, error) {
+	if text == "" {
+		return time.Time{}, false, nil
+	}
+	date, rest, err := ExtractDate(text, now)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if date.IsZero() || rest != "" {
+		return time.Time{}, false, nil
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()), true, nil
+}
+
+// Match 判断记录是否符合搜索条件
+func (q *AccountingSearchQuery) Match(record *AccountingRecord) bool {
+	switch q.Kind {
+	case SearchKindIncome:
+		if record.Amount <= 0 {
+			return false
+		}
+	case SearchKindExpense:
+		if record.Amount > 0 {
+			return false
+		}
+	}
+
+	amount := record.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	if amount < q.MinAmount || (q.HasMax && amount > q.MaxAmount) {
+		return false
+	}
+
+	if !q.From.IsZero() && record.Date.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !record.Date.Before(q.To.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	if q.Category != "" && record.CategoryOf() != q.Category {
+		return false
+	}
+
+	description := strings.ToLower(record.Description)
+	for _, keyword := range q.Keywords {
+		if !strings.Contains(description, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe 返回搜索条件的中文描述，没有任何条件时返回 "全部记录"
+func (q *AccountingSearchQuery) Describe() string {
+	var parts []string
+	if len(q.Keywords) > 0 {
+		parts = append(parts, "关键词 "+strings.Join(q.Keywords, " "))
+	}
+	switch q.Kind {
+	case SearchKindIncome:
+		parts = append(parts, "收入")
+	case SearchKindExpense:
+		parts = append(parts, "支出")
+	}
+	if q.Category != "" {
+		parts = append(parts, "分类 "+q.Category)
+	}
+	switch {
+	case q.HasMax && q.MinAmount == q.MaxAmount:
+		parts = append(parts, fmt.Sprintf("金额 %s", q.MinAmount))
+	case q.HasMax && q.MinAmount != 0:
+		parts = append(parts, fmt.Sprintf("金额 %s-%s", q.MinAmount, q.MaxAmount))
+	case q.MinAmount != 0:
+		parts = append(parts, fmt.Sprintf("金额 ≥%s", q.MinAmount))
+	case q.HasMax:
+		parts = append(parts, fmt.Sprintf("金额 ≤%s", q.MaxAmount))
+	}
+	switch {
+	case !q.From.IsZero() && !q.To.IsZero() && q.From.Equal(q.To):
+		parts = append(parts, q.From.Format("2006-01-02"))
+	case !q.From.IsZero() || !q.To.IsZero():
+		from, to := "", ""
+		if !q.From.IsZero() {
+			from = q.From.Format("2006-01-02")
+		}
+		if !q.To.IsZero() {
+			to = q.To.Format("2006-01-02")
+		}
+		parts = append(parts, fmt.Sprintf("%s~%s", from, to))
+	}
+	if len(parts) == 0 {
+		return "全部记录"
+	}
+	return strings.Join(parts, "，")
+}
+
+func isCategory(s string) bool {
+	for _, category := range Categories {
+		if s == category {
+			return true
+		}
+	}
+	return false
+}
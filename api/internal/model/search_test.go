@@ -0,0 +1,121 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	// 2026-10-14 周三 20:30
+	now := time.Date(2026, 10, 14, 20, 30, 0, 0, time.Local)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		input string
+		want  AccountingSearchQuery
+		err   bool
+	}{
+		{"", AccountingSearchQuery{}, false},
+		{"午饭 外卖", AccountingSearchQuery{Keywords: []string{"午饭", "外卖"}}, false},
+		{"支出 餐饮", AccountingSearchQuery{Kind: SearchKindExpense, Category: CategoryFood}, false},
+		{"收入", AccountingSearchQuery{Kind: SearchKindIncome}, false},
+
+		// 金额
+		{">100", AccountingSearchQuery{MinAmount: 10001}, false},
+		{">=100 <=500", AccountingSearchQuery{MinAmount: 10000, MaxAmount: 50000, HasMax: true}, false},
+		{"<500", AccountingSearchQuery{MaxAmount: 49999, HasMax: true}, false},
+		{"<0.01", AccountingSearchQuery{MaxAmount: 0, HasMax: true}, false},
+		{"<=0", AccountingSearchQuery{MaxAmount: 0, HasMax: true}, false},
+		{"100-500", AccountingSearchQuery{MinAmount: 10000, MaxAmount: 50000, HasMax: true}, false},
+		{"50", AccountingSearchQuery{MinAmount: 5000, MaxAmount: 5000, HasMax: true}, false},
+		{"2k", AccountingSearchQuery{MinAmount: 200000, MaxAmount: 200000, HasMax: true}, false},
+		{"-50", AccountingSearchQuery{Kind: SearchKindExpense, MinAmount: 5000, MaxAmount: 5000, HasMax: true}, false},
+		{"+50", AccountingSearchQuery{Kind: SearchKindIncome, MinAmount: 5000, MaxAmount: 5000, HasMax: true}, false},
+		{"<0", AccountingSearchQuery{}, true},
+		{">abc", AccountingSearchQuery{}, true},
+		{">500 <100", AccountingSearchQuery{}, true},
+
+		// 日期
+		{"昨天", AccountingSearchQuery{From: day(10, 13), To: day(10, 13)}, false},
+		{"9/1~9/30", AccountingSearchQuery{From: day(9, 1), To: day(9, 30)}, false},
+		{"上周一~昨天", AccountingSearchQuery{From: day(10, 5), To: day(10, 13)}, false},
+		{"2026-10-01..", AccountingSearchQuery{From: day(10, 1)}, false},
+		{"至9/30", AccountingSearchQuery{To: day(9, 30)}, false},
+		{"9/30~9/1", AccountingSearchQuery{}, true},
+		{"9/1~下周", AccountingSearchQuery{}, true},
+
+		// 组合
+		{"打车 支出 >20 上周一~昨天", AccountingSearchQuery{
+			Keywords:  []string{"打车"},
+			Kind:      SearchKindExpense,
+			MinAmount: 2001,
+			From:      day(10, 5),
+			To:        day(10, 13),
+		}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSearchQuery(tt.input, now)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseSearchQuery(%q) error = %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", tt.input, *got, tt.want)
+		}
+	}
+}
+
+func TestSearchMatch(t *testing.T) {
+	now := time.Date(2026, 10, 14, 20, 30, 0, 0, time.Local)
+	records := []*AccountingRecord{
+		{Amount: -3500, Description: "午饭外卖", Date: time.Date(2026, 10, 13, 12, 0, 0, 0, time.Local)},
+		{Amount: -5000, Description: "打车", Date: time.Date(2026, 10, 12, 23, 59, 0, 0, time.Local)},
+		{Amount: 5000, Description: "红包", Date: time.Date(2026, 10, 10, 9, 0, 0, 0, time.Local)},
+		{Amount: 0, Description: "免单咖啡", Date: time.Date(2026, 10, 14, 8, 0, 0, 0, time.Local)},
+		{Amount: -120000, Description: "房租", Category: CategoryHousing, Date: time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)},
+	}
+
+	tests := []struct {
+		input string
+		want  []int // 匹配的记录下标
+	}{
+		{"", []int{0, 1, 2, 3, 4}},
+		{"外卖", []int{0}},
+		{"午饭外卖", []int{0}},
+		{"50", []int{1, 2}},
+		{"-50", []int{1}},
+		{"+50", []int{2}},
+		{"收入", []int{2}},
+		{"支出", []int{0, 1, 3, 4}},
+		{"<0.01", []int{3}},
+		{">=35 <=50", []int{0, 1, 2}},
+		{">50", []int{4}},
+		{"居住", []int{4}},
+		{"餐饮", []int{0, 3}},
+		{"10/12", []int{1}},
+		{"10/12~10/13", []int{0, 1}},
+		{"~10/10", []int{2, 4}},
+		{"支出 昨天~", []int{0, 3}},
+	}
+	for _, tt := range tests {
+		query, err := ParseSearchQuery(tt.input, now)
+		if err != nil {
+			t.Fatalf("ParseSearchQuery(%q) error = %v", tt.input, err)
+		}
+		var got []int
+		for i, record := range records {
+			if query.Match(record) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 钱包
+const (
+	WalletCash   = "cash"   // 现金
+	WalletCard   = "card"   // 银行卡
+	WalletAlipay = "alipay" // 支付宝
+	WalletWechat = "wechat" // 微信
+	WalletUSDT   = "usdt"   // USDT
+)
+
+// DefaultWallet 没有设置默认钱包时记录使用的钱包，升级前的记录也算作这个钱包
+const DefaultWallet = WalletCash
+
+// Wallets 全部钱包，按显示顺序排列
+var Wallets = []string{WalletCash, WalletCard, WalletAlipay, WalletWechat, WalletUSDT}
+
+var walletNames = map[string]string{
+	WalletCash:   "现金",
+	WalletCard:   "银行卡",
+	WalletAlipay: "支付宝",
+	WalletWechat: "微信",
+	WalletUSDT:   "USDT",
+}
+
+// walletAliases 钱包别名，键统一为小写
+var walletAliases = map[string]string{
+	"cash":   WalletCash,
+	"现金":     WalletCash,
+	"card":   WalletCard,
+	"bank":   WalletCard,
+	"银行卡":    WalletCard,
+	"卡":      WalletCard,
+	"alipay": WalletAlipay,
+	"zfb":    WalletAlipay,
+	"支付宝":    WalletAlipay,
+	"wechat": WalletWechat,
+	"wx":     WalletWechat,
+	"微信":     WalletWechat,
+	"usdt":   WalletUSDT,
+}
+
+// walletPattern 匹配记账文本中的钱包标记，例如 "午餐-25 #支付宝"
+var walletPattern = regexp.MustCompile(`(?:^|\s)[#＃](\S+)`)
+
+// NormalizeWallet 将钱包别名转换为标准名称
+func NormalizeWallet(s string) (string, bool) {
+	wallet, ok := walletAliases[strings.ToLower(strings.TrimSpace(s))]
+	return wallet, ok
+}
+
+// WalletName 返回钱包的中文名称
+func WalletName(wallet string) string {
+	if wallet == "" {
+		wallet = DefaultWallet
+	}
+	if name, ok := walletNames[wallet]; ok {
+		return name
+	}
+	return wallet
+}
+
+// WalletCurrency 钱包固定的币种，没有固定币种时返回空字符串
+func WalletCurrency(wallet string) string {
+	if wallet == WalletUSDT {
+		return "USDT"
+	}
+	return ""
+}
+
+// ExtractWallet 从记账文本中提取钱包标记，返回标准名称和去掉标记后的文本
+// 没有钱包标记或标记不是已知钱包时返回空字符串和原文本
+func ExtractWallet(text string) (string, string) {
+	for _, loc := range walletPattern.FindAllStringSubmatchIndex(text, -1) {
+		wallet, ok := NormalizeWallet(text[loc[2]:loc[3]])
+		if !ok {
+			continue
+		}
+		return wallet, strings.TrimSpace(text[:loc[0]] + " " + text[loc[1]:])
+	}
+	return "", text
+}
+
+// RecordWallet 返回记录所属的钱包
+func (r *AccountingRecord) RecordWallet() string {
+	if r.Wallet == "" {
+		return DefaultWallet
+	}
+	return r.Wallet
+}
+
+// WalletTransfer 钱包之间的转账，不计入收支
+// 跨币种转账时 ToAmount 和 ToCurrency 为转入钱包收到的金额，例如银行卡 3600 CNY 换成 500 USDT
+type WalletTransfer struct {
+	ID         string    `json:"id"`
+	From       string    `json:"from"`        // 转出钱包
+	To         string    `json:"to"`          // 转入钱包
+	Amount     Money     `json:"amount"`      // 转出金额
+	Currency   string    `json:"currency"`    // 转出币种
+	ToAmount   Money     `json:"to_amount"`   // 转入金额
+	ToCurrency string    `json:"to_currency"` // 转入币种
+	Note       string    `json:"note,omitempty"`
+	Time       time.Time `json:"time"`
+}
@@ -0,0 +1,26 @@
+// Package parser 解析用户输入的记账文本，所有记账入口（回复机器人、/recurring 等）共用同一套语法。
+//
+// 语法（空格可省略）：
+//
+//	消息     = [日期] 条目 { 分隔符 [日期] 条目 }
+//	条目     = 描述 金额 [钱包] [备注]
+//	         | 金额 描述 [钱包] [备注]
+//	分隔符   = "," | "，" | ";" | "；" | "。" | 换行
+//	日期     = 今天 | 昨天 | 前天 | 大前天 | 周X | 上周X | M/D | M月D日 | YYYY-MM-DD
+//	金额     = [符号] 数字 [单位] [币种]
+//	符号     = "+" | "-"
+//	数字     = 阿拉伯数字（可带 k/w/千/万）| 中文数字（三十五、一百二）
+//	单位     = 元 | 块 | 块钱 | 毛 | 角 | 分
+//	币种     = USD | $ | 美元 | USDT | 100U | HKD | 港币 | ...（可出现在条目任意位置）
+//	钱包     = "#" 钱包名，例如 #现金、#支付宝、#wechat（可出现在条目任意位置，前面需有空格或位于开头）
+//	备注     = "(" 文本 ")" | "（" 文本 "）"，只能位于条目末尾
+//
+// 正负号规则（所有入口一致）：
+//
+//   - 写了 "+" 为收入，写了 "-" 为支出，写明的符号优先于关键词
+//   - 没写符号时默认为支出，描述中含 "收入"、"进账" 时为收入
+//   - 描述开头的 "收入"、"进账"、"支出" 只表示方向，不计入描述（"收入 2k 红包" 的描述为 "红包"）
+//
+// 日期、数量后的数字不是金额，例如 "3月100元" 的金额是 100，"水果2斤 15" 的金额是 15。
+// 金额细节见 model.ParseAmount，日期细节见 model.ExtractDate，币种细节见 model.ExtractCurrency，钱包细节见 model.ExtractWallet。
+package parser
@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+var (
+	// ErrNoAmount 文本中没有金额
+	ErrNoAmount = errors.New("没有找到金额")
+	// ErrMultipleAmounts 一条记录中出现了多个金额
+	ErrMultipleAmounts = errors.New("一条记录只能包含一个金额")
+)
+
+// DefaultDescription 没有描述时使用的描述
+const DefaultDescription = "未知项目"
+
+// Usage 记账格式说明，解析失败时展示给用户
+const Usage = "支持的格式：\n" +
+	"1. 午餐-100 或 午餐 100（没写符号默认为支出）\n" +
+	"2. 100 午餐 或 -100 午餐\n" +
+	"3. 工资+5000（收入需要加+号）\n" +
+	"4. 买菜-10(未报销)（括号中为备注）\n" +
+	"5. 昨天 打车三十五块、奶茶五毛、房租2k（日期和口语金额）\n" +
+	"6. 午餐 -12 USD（外币）\n" +
+	"7. 午餐-25 #支付宝（钱包，可选 现金/银行卡/支付宝/微信/usdt）"
+
+// 分隔多条记账的字符
+var separators = []string{",", "，", ";", "；", "。", "\n"}
+
+// 表示收支方向的关键词
+var (
+	incomeKeywords  = []string{"收入", "进账"}
+	expenseKeywords = []string{"支出"}
+)
+
+// Entry 解析出的一条记账
+type Entry struct {
+	Amount      model.Money // 带符号的金额，正数为收入，负数为支出，原始币种
+	Sign        int         // 原文写明的符号：1 为 "+"，-1 为 "-"，未写为 0
+	Currency    string      // 币种，为空表示使用周期本位币
+	Wallet      string      // 钱包，为空表示使用默认钱包
+	Description string      // 描述，不含备注
+	Note        string      // 括号中的备注，不含括号
+	Date        time.Time   // 记录日期，未写日期时为零值
+	Category    string      // 按描述（不含备注）推断的分类
+	Text        string      // 条目原文
+}
+
+// Income 是否为收入
+func (e *Entry) Income() bool {
+	return e.Amount > 0
+}
+
+// Request 转换为记账请求，备注以 "描述(备注)" 的形式保留在描述中
+func (e *Entry) Request() *model.AccountingExpenseRequest {
+	description := e.Description
+	if e.Note != "" {
+		description = fmt.Sprintf("%s(%s)", description, e.Note)
+	}
+	return &model.AccountingExpenseRequest{
+		Amount:      e.Amount,
+		Currency:    e.Currency,
+		Wallet:      e.Wallet,
+		Description: description,
+		Date:        e.Date,
+		Category:    e.Category,
+	}
+}
+
+// ParseEntry 解析一条记账，文本中必须恰好包含一个金额
+func ParseEntry(text string, now time.Time) (*Entry, error) {
+	entries, err := parseSegment(text, time.Time{}, now, true)
+	if err != nil {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// ParseEntries 解析一条消息中的多条记账
+// 消息开头的日期对所有条目生效，条目也可以有自己的日期；
+// 同一片段中有多个金额时按金额拆分为多条，不含金额的片段会被忽略。
+// 整条消息都没有金额时返回 ErrNoAmount
+func ParseEntries(text string, now time.Time) ([]*Entry, error) {
+	date, text, err := model.ExtractDate(text, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, segment := range splitSegments(text) {
+		parsed, err := parseSegment(segment, date, now, false)
+		if errors.Is(err, ErrNoAmount) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parsed...)
+	}
+	if len(entries) == 0 {
+		return nil, ErrNoAmount
+	}
+	return entries, nil
+}
+
+// 按分隔符拆分消息
+func splitSegments(text string) []string {
+	for _, sep := range separators[1:] {
+		text = strings.ReplaceAll(text, sep, separators[0])
+	}
+	var segments []string
+	for _, segment := range strings.Split(text, separators[0]) {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// 解析一个片段，strict 为 true 时片段中只能有一个金额
+func parseSegment(text string, defaultDate time.Time, now time.Time, strict bool) ([]*Entry, error) {
+	date, rest, err := model.ExtractDate(text, now)
+	if err != nil {
+		return nil, err
+	}
+	if date.IsZero() {
+		date = defaultDate
+	}
+	// 先去掉钱包标记，避免 "#usdt" 被当成币种
+	wallet, rest := model.ExtractWallet(rest)
+	currency, rest := model.ExtractCurrency(rest)
+	body, note := model.SplitNote(rest)
+
+	tokens := model.FindAmounts(body)
+	for _, token := range tokens {
+		if token.Err != nil {
+			return nil, token.Err
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, ErrNoAmount
+	}
+	if len(tokens) > 1 && strict {
+		texts := make([]string, len(tokens))
+		for i, token := range tokens {
+			texts[i] = token.Text
+		}
+		return nil, fmt.Errorf("%w，找到了 %s", ErrMultipleAmounts, strings.Join(texts, "、"))
+	}
+
+	entries := make([]*Entry, 0, len(tokens))
+	for i, piece := range splitPieces(body, tokens) {
+		entry, err := buildEntry(piece.text, piece.token)
+		if err != nil {
+			return nil, err
+		}
+		entry.Currency = currency
+		entry.Wallet = wallet
+		entry.Date = date
+		if i == len(tokens)-1 {
+			entry.Note = note
+		}
+		entry.Text = strings.TrimSpace(text)
+		if len(tokens) > 1 {
+			entry.Text = strings.TrimSpace(piece.text)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type piece struct {
+	text  string
+	token model.AmountToken // 位置相对于 text
+}
+
+// 将包含多个金额的片段拆开，每个金额带上它的描述
+// 金额在前的写法（"35 午饭 20 打车"）描述跟在金额后面，否则描述在金额前面
+func splitPieces(body string, tokens []model.AmountToken) []piece {
+	amountFirst := strings.TrimSpace(body[:tokens[0].Start]) == ""
+
+	pieces := make([]piece, len(tokens))
+	for i, token := range tokens {
+		start, end := 0, len(body)
+		if amountFirst {
+			start = token.Start
+			if i+1 < len(tokens) {
+				end = tokens[i+1].Start
+			}
+		} else {
+			if i > 0 {
+				start = tokens[i-1].End
+			}
+			if i+1 < len(tokens) {
+				end = token.End
+			}
+		}
+		token.Start -= start
+		token.End -= start
+		pieces[i] = piece{text: body[start:end], token: token}
+	}
+	return pieces
+}
+
+// 根据金额和剩余的描述构建一条记账
+func buildEntry(text string, token model.AmountToken) (*Entry, error) {
+	if token.Value == 0 {
+		return nil, fmt.Errorf("金额不能为 0")
+	}
+
+	description := cleanDescription(text[:token.Start] + " " + text[token.End:])
+	income := false
+	switch {
+	case token.Sign > 0:
+		income = true
+	case token.Sign < 0:
+	default:
+		// 没写符号时看关键词，默认为支出
+		income = containsAny(description, incomeKeywords)
+	}
+	description = cleanDescription(trimPrefixes(description, incomeKeywords, expenseKeywords))
+	if description == "" {
+		description = DefaultDescription
+	}
+
+	amount := -token.Value
+	if income {
+		amount = token.Value
+	}
+	return &Entry{
+		Amount:      amount,
+		Sign:        token.Sign,
+		Description: description,
+		Category:    model.Categorize(description, income),
+	}, nil
+}
+
+// 清理描述两端的空白和标点，合并中间多余的空格
+func cleanDescription(s string) string {
+	return strings.Trim(strings.Join(strings.Fields(s), " "), ":：,，、.。-— ")
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimPrefixes(s string, groups ...[]string) string {
+	for _, group := range groups {
+		for _, prefix := range group {
+			s = strings.TrimPrefix(s, prefix)
+		}
+	}
+	return s
+}
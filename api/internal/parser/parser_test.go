@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 2026-10-14 周三 20:30
+var testNow = time.Date(2026, 10, 14, 20, 30, 0, 0, time.Local)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 20, 30, 0, 0, time.Local)
+}
+
+// corpus 单条记账的共享语料，ParseEntry 和 ParseEntries 对每一条的结果必须一致
+var corpus = []struct {
+	input       string
+	amount      model.Money
+	description string
+	note        string
+	currency    string
+	date        time.Time
+	category    string
+}{
+	// 描述在前
+	{"午餐-100", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"午餐 -100", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"午餐 100", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"午餐100", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"工资+5000", 500000, "工资", "", "", time.Time{}, model.CategorySalary},
+	{"工资 +5000", 500000, "工资", "", "", time.Time{}, model.CategorySalary},
+	{"打车：35", -3500, "打车", "", "", time.Time{}, model.CategoryTransport},
+
+	// 金额在前
+	{"100 午餐", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"-100 午餐", -10000, "午餐", "", "", time.Time{}, model.CategoryFood},
+	{"+100 红包", 10000, "红包", "", "", time.Time{}, model.CategoryIncome},
+	{"12.5 咖啡", -1250, "咖啡", "", "", time.Time{}, model.CategoryFood},
+
+	// 备注
+	{"买菜-10(未报销)", -1000, "买菜", "未报销", "", time.Time{}, model.CategoryFood},
+	{"打车-50（公司报销）", -5000, "打车", "公司报销", "", time.Time{}, model.CategoryTransport},
+	{"买菜 10 (含运费5元)", -1000, "买菜", "含运费5元", "", time.Time{}, model.CategoryFood},
+
+	// 关键词
+	{"收入 2000 红包", 200000, "红包", "", "", time.Time{}, model.CategoryIncome},
+	{"支出20 打车", -2000, "打车", "", "", time.Time{}, model.CategoryTransport},
+	{"副业收入 800", 80000, "副业收入", "", "", time.Time{}, model.CategoryIncome},
+	{"进账 300", 30000, "未知项目", "", "", time.Time{}, model.CategoryIncome},
+
+	// 口语金额
+	{"打车三十五块", -3500, "打车", "", "", time.Time{}, model.CategoryTransport},
+	{"奶茶五毛", -50, "奶茶", "", "", time.Time{}, model.CategoryFood},
+	{"房租2k", -200000, "房租", "", "", time.Time{}, model.CategoryHousing},
+	{"奖金+1.5w", 1500000, "奖金", "", "", time.Time{}, model.CategorySalary},
+	{"买菜一百二", -12000, "买菜", "", "", time.Time{}, model.CategoryFood},
+	{"停车3块5", -350, "停车", "", "", time.Time{}, model.CategoryTransport},
+
+	// 日期
+	{"昨天 打车-30", -3000, "打车", "", "", day(10, 13), model.CategoryTransport},
+	{"前天午饭25", -2500, "午饭", "", "", day(10, 12), model.CategoryFood},
+	{"周五 电影-60", -6000, "电影", "", "", day(10, 9), model.CategoryEntertainment},
+	{"3/14 打车30", -3000, "打车", "", "", day(3, 14), model.CategoryTransport},
+	{"2026-10-01 机票-1200", -120000, "机票", "", "", day(10, 1), model.CategoryTransport},
+	{"3月100元", -10000, "3月", "", "", time.Time{}, model.CategoryOther},
+
+	// 数量不是金额
+	{"水果2斤 15", -1500, "水果2斤", "", "", time.Time{}, model.CategoryFood},
+	{"等车5分钟 打车30", -3000, "等车5分钟 打车", "", "", time.Time{}, model.CategoryTransport},
+	{"停车2小时 10", -1000, "停车2小时", "", "", time.Time{}, model.CategoryTransport},
+	{"酒店住2晚 600", -60000, "酒店住2晚", "", "", time.Time{}, model.CategoryOther},
+
+	// 币种
+	{"午餐 -12 USD", -1200, "午餐", "", "USD", time.Time{}, model.CategoryFood},
+	{"会员 100U", -10000, "会员", "", "USDT", time.Time{}, model.CategoryEntertainment},
+	{"$5 咖啡", -500, "咖啡", "", "USD", time.Time{}, model.CategoryFood},
+
+	// 没有描述
+	{"-30", -3000, "未知项目", "", "", time.Time{}, model.CategoryOther},
+}
+
+func TestParseEntry(t *testing.T) {
+	for _, tt := range corpus {
+		entry, err := ParseEntry(tt.input, testNow)
+		if err != nil {
+			t.Errorf("ParseEntry(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if entry.Amount != tt.amount || entry.Description != tt.description || entry.Note != tt.note ||
+			entry.Currency != tt.currency || !entry.Date.Equal(tt.date) || entry.Category != tt.category {
+			t.Errorf("ParseEntry(%q) = {%s %q %q %q %s %s}, want {%s %q %q %q %s %s}", tt.input,
+				entry.Amount, entry.Description, entry.Note, entry.Currency, entry.Date.Format("01-02"), entry.Category,
+				tt.amount, tt.description, tt.note, tt.currency, tt.date.Format("01-02"), tt.category)
+		}
+		// 分类随请求传递，记录不需要按带备注的描述重新推断
+		if req := entry.Request(); req.Category != entry.Category {
+			t.Errorf("ParseEntry(%q).Request().Category = %q, want %q", tt.input, req.Category, entry.Category)
+		}
+	}
+}
+
+func TestParseEntriesMatchesParseEntry(t *testing.T) {
+	for _, tt := range corpus {
+		single, err := ParseEntry(tt.input, testNow)
+		if err != nil {
+			continue
+		}
+		entries, err := ParseEntries(tt.input, testNow)
+		if err != nil {
+			t.Errorf("ParseEntries(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		// 消息开头的日期不属于任何片段，原文可能不同，其余字段必须一致
+		if len(entries) != 1 {
+			t.Errorf("ParseEntries(%q) returned %d entries, want 1", tt.input, len(entries))
+			continue
+		}
+		got := *entries[0]
+		got.Text = single.Text
+		if got != *single {
+			t.Errorf("ParseEntries(%q) = %+v, want %+v", tt.input, got, *single)
+		}
+	}
+}
+
+func TestParseEntryErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		target error // 为空时只要求返回错误
+	}{
+		{"", ErrNoAmount},
+		{"午餐", ErrNoAmount},
+		{"一个苹果", ErrNoAmount},
+		{"水果2斤", ErrNoAmount},
+		{"午饭35 打车20", ErrMultipleAmounts},
+		{"午餐12.345", nil},
+		{"午餐 0", nil},
+		{"2026-10-20 午餐-10", nil},
+		{"2月30日 午餐-10", nil},
+		{"房租99999999999", nil},
+	}
+	for _, tt := range tests {
+		entry, err := ParseEntry(tt.input, testNow)
+		if err == nil {
+			t.Errorf("ParseEntry(%q) = %+v, want error", tt.input, entry)
+			continue
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("ParseEntry(%q) error = %v, want %v", tt.input, err, tt.target)
+		}
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	type result struct {
+		amount      model.Money
+		description string
+		date        time.Time
+	}
+	tests := []struct {
+		input string
+		want  []result
+	}{
+		{"午饭35, 打车-20", []result{{-3500, "午饭", time.Time{}}, {-2000, "打车", time.Time{}}}},
+		{"午饭35，打车20；咖啡 18", []result{{-3500, "午饭", time.Time{}}, {-2000, "打车", time.Time{}}, {-1800, "咖啡", time.Time{}}}},
+		{"午饭35\n工资+8000", []result{{-3500, "午饭", time.Time{}}, {800000, "工资", time.Time{}}}},
+		{"午饭35 打车20", []result{{-3500, "午饭", time.Time{}}, {-2000, "打车", time.Time{}}}},
+		{"35 午饭 20 打车", []result{{-3500, "午饭", time.Time{}}, {-2000, "打车", time.Time{}}}},
+		{"昨天 午饭35, 打车20", []result{{-3500, "午饭", day(10, 13)}, {-2000, "打车", day(10, 13)}}},
+		{"昨天打车30, 前天午饭20", []result{{-3000, "打车", day(10, 13)}, {-2000, "午饭", day(10, 12)}}},
+		{"今天好累, 晚饭 三十五块", []result{{-3500, "晚饭", testNow}}},
+		{"好累, 晚饭 三十五块", []result{{-3500, "晚饭", time.Time{}}}},
+		{"早餐 -8\n地铁 -4\n午饭 -25", []result{{-800, "早餐", time.Time{}}, {-400, "地铁", time.Time{}}, {-2500, "午饭", time.Time{}}}},
+		{"停车2小时 10, 午饭35", []result{{-1000, "停车2小时", time.Time{}}, {-3500, "午饭", time.Time{}}}},
+		{"早餐 -8\r\n\n午饭 -25 #微信。咖啡 12", []result{{-800, "早餐", time.Time{}}, {-2500, "午饭", time.Time{}}, {-1200, "咖啡", time.Time{}}}},
+	}
+	for _, tt := range tests {
+		entries, err := ParseEntries(tt.input, testNow)
+		if err != nil {
+			t.Errorf("ParseEntries(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		var got []result
+		for _, entry := range entries {
+			got = append(got, result{entry.Amount, entry.Description, entry.Date})
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseEntries(%q) = %v, want %v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].amount != tt.want[i].amount || got[i].description != tt.want[i].description || !got[i].date.Equal(tt.want[i].date) {
+				t.Errorf("ParseEntries(%q)[%d] = %v, want %v", tt.input, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if _, err := ParseEntries("今天天气不错", testNow); !errors.Is(err, ErrNoAmount) {
+		t.Errorf("ParseEntries without amount error = %v, want ErrNoAmount", err)
+	}
+	if _, err := ParseEntries("午饭35, 咖啡12.345", testNow); err == nil {
+		t.Errorf("ParseEntries with invalid amount should fail")
+	}
+}
+
+func TestEntryRequest(t *testing.T) {
+	entry, err := ParseEntry("昨天 买菜-10(未报销)", testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := entry.Request()
+	if req.Amount != -1000 || req.Description != "买菜(未报销)" || !req.Date.Equal(day(10, 13)) {
+		t.Errorf("Request() = %+v", req)
+	}
+	if description, note := model.SplitNote(req.Description); description != "买菜" || note != "未报销" {
+		t.Errorf("SplitNote(%q) = %q, %q", req.Description, description, note)
+	}
+}
+
+func TestParseEntryWallet(t *testing.T) {
+	tests := []struct {
+		input       string
+		wallet      string
+		currency    string
+		description string
+	}{
+		{"午餐-25 #支付宝", model.WalletAlipay, "", "午餐"},
+		{"#微信 奶茶 15", model.WalletWechat, "", "奶茶"},
+		{"会员 100 #usdt", model.WalletUSDT, "", "会员"},
+		{"话题 #未知 30", "", "", "话题 #未知"},
+	}
+	for _, tt := range tests {
+		entry, err := ParseEntry(tt.input, testNow)
+		if err != nil {
+			t.Errorf("ParseEntry(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if entry.Wallet != tt.wallet || entry.Currency != tt.currency || entry.Description != tt.description {
+			t.Errorf("ParseEntry(%q) = {%q %q %q}, want {%q %q %q}", tt.input,
+				entry.Wallet, entry.Currency, entry.Description, tt.wallet, tt.currency, tt.description)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// CopyLedger 将 Redis 中的全部记账周期、记录和活跃周期标记复制到 to，返回复制的周期数
+// 记录按ID覆盖写入，重复执行不会产生重复数据；审计记录等其他数据仍保存在 Redis，不需要复制
+func CopyLedger(ctx context.Context, from *RedisLedgerStore, to LedgerStore) (int, error) {
+	cycleIDs, err := from.CycleIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, cycleID := range cycleIDs {
+		cycle, err := from.GetCycle(ctx, cycleID)
+		if err != nil {
+			log.Printf("跳过记账周期 %s: %v", cycleID, err)
+			continue
+		}
+		records, err := from.LoadRecords(ctx, cycleID)
+		if err != nil {
+			return copied, err
+		}
+		if err := to.SaveCycle(ctx, cycle); err != nil {
+			return copied, err
+		}
+		if err := to.AppendRecords(ctx, cycle, records...); err != nil {
+			return copied, err
+		}
+		if err := to.AddToHistory(ctx, cycle); err != nil {
+			return copied, err
+		}
+
+		if cycle.IsActive {
+			activeID, err := from.ActiveCycleID(ctx, cycle.ChatID, cycle.UserID)
+			if err != nil {
+				return copied, err
+			}
+			if activeID == cycle.ID {
+				if err := to.SetActiveCycleID(ctx, cycle.ChatID, cycle.UserID, cycle.ID); err != nil {
+					return copied, err
+				}
+			}
+		}
+		copied++
+		log.Printf("已复制记账周期 %s（%d 条记录）", cycleID, len(records))
+	}
+	if copied < len(cycleIDs) {
+		return copied, fmt.Errorf("共 %d 个记账周期，%d 个复制失败", len(cycleIDs), len(cycleIDs)-copied)
+	}
+	return copied, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+func TestCopyLedger(t *testing.T) {
+	ctx := context.Background()
+	from := newTestRedisStore(t)
+	to := newTestSqlStore(t)
+
+	// c1 已结束，c2 是活跃周期
+	ended := testCycle("c1")
+	ended.IsActive = false
+	active := testCycle("c2")
+	active.StartTime = ended.EndTime
+	cycles := []*model.AccountingCycle{ended, active}
+	records := [][]*model.AccountingRecord{
+		{testRecord("c1a", -1000, 2), testRecord("c1b", -1000, 3)},
+		{testRecord("c2a", -1000, 2)},
+	}
+	for i, cycle := range cycles {
+		if err := from.SaveCycle(ctx, cycle); err != nil {
+			t.Fatalf("SaveCycle error = %v", err)
+		}
+		if err := from.AddToHistory(ctx, cycle); err != nil {
+			t.Fatalf("AddToHistory error = %v", err)
+		}
+		if err := from.AppendRecords(ctx, cycle, records[i]...); err != nil {
+			t.Fatalf("AppendRecords error = %v", err)
+		}
+	}
+	if err := from.SetActiveCycleID(ctx, active.ChatID, active.UserID, active.ID); err != nil {
+		t.Fatalf("SetActiveCycleID error = %v", err)
+	}
+
+	// 重复执行结果相同
+	for i := 0; i < 2; i++ {
+		copied, err := CopyLedger(ctx, from, to)
+		if err != nil || copied != 2 {
+			t.Fatalf("CopyLedger = %d, %v, want 2", copied, err)
+		}
+	}
+
+	ids, err := to.HistoryCycleIDs(ctx, active.ChatID, active.UserID)
+	if err != nil || !reflect.DeepEqual(ids, []string{"c1", "c2"}) {
+		t.Errorf("HistoryCycleIDs = %v, %v, want [c1 c2]", ids, err)
+	}
+	if id, err := to.ActiveCycleID(ctx, active.ChatID, active.UserID); err != nil || id != "c2" {
+		t.Errorf("ActiveCycleID = %q, %v, want c2", id, err)
+	}
+	copiedRecords, err := to.LoadRecords(ctx, "c1")
+	if err != nil || !reflect.DeepEqual(recordIDs(copiedRecords), []string{"c1a", "c1b"}) {
+		t.Errorf("LoadRecords(c1) = %v, %v", recordIDs(copiedRecords), err)
+	}
+	checkTotals(t, to, ended, 0, 2000)
+	checkTotals(t, to, active, 0, 1000)
+}
@@ -0,0 +1,394 @@
+package store
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// RedisLedgerStore 基于 Redis 的存储，结构如下:
+//
+//	accounting:cycle:<周期ID>            周期基本信息（JSON，不含记录）
+//	accounting:records:<周期ID>          记录，field 为记录ID，值为记录 JSON
+//	accounting:dates:<周期ID>            记录ID按记录日期排序（有序集合，score 为 Unix 时间）
+//	accounting:totals:<周期ID>           收支合计，记账时增量维护，摘要直接读取
+//	accounting:cycles:<聊天ID>:<用户ID>  历史周期ID（有序集合，score 为开始时间）
+//	accounting:active:<聊天ID>:<用户ID>  当前活跃的周期ID
+//
+// 新增记录只写入这几个键，不需要读出整个周期。
+// 旧版本把记录保存在周期 JSON 中、历史周期保存为 JSON 数组，读取时自动迁移。
+type RedisLedgerStore struct {
+	rds *redis.Redis
+}
+
+func NewRedisLedgerStore(rds *redis.Redis) *RedisLedgerStore {
+	return &RedisLedgerStore{rds: rds}
+}
+
+func (s *RedisLedgerStore) GetCycle(ctx context.Context, cycleID string) (*model.AccountingCycle, error) {
+	data, err := s.rds.GetCtx(ctx, cycleKey(cycleID))
+	if err != nil {
+		return nil, fmt.Errorf("获取记账周期失败: %v", err)
+	}
+	if data == "" {
+		return nil, ErrCycleNotFound
+	}
+
+	var cycle model.AccountingCycle
+	if err := json.Unmarshal([]byte(data), &cycle); err != nil {
+		return nil, fmt.Errorf("解析记账周期失败: %v", err)
+	}
+
+	// 旧版本的记录保存在周期 JSON 中，迁移到单独的键
+	if len(cycle.Records) > 0 {
+		if err := s.migrateRecords(ctx, &cycle); err != nil {
+			log.Printf("迁移记账周期 %s 失败: %v", cycleID, err)
+		}
+	}
+	cycle.Records = nil
+	return &cycle, nil
+}
+
+func (s *RedisLedgerStore) SaveCycle(ctx context.Context, cycle *model.AccountingCycle) error {
+	meta := *cycle
+	meta.Records = nil
+	data, err := json.Marshal(&meta)
+	if err != nil {
+		return fmt.Errorf("序列化记账周期失败: %v", err)
+	}
+	if err := s.rds.SetCtx(ctx, cycleKey(cycle.ID), string(data)); err != nil {
+		return fmt.Errorf("保存记账周期失败: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisLedgerStore) LoadRecords(ctx context.Context, cycleID string) ([]*model.AccountingRecord, error) {
+	ids, err := s.rds.ZrangeCtx(ctx, datesKey(cycleID), 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("获取记账记录失败: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := s.rds.HmgetCtx(ctx, recordsKey(cycleID), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("获取记账记录失败: %v", err)
+	}
+
+	records := make([]*model.AccountingRecord, 0, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		var record model.AccountingRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			log.Printf("解析记账记录失败: %v", err)
+			continue
+		}
+		records = append(records, &record)
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// 追加记录的脚本，KEYS 为记录、日期和合计三个键
+// ARGV 按记录依次为: 记录ID、旧记录 JSON（不存在时为空）、记录 JSON、日期、合计字段数 n，以及 n 组合计字段和增量
+// 增量已经减去旧记录的金额；旧记录在读取后被修改时整体放弃写入，由调用方重试
+// 脚本整体执行，批量记账时不会只写入一部分记录
+var appendRecordsScript = redis.NewScript(`
+local i = 1
+local seen = {}
+while i <= #ARGV do
+	if not seen[ARGV[i]] and (redis.call('HGET', KEYS[1], ARGV[i]) or '') ~= ARGV[i + 1] then
+		return redis.error_reply('RECORD_CHANGED')
+	end
+	seen[ARGV[i]] = true
+	i = i + 5 + 2 * tonumber(ARGV[i + 4])
+end
+i = 1
+while i <= #ARGV do
+	local id, data, score, n = ARGV[i], ARGV[i + 2], ARGV[i + 3], tonumber(ARGV[i + 4])
+	redis.call('HSET', KEYS[1], id, data)
+	redis.call('ZADD', KEYS[2], score, id)
+	for j = 1, n do
+		redis.call('HINCRBY', KEYS[3], ARGV[i + 3 + 2 * j], ARGV[i + 4 + 2 * j])
+	end
+	i = i + 5 + 2 * n
+end
+return #ARGV
+`)
+
+// 并发覆盖同一记录时的重试次数
+const appendRecordsRetries = 3
+
+// AppendRecords 只写入新记录，与周期已有的记录数无关
+// 记录ID已存在时先从合计中减去旧记录，重复提交不会重复计算
+func (s *RedisLedgerStore) AppendRecords(ctx context.Context, cycle *model.AccountingCycle, records ...*model.AccountingRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	keys := []string{recordsKey(cycle.ID), datesKey(cycle.ID), totalsKey(cycle.ID)}
+	for attempt := 0; ; attempt++ {
+		args, err := s.appendRecordsArgs(ctx, cycle, records)
+		if err != nil {
+			return err
+		}
+		_, err = s.rds.ScriptRunCtx(ctx, appendRecordsScript, keys, args...)
+		if err == nil {
+			return nil
+		}
+		if !strings.Contains(err.Error(), "RECORD_CHANGED") || attempt+1 >= appendRecordsRetries {
+			return fmt.Errorf("保存记账记录失败: %v", err)
+		}
+	}
+}
+
+// 生成追加记录脚本的参数，同一批中重复的记录ID以前一条为旧记录
+func (s *RedisLedgerStore) appendRecordsArgs(ctx context.Context, cycle *model.AccountingCycle, records []*model.AccountingRecord) ([]any, error) {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	olds, err := s.rds.HmgetCtx(ctx, recordsKey(cycle.ID), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("获取记账记录失败: %v", err)
+	}
+
+	base := cycle.Currency()
+	written := make(map[string]string, len(records))
+	var args []any
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("序列化记账记录失败: %v", err)
+		}
+		old, ok := written[record.ID]
+		if !ok {
+			old = olds[i]
+		}
+		written[record.ID] = string(data)
+
+		totals := recordTotals(record, base)
+		if old != "" {
+			var oldRecord model.AccountingRecord
+			if err := json.Unmarshal([]byte(old), &oldRecord); err != nil {
+				return nil, fmt.Errorf("解析记账记录失败: %v", err)
+			}
+			for field, value := range recordTotals(&oldRecord, base) {
+				totals[field] -= value
+			}
+		}
+		args = append(args, record.ID, old, string(data), record.Date.Unix(), len(totals))
+		for field, value := range totals {
+			args = append(args, field, int64(value))
+		}
+	}
+	return args, nil
+}
+
+func (s *RedisLedgerStore) UpdateRecord(ctx context.Context, cycle *model.AccountingCycle, record *model.AccountingRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化记账记录失败: %v", err)
+	}
+	if err := s.rds.HsetCtx(ctx, recordsKey(cycle.ID), record.ID, string(data)); err != nil {
+		return fmt.Errorf("保存记账记录失败: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisLedgerStore) GetTotals(ctx context.Context, cycle *model.AccountingCycle) (*Totals, error) {
+	values, err := s.rds.HgetallCtx(ctx, totalsKey(cycle.ID))
+	if err != nil {
+		return nil, fmt.Errorf("获取收支合计失败: %v", err)
+	}
+	fields := make(map[string]model.Money, len(values))
+	for field, text := range values {
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			fields[field] = model.Money(value)
+		}
+	}
+	return newTotals(cycle, fields), nil
+}
+
+func (s *RedisLedgerStore) AddToHistory(ctx context.Context, cycle *model.AccountingCycle) error {
+	// 先合并旧格式的历史记录，否则有序集合不再为空后旧记录不会被迁移
+	if err := s.migrateHistory(ctx, cycle.ChatID, cycle.UserID); err != nil {
+		return err
+	}
+	if _, err := s.rds.ZaddCtx(ctx, historyKey(cycle.ChatID, cycle.UserID), cycle.StartTime.Unix(), cycle.ID); err != nil {
+		return fmt.Errorf("保存历史记录失败: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisLedgerStore) HistoryCycleIDs(ctx context.Context, chatID int64, userID int64) ([]string, error) {
+	if err := s.migrateHistory(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+	cycleIDs, err := s.rds.ZrangeCtx(ctx, historyKey(chatID, userID), 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("获取历史记录失败: %v", err)
+	}
+	return cycleIDs, nil
+}
+
+func (s *RedisLedgerStore) ActiveCycleID(ctx context.Context, chatID int64, userID int64) (string, error) {
+	cycleID, err := s.rds.GetCtx(ctx, activeKey(chatID, userID))
+	if err != nil {
+		return "", fmt.Errorf("获取活跃周期失败: %v", err)
+	}
+	return cycleID, nil
+}
+
+func (s *RedisLedgerStore) SetActiveCycleID(ctx context.Context, chatID int64, userID int64, cycleID string) error {
+	if err := s.rds.SetCtx(ctx, activeKey(chatID, userID), cycleID); err != nil {
+		return fmt.Errorf("设置活跃周期失败: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisLedgerStore) ClearActiveCycleID(ctx context.Context, chatID int64, userID int64) error {
+	if _, err := s.rds.DelCtx(ctx, activeKey(chatID, userID)); err != nil {
+		return fmt.Errorf("清除活跃周期标记失败: %v", err)
+	}
+	return nil
+}
+
+// CycleIDs 列出 Redis 中保存的全部记账周期ID，用于迁移到其他存储
+func (s *RedisLedgerStore) CycleIDs(ctx context.Context) ([]string, error) {
+	prefix := cycleKey("")
+	var cycleIDs []string
+	var cursor uint64
+	for {
+		keys, next, err := s.rds.ScanCtx(ctx, cursor, prefix+"*", 200)
+		if err != nil {
+			return nil, fmt.Errorf("获取记账周期列表失败: %v", err)
+		}
+		for _, key := range keys {
+			cycleIDs = append(cycleIDs, strings.TrimPrefix(key, prefix))
+		}
+		if next == 0 {
+			return cycleIDs, nil
+		}
+		cursor = next
+	}
+}
+
+// 将旧版本保存在周期 JSON 中的记录迁移到单独的键，并重新计算合计
+// 记录按ID写入，合计按迁移后的全部记录重算，中途失败后再次迁移结果相同
+func (s *RedisLedgerStore) migrateRecords(ctx context.Context, cycle *model.AccountingCycle) error {
+	values := make(map[string]string, len(cycle.Records))
+	pairs := make([]redis.Pair, 0, len(cycle.Records))
+	for i, record := range cycle.Records {
+		// 旧记录没有ID，按位置生成固定的ID，重复迁移不会产生重复记录
+		if record.ID == "" {
+			sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d", cycle.ID, i)))
+			record.ID = hex.EncodeToString(sum[:])[:12]
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("序列化记账记录失败: %v", err)
+		}
+		values[record.ID] = string(data)
+		pairs = append(pairs, redis.Pair{Key: record.ID, Score: record.Date.Unix()})
+	}
+	if err := s.rds.HmsetCtx(ctx, recordsKey(cycle.ID), values); err != nil {
+		return err
+	}
+	if _, err := s.rds.ZaddsCtx(ctx, datesKey(cycle.ID), pairs...); err != nil {
+		return err
+	}
+
+	records, err := s.LoadRecords(ctx, cycle.ID)
+	if err != nil {
+		return err
+	}
+	totals := make(map[string]model.Money)
+	for _, record := range records {
+		for field, value := range recordTotals(record, cycle.Currency()) {
+			totals[field] += value
+		}
+	}
+	fields := make(map[string]string, len(totals))
+	for field, value := range totals {
+		fields[field] = strconv.FormatInt(int64(value), 10)
+	}
+	if _, err := s.rds.DelCtx(ctx, totalsKey(cycle.ID)); err != nil {
+		return err
+	}
+	if err := s.rds.HmsetCtx(ctx, totalsKey(cycle.ID), fields); err != nil {
+		return err
+	}
+
+	// 最后去掉周期 JSON 中的记录，之前的步骤失败时下次读取会重新迁移
+	return s.SaveCycle(ctx, cycle)
+}
+
+// 将旧版本 JSON 数组格式的历史记录合并到有序集合，合并后删除旧键
+// 有序集合中已有的周期按ID去重，中途失败后再次合并结果相同
+func (s *RedisLedgerStore) migrateHistory(ctx context.Context, chatID int64, userID int64) error {
+	legacyKey := fmt.Sprintf("accounting:history:%d:%d", chatID, userID)
+	data, err := s.rds.GetCtx(ctx, legacyKey)
+	if err != nil {
+		return fmt.Errorf("获取历史记录失败: %v", err)
+	}
+	if data == "" {
+		return nil
+	}
+	var legacyIDs []string
+	if err := json.Unmarshal([]byte(data), &legacyIDs); err != nil {
+		return fmt.Errorf("解析历史记录失败: %v", err)
+	}
+
+	// 已经不存在的周期不再迁移
+	var pairs []redis.Pair
+	for _, cycleID := range legacyIDs {
+		cycle, err := s.GetCycle(ctx, cycleID)
+		if err != nil {
+			continue
+		}
+		pairs = append(pairs, redis.Pair{Key: cycle.ID, Score: cycle.StartTime.Unix()})
+	}
+	if len(pairs) > 0 {
+		if _, err := s.rds.ZaddsCtx(ctx, historyKey(chatID, userID), pairs...); err != nil {
+			return fmt.Errorf("迁移历史记录失败: %v", err)
+		}
+	}
+	if _, err := s.rds.DelCtx(ctx, legacyKey); err != nil {
+		log.Printf("删除旧历史记录失败: %v", err)
+	}
+	return nil
+}
+
+func cycleKey(cycleID string) string {
+	return fmt.Sprintf("accounting:cycle:%s", cycleID)
+}
+
+func recordsKey(cycleID string) string {
+	return fmt.Sprintf("accounting:records:%s", cycleID)
+}
+
+func datesKey(cycleID string) string {
+	return fmt.Sprintf("accounting:dates:%s", cycleID)
+}
+
+func totalsKey(cycleID string) string {
+	return fmt.Sprintf("accounting:totals:%s", cycleID)
+}
+
+func historyKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:cycles:%d:%d", chatID, userID)
+}
+
+func activeKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("accounting:active:%d:%d", chatID, userID)
+}
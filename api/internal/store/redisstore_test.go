@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qx/syft_robot/api/internal/model"
+)
+
+// 同一批中重复的记录ID只计算最后一条
+func TestRedisAppendDuplicateIDs(t *testing.T) {
+	ctx := context.Background()
+	ledger := newTestRedisStore(t)
+	cycle := testCycle("c1")
+	if err := ledger.SaveCycle(ctx, cycle); err != nil {
+		t.Fatalf("SaveCycle error = %v", err)
+	}
+
+	err := ledger.AppendRecords(ctx, cycle, testRecord("r1", -1000, 2), testRecord("r1", -3000, 2), testRecord("r2", -500, 3))
+	if err != nil {
+		t.Fatalf("AppendRecords error = %v", err)
+	}
+	checkTotals(t, ledger, cycle, 0, 3500)
+
+	records, err := ledger.LoadRecords(ctx, cycle.ID)
+	if err != nil {
+		t.Fatalf("LoadRecords error = %v", err)
+	}
+	if len(records) != 2 || records[0].Amount != -3000 {
+		t.Errorf("LoadRecords = %v", recordIDs(records))
+	}
+}
+
+// 旧版本记录保存在周期 JSON 中，读取周期时迁移并重新计算合计
+func TestRedisMigrateLegacyRecords(t *testing.T) {
+	ctx := context.Background()
+	ledger := newTestRedisStore(t)
+	cycle := testCycle("legacy")
+	cycle.Records = append(cycle.Records, testRecord("", -1200, 2), testRecord("", 3000, 3), testRecord("", -800, 4))
+	data, err := json.Marshal(cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.rds.Set(cycleKey(cycle.ID), string(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复读取不会重复迁移
+	for i := 0; i < 2; i++ {
+		got, err := ledger.GetCycle(ctx, cycle.ID)
+		if err != nil {
+			t.Fatalf("GetCycle error = %v", err)
+		}
+		if len(got.Records) != 0 {
+			t.Errorf("GetCycle 返回了 %d 条记录，want 0", len(got.Records))
+		}
+		records, err := ledger.LoadRecords(ctx, cycle.ID)
+		if err != nil {
+			t.Fatalf("LoadRecords error = %v", err)
+		}
+		if len(records) != 3 || records[0].ID == "" || records[0].Amount != -1200 {
+			t.Errorf("LoadRecords = %v", recordIDs(records))
+		}
+		checkTotals(t, ledger, got, 3000, 2000)
+	}
+}
+
+// 升级后先开始新周期再读取历史，旧格式的历史记录也不会丢失
+func TestRedisMigrateLegacyHistory(t *testing.T) {
+	ctx := context.Background()
+	ledger := newTestRedisStore(t)
+	old1, old2 := testCycle("old1"), testCycle("old2")
+	old2.StartTime = old1.EndTime
+	for _, cycle := range []*model.AccountingCycle{old1, old2} {
+		if err := ledger.SaveCycle(ctx, cycle); err != nil {
+			t.Fatalf("SaveCycle error = %v", err)
+		}
+	}
+	// missing 已被删除，不迁移
+	legacyKey := fmt.Sprintf("accounting:history:%d:%d", old1.ChatID, old1.UserID)
+	if err := ledger.rds.Set(legacyKey, `["old1","missing","old2"]`); err != nil {
+		t.Fatal(err)
+	}
+
+	current := testCycle("new")
+	current.StartTime = old2.StartTime.AddDate(0, 1, 0)
+	if err := ledger.SaveCycle(ctx, current); err != nil {
+		t.Fatalf("SaveCycle error = %v", err)
+	}
+	if err := ledger.AddToHistory(ctx, current); err != nil {
+		t.Fatalf("AddToHistory error = %v", err)
+	}
+
+	ids, err := ledger.HistoryCycleIDs(ctx, current.ChatID, current.UserID)
+	if err != nil {
+		t.Fatalf("HistoryCycleIDs error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"old1", "old2", "new"}) {
+		t.Errorf("HistoryCycleIDs = %v, want [old1 old2 new]", ids)
+	}
+	if exists, err := ledger.rds.Exists(legacyKey); err != nil || exists {
+		t.Errorf("旧历史记录未删除: %v, %v", exists, err)
+	}
+}
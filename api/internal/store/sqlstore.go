@@ -0,0 +1,370 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qx/syft_robot/api/internal/config"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+
+	// 注册 sqlite3 驱动，MySQL 驱动由 sqlx 注册
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SqlLedgerStore 基于 SQL 的存储，通过 go-zero sqlx 访问，支持 MySQL 和 SQLite
+// 表结构在创建时自动建立，语句只使用两者都支持的语法（REPLACE INTO、? 占位符）。
+// 历史周期直接按开始时间查询 ledger_cycles，合计由 SUM 查询得到，不需要单独维护。
+type SqlLedgerStore struct {
+	conn sqlx.SqlConn
+}
+
+// 周期表的一行
+type cycleRow struct {
+	ID            string    `db:"id"`
+	ChatID        int64     `db:"chat_id"`
+	UserID        int64     `db:"user_id"`
+	StartTime     time.Time `db:"start_time"`
+	EndTime       time.Time `db:"end_time"`
+	Income        int64     `db:"income"`
+	BaseCurrency  string    `db:"base_currency"`
+	IsActive      bool      `db:"is_active"`
+	GoalAllocated int64     `db:"goal_allocated"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// 记录表的一行，orig_currency 和 orig_value 是按周期本位币计算的原始币种和金额，用于合计
+type recordRow struct {
+	ID             string    `db:"id"`
+	CycleID        string    `db:"cycle_id"`
+	Amount         int64     `db:"amount"`
+	Currency       string    `db:"currency"`
+	OriginalAmount int64     `db:"original_amount"`
+	Rate           int64     `db:"rate"`
+	Description    string    `db:"description"`
+	Category       string    `db:"category"`
+	Source         string    `db:"source"`
+	ExternalID     string    `db:"external_id"`
+	Reimbursement  string    `db:"reimbursement"` // 报销信息 JSON，不需要报销时为空
+	ReceiptFileID  string    `db:"receipt_file_id"`
+	Wallet         string    `db:"wallet"`
+	RecordDate     time.Time `db:"record_date"`
+	CreatedAt      time.Time `db:"created_at"`
+	OrigCurrency   string    `db:"orig_currency"`
+	OrigValue      int64     `db:"orig_value"`
+}
+
+const (
+	cycleColumns  = "id, chat_id, user_id, start_time, end_time, income, base_currency, is_active, goal_allocated, created_at"
+	recordColumns = "id, cycle_id, amount, currency, original_amount, rate, description, category, source, external_id, " +
+		"reimbursement, receipt_file_id, wallet, record_date, created_at, orig_currency, orig_value"
+)
+
+var ledgerTables = []string{
+	`CREATE TABLE IF NOT EXISTS ledger_cycles (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		start_time DATETIME NOT NULL,
+		end_time DATETIME NOT NULL,
+		income BIGINT NOT NULL DEFAULT 0,
+		base_currency VARCHAR(16) NOT NULL DEFAULT '',
+		is_active BOOLEAN NOT NULL DEFAULT FALSE,
+		goal_allocated BIGINT NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS ledger_records (
+		id VARCHAR(64) NOT NULL,
+		cycle_id VARCHAR(64) NOT NULL,
+		amount BIGINT NOT NULL,
+		currency VARCHAR(16) NOT NULL DEFAULT '',
+		original_amount BIGINT NOT NULL DEFAULT 0,
+		rate BIGINT NOT NULL DEFAULT 0,
+		description TEXT NOT NULL,
+		category VARCHAR(64) NOT NULL DEFAULT '',
+		source VARCHAR(32) NOT NULL DEFAULT '',
+		external_id VARCHAR(128) NOT NULL DEFAULT '',
+		reimbursement TEXT NOT NULL,
+		receipt_file_id VARCHAR(255) NOT NULL DEFAULT '',
+		wallet VARCHAR(16) NOT NULL DEFAULT '',
+		record_date DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		orig_currency VARCHAR(16) NOT NULL,
+		orig_value BIGINT NOT NULL,
+		PRIMARY KEY (cycle_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ledger_active (
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		cycle_id VARCHAR(64) NOT NULL,
+		PRIMARY KEY (chat_id, user_id)
+	)`,
+}
+
+// 建表后新增的列，旧表中没有时补上
+var ledgerColumns = []struct {
+	table, name, definition string
+}{
+	{"ledger_records", "wallet", "VARCHAR(16) NOT NULL DEFAULT ''"},
+}
+
+// 索引：MySQL 不支持 CREATE INDEX IF NOT EXISTS，建表后单独处理
+var ledgerIndexes = []struct {
+	name, table, columns string
+}{
+	{"idx_ledger_cycles_owner", "ledger_cycles", "chat_id, user_id, start_time"},
+	{"idx_ledger_records_date", "ledger_records", "cycle_id, record_date"},
+}
+
+// NewSqlLedgerStore 连接数据库并建立表结构
+func NewSqlLedgerStore(c config.LedgerConf) (*SqlLedgerStore, error) {
+	if c.DataSource == "" {
+		return nil, fmt.Errorf("sql 记账存储未配置 DataSource")
+	}
+	s := &SqlLedgerStore{conn: sqlx.NewSqlConn(c.Driver, c.DataSource)}
+	if err := s.createTables(context.Background(), c.Driver); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SqlLedgerStore) createTables(ctx context.Context, driver string) error {
+	for _, table := range ledgerTables {
+		if driver == "mysql" {
+			table += " DEFAULT CHARSET=utf8mb4"
+		}
+		if _, err := s.conn.ExecCtx(ctx, table); err != nil {
+			return fmt.Errorf("创建记账表失败: %v", err)
+		}
+	}
+	for _, column := range ledgerColumns {
+		// 查询失败说明列不存在，两种数据库都适用
+		if _, err := s.conn.ExecCtx(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column.name, column.table)); err == nil {
+			continue
+		}
+		_, err := s.conn.ExecCtx(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.table, column.name, column.definition))
+		if err != nil {
+			return fmt.Errorf("更新记账表失败: %v", err)
+		}
+	}
+	for _, index := range ledgerIndexes {
+		if driver == "mysql" {
+			var count int
+			query := "SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?"
+			if err := s.conn.QueryRowCtx(ctx, &count, query, index.table, index.name); err != nil {
+				return fmt.Errorf("检查记账表索引失败: %v", err)
+			}
+			if count > 0 {
+				continue
+			}
+			_, err := s.conn.ExecCtx(ctx, fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index.name, index.table, index.columns))
+			if err != nil {
+				return fmt.Errorf("创建记账表索引失败: %v", err)
+			}
+			continue
+		}
+		_, err := s.conn.ExecCtx(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", index.name, index.table, index.columns))
+		if err != nil {
+			return fmt.Errorf("创建记账表索引失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *SqlLedgerStore) GetCycle(ctx context.Context, cycleID string) (*model.AccountingCycle, error) {
+	var row cycleRow
+	query := "SELECT " + cycleColumns + " FROM ledger_cycles WHERE id = ?"
+	err := s.conn.QueryRowCtx(ctx, &row, query, cycleID)
+	if errors.Is(err, sqlx.ErrNotFound) {
+		return nil, ErrCycleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取记账周期失败: %v", err)
+	}
+	return &model.AccountingCycle{
+		ID:            row.ID,
+		ChatID:        row.ChatID,
+		UserID:        row.UserID,
+		StartTime:     row.StartTime,
+		EndTime:       row.EndTime,
+		Income:        model.Money(row.Income),
+		BaseCurrency:  row.BaseCurrency,
+		IsActive:      row.IsActive,
+		GoalAllocated: model.Money(row.GoalAllocated),
+		CreatedAt:     row.CreatedAt,
+	}, nil
+}
+
+func (s *SqlLedgerStore) SaveCycle(ctx context.Context, cycle *model.AccountingCycle) error {
+	query := "REPLACE INTO ledger_cycles (" + cycleColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := s.conn.ExecCtx(ctx, query, cycle.ID, cycle.ChatID, cycle.UserID, cycle.StartTime, cycle.EndTime,
+		int64(cycle.Income), cycle.BaseCurrency, cycle.IsActive, int64(cycle.GoalAllocated), cycle.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("保存记账周期失败: %v", err)
+	}
+	return nil
+}
+
+func (s *SqlLedgerStore) LoadRecords(ctx context.Context, cycleID string) ([]*model.AccountingRecord, error) {
+	var rows []*recordRow
+	query := "SELECT " + recordColumns + " FROM ledger_records WHERE cycle_id = ?"
+	if err := s.conn.QueryRowsCtx(ctx, &rows, query, cycleID); err != nil {
+		return nil, fmt.Errorf("获取记账记录失败: %v", err)
+	}
+
+	records := make([]*model.AccountingRecord, 0, len(rows))
+	for _, row := range rows {
+		record := &model.AccountingRecord{
+			ID:             row.ID,
+			Amount:         model.Money(row.Amount),
+			Currency:       row.Currency,
+			OriginalAmount: model.Money(row.OriginalAmount),
+			Rate:           model.Rate(row.Rate),
+			Description:    row.Description,
+			Category:       row.Category,
+			Source:         row.Source,
+			ExternalID:     row.ExternalID,
+			ReceiptFileID:  row.ReceiptFileID,
+			Wallet:         row.Wallet,
+			Date:           row.RecordDate,
+			CreatedAt:      row.CreatedAt,
+		}
+		if row.Reimbursement != "" {
+			var reimbursement model.Reimbursement
+			if err := json.Unmarshal([]byte(row.Reimbursement), &reimbursement); err != nil {
+				return nil, fmt.Errorf("解析报销信息失败: %v", err)
+			}
+			record.Reimbursement = &reimbursement
+		}
+		records = append(records, record)
+	}
+	// 数据库的时间精度可能不同，统一在内存中排序
+	sortRecords(records)
+	return records, nil
+}
+
+// AppendRecords 合计由查询得到，重复写入同一记录不会重复计算
+func (s *SqlLedgerStore) AppendRecords(ctx context.Context, cycle *model.AccountingCycle, records ...*model.AccountingRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	err := s.conn.TransactCtx(ctx, func(ctx context.Context, session sqlx.Session) error {
+		for _, record := range records {
+			if err := replaceRecord(ctx, session, cycle, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("保存记账记录失败: %v", err)
+	}
+	return nil
+}
+
+func (s *SqlLedgerStore) UpdateRecord(ctx context.Context, cycle *model.AccountingCycle, record *model.AccountingRecord) error {
+	if err := replaceRecord(ctx, s.conn, cycle, record); err != nil {
+		return fmt.Errorf("保存记账记录失败: %v", err)
+	}
+	return nil
+}
+
+func replaceRecord(ctx context.Context, session sqlx.Session, cycle *model.AccountingCycle, record *model.AccountingRecord) error {
+	var reimbursement string
+	if record.Reimbursement != nil {
+		data, err := json.Marshal(record.Reimbursement)
+		if err != nil {
+			return err
+		}
+		reimbursement = string(data)
+	}
+	base := cycle.Currency()
+	query := "REPLACE INTO ledger_records (" + recordColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := session.ExecCtx(ctx, query, record.ID, cycle.ID, int64(record.Amount), record.Currency,
+		int64(record.OriginalAmount), int64(record.Rate), record.Description, record.Category, record.Source,
+		record.ExternalID, reimbursement, record.ReceiptFileID, record.Wallet, record.Date, record.CreatedAt,
+		record.OriginalCurrency(base), int64(record.OriginalValue(base)))
+	return err
+}
+
+func (s *SqlLedgerStore) GetTotals(ctx context.Context, cycle *model.AccountingCycle) (*Totals, error) {
+	var base struct {
+		Income  int64 `db:"income"`
+		Expense int64 `db:"expense"`
+	}
+	query := "SELECT COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS income, " +
+		"COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0) AS expense " +
+		"FROM ledger_records WHERE cycle_id = ?"
+	if err := s.conn.QueryRowCtx(ctx, &base, query, cycle.ID); err != nil {
+		return nil, fmt.Errorf("获取收支合计失败: %v", err)
+	}
+
+	var rows []struct {
+		Currency string `db:"orig_currency"`
+		Income   int64  `db:"income"`
+		Expense  int64  `db:"expense"`
+	}
+	query = "SELECT orig_currency, " +
+		"COALESCE(SUM(CASE WHEN orig_value > 0 THEN orig_value ELSE 0 END), 0) AS income, " +
+		"COALESCE(SUM(CASE WHEN orig_value <= 0 THEN -orig_value ELSE 0 END), 0) AS expense " +
+		"FROM ledger_records WHERE cycle_id = ? GROUP BY orig_currency"
+	if err := s.conn.QueryRowsCtx(ctx, &rows, query, cycle.ID); err != nil {
+		return nil, fmt.Errorf("获取收支合计失败: %v", err)
+	}
+
+	fields := map[string]model.Money{
+		"income":  model.Money(base.Income),
+		"expense": model.Money(base.Expense),
+	}
+	for _, row := range rows {
+		fields["income:"+row.Currency] = model.Money(row.Income)
+		fields["expense:"+row.Currency] = model.Money(row.Expense)
+	}
+	return newTotals(cycle, fields), nil
+}
+
+// AddToHistory 历史周期就是 ledger_cycles 中的周期，只需要保存周期
+func (s *SqlLedgerStore) AddToHistory(ctx context.Context, cycle *model.AccountingCycle) error {
+	return s.SaveCycle(ctx, cycle)
+}
+
+func (s *SqlLedgerStore) HistoryCycleIDs(ctx context.Context, chatID int64, userID int64) ([]string, error) {
+	var cycleIDs []string
+	query := "SELECT id FROM ledger_cycles WHERE chat_id = ? AND user_id = ? ORDER BY start_time"
+	if err := s.conn.QueryRowsCtx(ctx, &cycleIDs, query, chatID, userID); err != nil {
+		return nil, fmt.Errorf("获取历史记录失败: %v", err)
+	}
+	return cycleIDs, nil
+}
+
+func (s *SqlLedgerStore) ActiveCycleID(ctx context.Context, chatID int64, userID int64) (string, error) {
+	var cycleID string
+	query := "SELECT cycle_id FROM ledger_active WHERE chat_id = ? AND user_id = ?"
+	err := s.conn.QueryRowCtx(ctx, &cycleID, query, chatID, userID)
+	if errors.Is(err, sqlx.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取活跃周期失败: %v", err)
+	}
+	return cycleID, nil
+}
+
+func (s *SqlLedgerStore) SetActiveCycleID(ctx context.Context, chatID int64, userID int64, cycleID string) error {
+	query := "REPLACE INTO ledger_active (chat_id, user_id, cycle_id) VALUES (?, ?, ?)"
+	if _, err := s.conn.ExecCtx(ctx, query, chatID, userID, cycleID); err != nil {
+		return fmt.Errorf("设置活跃周期失败: %v", err)
+	}
+	return nil
+}
+
+func (s *SqlLedgerStore) ClearActiveCycleID(ctx context.Context, chatID int64, userID int64) error {
+	query := "DELETE FROM ledger_active WHERE chat_id = ? AND user_id = ?"
+	if _, err := s.conn.ExecCtx(ctx, query, chatID, userID); err != nil {
+		return fmt.Errorf("清除活跃周期标记失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qx/syft_robot/api/internal/config"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// ErrCycleNotFound 记账周期不存在
+var ErrCycleNotFound = errors.New("记账周期不存在")
+
+// LedgerStore 记账周期和记录的存储
+// 周期只保存基本信息，记录通过 AppendRecords 和 UpdateRecord 单独保存，合计由存储维护
+type LedgerStore interface {
+	// GetCycle 获取记账周期的基本信息，不加载记录，不存在时返回 ErrCycleNotFound
+	GetCycle(ctx context.Context, cycleID string) (*model.AccountingCycle, error)
+	// SaveCycle 保存记账周期的基本信息
+	SaveCycle(ctx context.Context, cycle *model.AccountingCycle) error
+	// LoadRecords 按日期顺序加载周期的全部记录
+	LoadRecords(ctx context.Context, cycleID string) ([]*model.AccountingRecord, error)
+	// AppendRecords 追加记录并更新合计，记录ID已存在时覆盖
+	AppendRecords(ctx context.Context, cycle *model.AccountingCycle, records ...*model.AccountingRecord) error
+	// UpdateRecord 保存金额不变的记录修改，例如报销状态
+	UpdateRecord(ctx context.Context, cycle *model.AccountingCycle, record *model.AccountingRecord) error
+	// GetTotals 获取收支合计
+	GetTotals(ctx context.Context, cycle *model.AccountingCycle) (*Totals, error)
+	// AddToHistory 添加记账周期到历史记录，重复添加不会产生重复项
+	AddToHistory(ctx context.Context, cycle *model.AccountingCycle) error
+	// HistoryCycleIDs 获取历史记账周期ID列表，按开始时间排列
+	HistoryCycleIDs(ctx context.Context, chatID int64, userID int64) ([]string, error)
+	// ActiveCycleID 获取当前活跃的记账周期ID，没有时返回空字符串
+	ActiveCycleID(ctx context.Context, chatID int64, userID int64) (string, error)
+	// SetActiveCycleID 设置当前活跃的记账周期
+	SetActiveCycleID(ctx context.Context, chatID int64, userID int64, cycleID string) error
+	// ClearActiveCycleID 清除活跃周期标记
+	ClearActiveCycleID(ctx context.Context, chatID int64, userID int64) error
+}
+
+// Totals 记账周期的收支合计
+type Totals struct {
+	Income     model.Money            // 收入（本位币，不含周期收入）
+	Expense    model.Money            // 支出（本位币）
+	ByCurrency []*model.CurrencyTotal // 按原始币种的收支，本位币排在最前，包含周期收入
+}
+
+// MustNewLedgerStore 按配置创建存储，配置错误时 panic
+func MustNewLedgerStore(c config.LedgerConf, rds *redis.Redis) LedgerStore {
+	ledger, err := NewLedgerStore(c, rds)
+	if err != nil {
+		panic(err)
+	}
+	return ledger
+}
+
+// NewLedgerStore 按配置创建存储，未配置时使用 Redis
+func NewLedgerStore(c config.LedgerConf, rds *redis.Redis) (LedgerStore, error) {
+	switch c.Store {
+	case "", "redis":
+		return NewRedisLedgerStore(rds), nil
+	case "sql":
+		return NewSqlLedgerStore(c)
+	default:
+		return nil, fmt.Errorf("不支持的记账存储: %s", c.Store)
+	}
+}
+
+// 合计的字段为 income、expense（本位币）以及 income:<币种>、expense:<币种>（原始币种）
+
+// 一条记录对合计各字段的贡献
+func recordTotals(record *model.AccountingRecord, base string) map[string]model.Money {
+	totals := make(map[string]model.Money, 2)
+	if record.Amount > 0 {
+		totals["income"] = record.Amount
+	} else {
+		totals["expense"] = -record.Amount
+	}
+	currency := record.OriginalCurrency(base)
+	if value := record.OriginalValue(base); value > 0 {
+		totals["income:"+currency] += value
+	} else {
+		totals["expense:"+currency] += -value
+	}
+	return totals
+}
+
+// 由合计字段生成 Totals，按原始币种的合计中本位币排在最前
+func newTotals(cycle *model.AccountingCycle, fields map[string]model.Money) *Totals {
+	base := cycle.Currency()
+	totals := &Totals{ByCurrency: []*model.CurrencyTotal{{Currency: base, Income: cycle.Income}}}
+	others := make(map[string]*model.CurrencyTotal)
+	for field, value := range fields {
+		kind, currency, _ := strings.Cut(field, ":")
+		if currency == "" {
+			if kind == "income" {
+				totals.Income = value
+			} else {
+				totals.Expense = value
+			}
+			continue
+		}
+
+		total := totals.ByCurrency[0]
+		if currency != base {
+			if total = others[currency]; total == nil {
+				total = &model.CurrencyTotal{Currency: currency}
+				others[currency] = total
+			}
+		}
+		if kind == "income" {
+			total.Income += value
+		} else {
+			total.Expense += value
+		}
+	}
+
+	currencies := make([]string, 0, len(others))
+	for currency := range others {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		totals.ByCurrency = append(totals.ByCurrency, others[currency])
+	}
+	return totals
+}
+
+// 按日期排序，同一秒内的记录按写入顺序排列
+func sortRecords(records []*model.AccountingRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].Date.Equal(records[j].Date) {
+			return records[i].Date.Before(records[j].Date)
+		}
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+}
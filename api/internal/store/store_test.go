@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/qx/syft_robot/api/internal/config"
+	"github.com/qx/syft_robot/api/internal/model"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+func newTestRedisStore(t *testing.T) *RedisLedgerStore {
+	t.Helper()
+	server := miniredis.RunT(t)
+	return NewRedisLedgerStore(redis.MustNewRedis(redis.RedisConf{Host: server.Addr(), Type: redis.NodeType}))
+}
+
+func newTestSqlStore(t *testing.T) *SqlLedgerStore {
+	t.Helper()
+	s, err := NewSqlLedgerStore(config.LedgerConf{
+		Store:      "sql",
+		Driver:     "sqlite3",
+		DataSource: filepath.Join(t.TempDir(), "ledger.db"),
+	})
+	if err != nil {
+		t.Fatalf("NewSqlLedgerStore error = %v", err)
+	}
+	return s
+}
+
+// 2026-10-01 开始的人民币周期，收入 5000
+func testCycle(id string) *model.AccountingCycle {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+	return &model.AccountingCycle{
+		ID:        id,
+		ChatID:    -100,
+		UserID:    42,
+		StartTime: start,
+		EndTime:   start.AddDate(0, 1, 0),
+		Income:    500000,
+		IsActive:  true,
+		CreatedAt: start,
+	}
+}
+
+func testRecord(id string, amount model.Money, day int) *model.AccountingRecord {
+	date := time.Date(2026, 10, day, 12, 0, 0, 0, time.Local)
+	return &model.AccountingRecord{
+		ID:          id,
+		Amount:      amount,
+		Description: "记录" + id,
+		Wallet:      model.WalletCash,
+		Date:        date,
+		CreatedAt:   date,
+	}
+}
+
+func recordIDs(records []*model.AccountingRecord) []string {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+func checkTotals(t *testing.T, ledger LedgerStore, cycle *model.AccountingCycle, income, expense model.Money) {
+	t.Helper()
+	totals, err := ledger.GetTotals(context.Background(), cycle)
+	if err != nil {
+		t.Fatalf("GetTotals error = %v", err)
+	}
+	if totals.Income != income || totals.Expense != expense {
+		t.Errorf("GetTotals = 收入 %s 支出 %s, want 收入 %s 支出 %s", totals.Income, totals.Expense, income, expense)
+	}
+}
+
+// 两种存储的行为应当一致
+func TestLedgerStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) LedgerStore{
+		"redis": func(t *testing.T) LedgerStore { return newTestRedisStore(t) },
+		"sql":   func(t *testing.T) LedgerStore { return newTestSqlStore(t) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testLedgerStore(t, newStore(t))
+		})
+	}
+}
+
+func testLedgerStore(t *testing.T, ledger LedgerStore) {
+	ctx := context.Background()
+	cycle := testCycle("c1")
+
+	if _, err := ledger.GetCycle(ctx, cycle.ID); err != ErrCycleNotFound {
+		t.Fatalf("GetCycle(不存在) error = %v, want ErrCycleNotFound", err)
+	}
+	if err := ledger.SaveCycle(ctx, cycle); err != nil {
+		t.Fatalf("SaveCycle error = %v", err)
+	}
+	got, err := ledger.GetCycle(ctx, cycle.ID)
+	if err != nil {
+		t.Fatalf("GetCycle error = %v", err)
+	}
+	if got.ID != cycle.ID || got.ChatID != cycle.ChatID || got.UserID != cycle.UserID || got.Income != cycle.Income ||
+		!got.IsActive || !got.StartTime.Equal(cycle.StartTime) || !got.EndTime.Equal(cycle.EndTime) {
+		t.Errorf("GetCycle = %+v, want %+v", got, cycle)
+	}
+
+	// 写入顺序与日期顺序不同，读取时按日期排列
+	lunch := testRecord("r1", -3500, 3)
+	salary := testRecord("r2", 100000, 2)
+	taxi := testRecord("r3", -5000, 5)
+	if err := ledger.AppendRecords(ctx, cycle, lunch, salary); err != nil {
+		t.Fatalf("AppendRecords error = %v", err)
+	}
+	if err := ledger.AppendRecords(ctx, cycle, taxi); err != nil {
+		t.Fatalf("AppendRecords error = %v", err)
+	}
+	records, err := ledger.LoadRecords(ctx, cycle.ID)
+	if err != nil {
+		t.Fatalf("LoadRecords error = %v", err)
+	}
+	if ids := recordIDs(records); !reflect.DeepEqual(ids, []string{"r2", "r1", "r3"}) {
+		t.Errorf("LoadRecords ids = %v, want [r2 r1 r3]", ids)
+	}
+	if records[1].Amount != -3500 || records[1].Description != "记录r1" || records[1].Wallet != model.WalletCash ||
+		!records[1].Date.Equal(lunch.Date) {
+		t.Errorf("LoadRecords[1] = %+v, want %+v", records[1], lunch)
+	}
+	checkTotals(t, ledger, cycle, 100000, 8500)
+
+	// 覆盖同一ID的记录，合计只计算新记录
+	replaced := testRecord("r1", -2000, 3)
+	if err := ledger.AppendRecords(ctx, cycle, replaced); err != nil {
+		t.Fatalf("AppendRecords(覆盖) error = %v", err)
+	}
+	checkTotals(t, ledger, cycle, 100000, 7000)
+	records, _ = ledger.LoadRecords(ctx, cycle.ID)
+	if len(records) != 3 || records[1].Amount != -2000 {
+		t.Errorf("LoadRecords after replace = %v", recordIDs(records))
+	}
+
+	// 修改报销状态不影响合计
+	replaced.Reimbursement = &model.Reimbursement{Status: model.ReimbursePending}
+	if err := ledger.UpdateRecord(ctx, cycle, replaced); err != nil {
+		t.Fatalf("UpdateRecord error = %v", err)
+	}
+	records, _ = ledger.LoadRecords(ctx, cycle.ID)
+	if records[1].Reimbursement == nil || records[1].Reimbursement.Status != model.ReimbursePending {
+		t.Errorf("UpdateRecord 未保存报销状态: %+v", records[1])
+	}
+	checkTotals(t, ledger, cycle, 100000, 7000)
+
+	// 外币记录按原始币种单独合计
+	usd := testRecord("r4", -7200, 6)
+	usd.Currency, usd.OriginalAmount = "USD", -1000
+	if err := ledger.AppendRecords(ctx, cycle, usd); err != nil {
+		t.Fatalf("AppendRecords(USD) error = %v", err)
+	}
+	totals, err := ledger.GetTotals(ctx, cycle)
+	if err != nil {
+		t.Fatalf("GetTotals error = %v", err)
+	}
+	if len(totals.ByCurrency) != 2 || totals.ByCurrency[0].Currency != model.DefaultCurrency ||
+		totals.ByCurrency[0].Expense != 7000 || totals.ByCurrency[0].Income != 600000 ||
+		totals.ByCurrency[1].Currency != "USD" || totals.ByCurrency[1].Expense != 1000 {
+		t.Errorf("GetTotals.ByCurrency = %+v %+v", totals.ByCurrency[0], totals.ByCurrency[len(totals.ByCurrency)-1])
+	}
+
+	// 历史和活跃周期
+	next := testCycle("c2")
+	next.StartTime = next.EndTime
+	for _, c := range []*model.AccountingCycle{next, cycle, cycle} {
+		if err := ledger.SaveCycle(ctx, c); err != nil {
+			t.Fatalf("SaveCycle error = %v", err)
+		}
+		if err := ledger.AddToHistory(ctx, c); err != nil {
+			t.Fatalf("AddToHistory error = %v", err)
+		}
+	}
+	ids, err := ledger.HistoryCycleIDs(ctx, cycle.ChatID, cycle.UserID)
+	if err != nil {
+		t.Fatalf("HistoryCycleIDs error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"c1", "c2"}) {
+		t.Errorf("HistoryCycleIDs = %v, want [c1 c2]", ids)
+	}
+
+	if id, err := ledger.ActiveCycleID(ctx, cycle.ChatID, cycle.UserID); err != nil || id != "" {
+		t.Errorf("ActiveCycleID = %q, %v, want empty", id, err)
+	}
+	for _, id := range []string{"c1", "c2"} {
+		if err := ledger.SetActiveCycleID(ctx, cycle.ChatID, cycle.UserID, id); err != nil {
+			t.Fatalf("SetActiveCycleID error = %v", err)
+		}
+	}
+	if id, err := ledger.ActiveCycleID(ctx, cycle.ChatID, cycle.UserID); err != nil || id != "c2" {
+		t.Errorf("ActiveCycleID = %q, %v, want c2", id, err)
+	}
+	if err := ledger.ClearActiveCycleID(ctx, cycle.ChatID, cycle.UserID); err != nil {
+		t.Fatalf("ClearActiveCycleID error = %v", err)
+	}
+	if id, err := ledger.ActiveCycleID(ctx, cycle.ChatID, cycle.UserID); err != nil || id != "" {
+		t.Errorf("ActiveCycleID after clear = %q, %v, want empty", id, err)
+	}
+}
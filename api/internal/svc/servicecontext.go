@@ -3,6 +3,7 @@ package svc
 import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/qx/syft_robot/api/internal/config"
+	"github.com/qx/syft_robot/api/internal/store"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 )
 
@@ -10,6 +11,7 @@ type ServiceContext struct {
 	Config config.Config
 	Bot    *tgbotapi.BotAPI
 	Redis  *redis.Redis
+	Ledger store.LedgerStore // 记账周期和记录的存储
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
@@ -24,5 +26,6 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		Config: c,
 		Bot:    bot,
 		Redis:  redisClient,
+		Ledger: store.MustNewLedgerStore(c.Ledger, redisClient),
 	}
 } 
\ No newline at end of file